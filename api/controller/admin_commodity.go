@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func AdminCommodityList(c *gin.Context) {
+	listQuery := new(request.AdminCommodityList)
+	if err := c.ShouldBindQuery(listQuery); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	pagination := app.NewPagination(c)
+	commodityList, err := appservice.NewCommodityAppSvc(c).AdminListCommodities(listQuery, pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(commodityList)
+}
+
+func AdminCommodityCreate(c *gin.Context) {
+	request := new(request.AdminCommodityCreate)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	created, err := appservice.NewCommodityAppSvc(c).AdminCreateCommodity(request)
+	if err != nil {
+		adminCommodityError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(created)
+}
+
+func AdminCommodityUpdate(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	request := new(request.AdminCommodityUpdate)
+	if err := c.ShouldBindJSON(request); err != nil || commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminUpdateCommodity(commodityId, request); err != nil {
+		adminCommodityError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCommoditySellStatus(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	request := new(request.AdminCommoditySellStatus)
+	if err := c.ShouldBindJSON(request); err != nil || commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminSetCommoditySellStatus(commodityId, request.SellStatus); err != nil {
+		adminCommodityError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCommodityDelete(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	if commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminDeleteCommodity(commodityId); err != nil {
+		adminCommodityError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCommodityRestore(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	if commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminRestoreCommodity(commodityId); err != nil {
+		adminCommodityError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// adminCommodityError 把商品管理的业务错误原样返回, 其他错误作为服务器错误
+func adminCommodityError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrCommodityNotExists,
+		errcode.ErrCommodityStockOut,
+		errcode.ErrCommodityCategoryInvalid,
+		errcode.ErrCommodityPriceInvalid,
+		errcode.ErrCommoditySkuInvalid,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr)
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
+
+func AdminCategoryCreate(c *gin.Context) {
+	request := new(request.AdminCategory)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	created, err := appservice.NewCommodityAppSvc(c).AdminCreateCategory(request)
+	if err != nil {
+		adminCategoryError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(created)
+}
+
+func AdminCategoryUpdate(c *gin.Context) {
+	categoryId, _ := strconv.ParseInt(c.Param("category_id"), 10, 64)
+	request := new(request.AdminCategory)
+	if err := c.ShouldBindJSON(request); err != nil || categoryId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminUpdateCategory(categoryId, request); err != nil {
+		adminCategoryError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCategoryRerank(c *gin.Context) {
+	request := new(request.AdminCategoryRerank)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminRerankCategories(request); err != nil {
+		adminCategoryError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCategoryDelete(c *gin.Context) {
+	categoryId, _ := strconv.ParseInt(c.Param("category_id"), 10, 64)
+	if categoryId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	if err := appservice.NewCommodityAppSvc(c).AdminDeleteCategory(categoryId); err != nil {
+		adminCategoryError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func adminCategoryError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrCategoryNotExists,
+		errcode.ErrCategoryLevelInvalid,
+		errcode.ErrCategoryNotEmpty,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr)
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
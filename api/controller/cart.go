@@ -23,8 +23,14 @@ func AddCartItem(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, errcode.ErrCommodityNotExists) {
 			app.NewResponse(c).Error(errcode.ErrCommodityNotExists)
+		} else if errors.Is(err, errcode.ErrCommodityOffShelf) {
+			app.NewResponse(c).Error(errcode.ErrCommodityOffShelf)
 		} else if errors.Is(err, errcode.ErrCommodityStockOut) {
 			app.NewResponse(c).Error(errcode.ErrCommodityStockOut)
+		} else if errors.Is(err, errcode.ErrCartFull) {
+			app.NewResponse(c).Error(errcode.ErrCartFull)
+		} else if errors.Is(err, errcode.ErrCartPurchaseLimit) {
+			app.NewResponse(c).Error(errcode.ErrCartPurchaseLimit.WithCause(err))
 		} else {
 			// WithCause 记得加, 不然请求的错误日志里记不到错误原因
 			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
@@ -34,22 +40,37 @@ func AddCartItem(c *gin.Context) {
 	app.NewResponse(c).SuccessOk()
 }
 
+// CheckCartItemBill 结算购物项, 不传 item_id 时结算购物车中当前勾选的购物项,
+// 不传 user_coupon_id 时使用优惠金额最高的优惠券, 不传 use_points 时不使用积分抵扣
 func CheckCartItemBill(c *gin.Context) {
 	itemIdList := c.QueryArray("item_id")
-	if len(itemIdList) == 0 {
-		app.NewResponse(c).Error(errcode.ErrParams)
-	}
 	itemIds := lo.Map(itemIdList, func(itemId string, index int) int64 {
 		i, _ := strconv.ParseInt(itemId, 10, 64)
 		return i
 	})
+	userCouponId, _ := strconv.ParseInt(c.Query("user_coupon_id"), 10, 64)
+	usePoints, _ := strconv.Atoi(c.Query("use_points"))
+	if usePoints < 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
 	cartAppSvc := appservice.NewCartAppSvc(c)
-	replyData, err := cartAppSvc.CheckCartItemBillV2(itemIds, c.GetInt64("userId"))
+	replyData, err := cartAppSvc.CheckCartItemBillV2(itemIds, userCouponId, usePoints, c.GetInt64("userId"))
 	if err != nil {
 		if errors.Is(err, errcode.ErrCartItemParam) {
 			app.NewResponse(c).Error(errcode.ErrCartItemParam)
 		} else if errors.Is(err, errcode.ErrCartWrongUser) {
 			app.NewResponse(c).Error(errcode.ErrCartWrongUser)
+		} else if errors.Is(err, errcode.ErrCommodityNotExists) {
+			app.NewResponse(c).Error(errcode.ErrCommodityNotExists.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCommodityOffShelf) {
+			app.NewResponse(c).Error(errcode.ErrCommodityOffShelf.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCommodityStockOut) {
+			app.NewResponse(c).Error(errcode.ErrCommodityStockOut.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCouponUnavailable) {
+			app.NewResponse(c).Error(errcode.ErrCouponUnavailable.WithCause(err))
+		} else if errors.Is(err, errcode.ErrPointsInsufficient) {
+			app.NewResponse(c).Error(errcode.ErrPointsInsufficient.WithCause(err))
 		} else {
 			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
 		}
@@ -71,6 +92,8 @@ func UpdateCartItem(c *gin.Context) {
 			app.NewResponse(c).Error(errcode.ErrCartItemParam)
 		} else if errors.Is(err, errcode.ErrCartWrongUser) {
 			app.NewResponse(c).Error(errcode.ErrCartWrongUser)
+		} else if errors.Is(err, errcode.ErrCartPurchaseLimit) {
+			app.NewResponse(c).Error(errcode.ErrCartPurchaseLimit.WithCause(err))
 		} else {
 			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
 		}
@@ -81,12 +104,12 @@ func UpdateCartItem(c *gin.Context) {
 
 func UserCartItems(c *gin.Context) {
 	cartAppSvc := appservice.NewCartAppSvc(c)
-	cartItems, err := cartAppSvc.GetUserCartItems(c.GetInt64("userId"))
+	userCart, err := cartAppSvc.GetUserCartItems(c.GetInt64("userId"))
 	if err != nil {
 		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
 		return
 	}
-	app.NewResponse(c).Success(cartItems)
+	app.NewResponse(c).Success(userCart)
 }
 
 func DeleteCartItem(c *gin.Context) {
@@ -103,3 +126,154 @@ func DeleteCartItem(c *gin.Context) {
 	}
 	app.NewResponse(c).SuccessOk()
 }
+
+// SelectCartItems 勾选或取消勾选购物项
+func SelectCartItems(c *gin.Context) {
+	request := new(request.CartItemSelect)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCartAppSvc(c).SelectCartItems(request, c.GetInt64("userId")); err != nil {
+		cartBatchError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// SelectAllCartItems 全选或取消全选购物车
+func SelectAllCartItems(c *gin.Context) {
+	request := new(request.CartSelectAll)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCartAppSvc(c).SelectAllCartItems(request, c.GetInt64("userId")); err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// BatchDeleteCartItems 批量删除购物项
+func BatchDeleteCartItems(c *gin.Context) {
+	request := new(request.CartItemBatchDelete)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCartAppSvc(c).DeleteCartItems(request, c.GetInt64("userId")); err != nil {
+		cartBatchError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// ClearInvalidCartItems 清空购物车中的失效购物项
+func ClearInvalidCartItems(c *gin.Context) {
+	replyData, err := appservice.NewCartAppSvc(c).ClearInvalidCartItems(c.GetInt64("userId"))
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(replyData)
+}
+
+func cartBatchError(c *gin.Context, err error) {
+	if errors.Is(err, errcode.ErrCartWrongUser) {
+		app.NewResponse(c).Error(errcode.ErrCartWrongUser)
+		return
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
+
+// guestCartToken 读取请求头中未登录用户的购物车标识, required 为 false 时允许为空
+func guestCartToken(c *gin.Context, required bool) (string, bool) {
+	header := new(request.GuestCartHeader)
+	if err := c.ShouldBindHeader(header); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return "", false
+	}
+	if required && header.CartToken == "" {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return "", false
+	}
+	return header.CartToken, true
+}
+
+func GuestCartAddItem(c *gin.Context) {
+	cartToken, ok := guestCartToken(c, false)
+	if !ok {
+		return
+	}
+	request := new(request.GuestCartItem)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	replyData, err := appservice.NewCartAppSvc(c).GuestCartAddItem(cartToken, request)
+	if err != nil {
+		guestCartError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(replyData)
+}
+
+func GuestCartItems(c *gin.Context) {
+	cartToken, ok := guestCartToken(c, true)
+	if !ok {
+		return
+	}
+	guestCart, err := appservice.NewCartAppSvc(c).GetGuestCartItems(cartToken)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(guestCart)
+}
+
+func GuestCartUpdateItem(c *gin.Context) {
+	cartToken, ok := guestCartToken(c, true)
+	if !ok {
+		return
+	}
+	request := new(request.GuestCartItem)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCartAppSvc(c).GuestCartUpdateItem(cartToken, request); err != nil {
+		guestCartError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func GuestCartDeleteItem(c *gin.Context) {
+	cartToken, ok := guestCartToken(c, true)
+	if !ok {
+		return
+	}
+	skuId, _ := strconv.ParseInt(c.Param("sku_id"), 10, 64)
+	if err := appservice.NewCartAppSvc(c).GuestCartDeleteItem(cartToken, skuId); err != nil {
+		guestCartError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func guestCartError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrCartItemParam,
+		errcode.ErrCartFull,
+		errcode.ErrCommodityNotExists,
+		errcode.ErrCommodityOffShelf,
+		errcode.ErrCommodityStockOut,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr)
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
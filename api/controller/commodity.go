@@ -24,10 +24,14 @@ func GetCategoriesWithParentId(c *gin.Context) {
 }
 
 func CommoditiesInCategory(c *gin.Context) {
-	categoryId, _ := strconv.ParseInt(c.Query("category_id"), 10, 64)
+	listQuery := new(request.CommodityInCategory)
+	if err := c.ShouldBindQuery(listQuery); err != nil || !validPriceRange(&listQuery.CommodityListFilter) {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
 	pagination := app.NewPagination(c)
 	svc := appservice.NewCommodityAppSvc(c)
-	commodityList, err := svc.GetCategoryCommodityList(categoryId, pagination)
+	commodityList, err := svc.GetCategoryCommodityList(listQuery, pagination)
 	if err != nil {
 		if errors.Is(err, errcode.ErrParams) {
 			app.NewResponse(c).Error(errcode.ErrParams)
@@ -41,22 +45,69 @@ func CommoditiesInCategory(c *gin.Context) {
 
 func CommoditySearch(c *gin.Context) {
 	searchQuery := new(request.CommoditySearch)
-	if err := c.ShouldBindQuery(searchQuery); err != nil {
+	if err := c.ShouldBindQuery(searchQuery); err != nil || !validPriceRange(&searchQuery.CommodityListFilter) {
 		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
 		return
 	}
 
 	pagination := app.NewPagination(c)
 	svc := appservice.NewCommodityAppSvc(c)
-	commodityList, err := svc.SearchCommodity(searchQuery.Keyword, pagination)
+	commodityList, err := svc.SearchCommodity(searchQuery, pagination)
 	if err != nil {
-		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		if errors.Is(err, errcode.ErrParams) {
+			app.NewResponse(c).Error(errcode.ErrParams)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
 		return
 	}
 
 	app.NewResponse(c).SetPagination(pagination).Success(commodityList)
 }
 
+func SearchSuggest(c *gin.Context) {
+	suggestQuery := new(request.SearchSuggest)
+	if err := c.ShouldBindQuery(suggestQuery); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+
+	svc := appservice.NewCommodityAppSvc(c)
+	suggestions, err := svc.SearchSuggest(suggestQuery.Keyword)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+
+	app.NewResponse(c).Success(suggestions)
+}
+
+func HotSearch(c *gin.Context) {
+	hotQuery := new(request.HotSearch)
+	if err := c.ShouldBindQuery(hotQuery); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+
+	svc := appservice.NewCommodityAppSvc(c)
+	hotQueries, err := svc.HotSearchQueries(hotQuery)
+	if err != nil {
+		if errors.Is(err, errcode.ErrParams) {
+			app.NewResponse(c).Error(errcode.ErrParams)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+
+	app.NewResponse(c).Success(hotQueries)
+}
+
+// validPriceRange 同时指定最低价和最高价时, 最低价不能高于最高价
+func validPriceRange(filter *request.CommodityListFilter) bool {
+	return filter.MinPrice == 0 || filter.MaxPrice == 0 || filter.MinPrice <= filter.MaxPrice
+}
+
 func CommodityInfo(c *gin.Context) {
 	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
 	if commodityId <= 0 {
@@ -73,3 +124,67 @@ func CommodityInfo(c *gin.Context) {
 
 	app.NewResponse(c).Success(commodityInfo)
 }
+
+func RelatedCommodities(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	relatedQuery := new(request.RelatedCommodities)
+	if err := c.ShouldBindQuery(relatedQuery); err != nil || commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+
+	svc := appservice.NewCommodityAppSvc(c)
+	relatedList, err := svc.RelatedCommodities(commodityId, relatedQuery.Size)
+	if err != nil {
+		if errors.Is(err, errcode.ErrCommodityNotExists) {
+			app.NewResponse(c).Error(errcode.ErrCommodityNotExists)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+
+	app.NewResponse(c).Success(relatedList)
+}
+
+func CommodityStock(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	if commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+
+	svc := appservice.NewCommodityAppSvc(c)
+	stock, err := svc.GetCommodityStock(commodityId)
+	if err != nil {
+		if errors.Is(err, errcode.ErrCommodityNotExists) {
+			app.NewResponse(c).Error(errcode.ErrCommodityNotExists)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+
+	app.NewResponse(c).Success(stock)
+}
+
+func CommoditySkus(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	if commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+
+	svc := appservice.NewCommodityAppSvc(c)
+	skuMatrix, err := svc.GetCommoditySkus(commodityId)
+	if err != nil {
+		if errors.Is(err, errcode.ErrCommodityNotExists) {
+			app.NewResponse(c).Error(errcode.ErrCommodityNotExists)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+
+	app.NewResponse(c).Success(skuMatrix)
+}
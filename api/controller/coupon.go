@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func CouponTemplates(c *gin.Context) {
+	templates, err := appservice.NewCouponAppSvc(c).GetClaimableTemplates()
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(templates)
+}
+
+func CouponClaim(c *gin.Context) {
+	templateId, _ := strconv.ParseInt(c.Param("template_id"), 10, 64)
+	if templateId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	coupon, err := appservice.NewCouponAppSvc(c).ClaimCoupon(c.GetInt64("userId"), templateId)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(coupon)
+}
+
+func UserCoupons(c *gin.Context) {
+	listQuery := new(request.UserCouponList)
+	if err := c.ShouldBindQuery(listQuery); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	pagination := app.NewPagination(c)
+	coupons, err := appservice.NewCouponAppSvc(c).GetUserCoupons(c.GetInt64("userId"), listQuery, pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(coupons)
+}
+
+func AdminCouponTemplateCreate(c *gin.Context) {
+	request := new(request.CouponTemplateCreate)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	template, err := appservice.NewCouponAppSvc(c).AdminCreateTemplate(request)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(template)
+}
+
+func AdminCouponTemplateStatus(c *gin.Context) {
+	templateId, _ := strconv.ParseInt(c.Param("template_id"), 10, 64)
+	request := new(request.CouponTemplateStatus)
+	if err := c.ShouldBindJSON(request); err != nil || templateId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewCouponAppSvc(c).AdminSetTemplateStatus(templateId, request); err != nil {
+		couponError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func AdminCouponIssue(c *gin.Context) {
+	templateId, _ := strconv.ParseInt(c.Param("template_id"), 10, 64)
+	request := new(request.CouponIssue)
+	if err := c.ShouldBindJSON(request); err != nil || templateId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	issued, err := appservice.NewCouponAppSvc(c).AdminIssueCoupons(templateId, request)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(issued)
+}
+
+// couponError 把优惠券的业务错误原样返回, 其他错误作为服务器错误
+func couponError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrCouponNotExists,
+		errcode.ErrCouponNotClaimable,
+		errcode.ErrCouponSoldOut,
+		errcode.ErrCouponClaimLimit,
+		errcode.ErrCouponUnavailable,
+		errcode.ErrParams,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr.WithCause(err))
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func AdminDiscountCampaigns(c *gin.Context) {
+	pagination := app.NewPagination(c)
+	campaigns, err := appservice.NewDiscountAppSvc(c).AdminGetCampaigns(pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(campaigns)
+}
+
+func AdminDiscountCampaignCreate(c *gin.Context) {
+	request := new(request.DiscountCampaignCreate)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	campaign, err := appservice.NewDiscountAppSvc(c).AdminCreateCampaign(request)
+	if err != nil {
+		discountError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(campaign)
+}
+
+func AdminDiscountCampaignStatus(c *gin.Context) {
+	campaignId, _ := strconv.ParseInt(c.Param("campaign_id"), 10, 64)
+	request := new(request.DiscountCampaignStatus)
+	if err := c.ShouldBindJSON(request); err != nil || campaignId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewDiscountAppSvc(c).AdminSetCampaignStatus(campaignId, request); err != nil {
+		discountError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// discountError 把满减活动的业务错误原样返回, 其他错误作为服务器错误
+func discountError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrDiscountNotExists,
+		errcode.ErrParams,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr.WithCause(err))
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
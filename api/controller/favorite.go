@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func FavoriteAdd(c *gin.Context) {
+	request := new(request.FavoriteAdd)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewFavoriteAppSvc(c).AddFavorite(c.GetInt64("userId"), request.CommodityId); err != nil {
+		favoriteError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func FavoriteRemove(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	if commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	if err := appservice.NewFavoriteAppSvc(c).RemoveFavorite(c.GetInt64("userId"), commodityId); err != nil {
+		favoriteError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+func UserFavorites(c *gin.Context) {
+	pagination := app.NewPagination(c)
+	favorites, err := appservice.NewFavoriteAppSvc(c).GetUserFavorites(c.GetInt64("userId"), pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(favorites)
+}
+
+func FavoriteMoveToCart(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	request := new(request.FavoriteMoveToCart)
+	if err := c.ShouldBindJSON(request); err != nil || commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewFavoriteAppSvc(c).MoveToCart(c.GetInt64("userId"), commodityId, request); err != nil {
+		favoriteError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// favoriteError 把收藏和加购的业务错误原样返回, 其他错误作为服务器错误
+func favoriteError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrFavoriteNotExists,
+		errcode.ErrCommodityNotExists,
+		errcode.ErrCommodityOffShelf,
+		errcode.ErrCommodityStockOut,
+		errcode.ErrCommoditySkuInvalid,
+		errcode.ErrCartFull,
+		errcode.ErrCartPurchaseLimit,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr)
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
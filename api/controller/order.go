@@ -5,8 +5,11 @@ import (
 	"github.com/Ian-zy0329/go-mall/api/request"
 	"github.com/Ian-zy0329/go-mall/common/app"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/logic/appservice"
 	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
 )
 
 func OrderCreate(c *gin.Context) {
@@ -24,6 +27,18 @@ func OrderCreate(c *gin.Context) {
 			app.NewResponse(c).Error(errcode.ErrCartWrongUser)
 		} else if errors.Is(err, errcode.ErrCommodityStockOut) {
 			app.NewResponse(c).Error(errcode.ErrCommodityStockOut.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCommodityNotExists) {
+			app.NewResponse(c).Error(errcode.ErrCommodityNotExists.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCommodityOffShelf) {
+			app.NewResponse(c).Error(errcode.ErrCommodityOffShelf.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCartPurchaseLimit) {
+			app.NewResponse(c).Error(errcode.ErrCartPurchaseLimit.WithCause(err))
+		} else if errors.Is(err, errcode.ErrOrderNumLimit) {
+			app.NewResponse(c).Error(errcode.ErrOrderNumLimit.WithCause(err))
+		} else if errors.Is(err, errcode.ErrCouponUnavailable) {
+			app.NewResponse(c).Error(errcode.ErrCouponUnavailable.WithCause(err))
+		} else if errors.Is(err, errcode.ErrPointsInsufficient) {
+			app.NewResponse(c).Error(errcode.ErrPointsInsufficient.WithCause(err))
 		} else {
 			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
 		}
@@ -73,6 +88,42 @@ func CancelOrder(c *gin.Context) {
 	app.NewResponse(c).SuccessOk()
 }
 
+func ConfirmReceipt(c *gin.Context) {
+	orderNo := c.Param("order_no")
+	orderAppSvc := appservice.NewOrderAppSvc(c)
+	err := orderAppSvc.ConfirmReceipt(orderNo, c.GetInt64("userId"))
+	if err != nil {
+		if errors.Is(err, errcode.ErrOrderParams) {
+			app.NewResponse(c).Error(errcode.ErrOrderParams)
+		} else if errors.Is(err, errcode.ErrOrderCanNotBeChanged) {
+			app.NewResponse(c).Error(errcode.ErrOrderCanNotBeChanged)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// AdminOrderClose 商家关闭订单, 已支付的订单关闭后需要在支付平台退款
+func AdminOrderClose(c *gin.Context) {
+	orderNo := c.Param("order_no")
+	err := appservice.NewOrderAppSvc(c).MerchantCloseOrder(orderNo)
+	if err != nil {
+		if errors.Is(err, errcode.ErrOrderParams) {
+			app.NewResponse(c).Error(errcode.ErrOrderParams)
+		} else if errors.Is(err, errcode.ErrOrderCanNotBeChanged) {
+			app.NewResponse(c).Error(errcode.ErrOrderCanNotBeChanged)
+		} else if errors.Is(err, errcode.ErrTooManyRequests) {
+			app.NewResponse(c).Error(errcode.ErrTooManyRequests)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
 func CreateOrderPay(c *gin.Context) {
 	request := new(request.OrderPayCreate)
 	if err := c.ShouldBindJSON(request); err != nil {
@@ -91,3 +142,22 @@ func CreateOrderPay(c *gin.Context) {
 	}
 	app.NewResponse(c).Success(reply)
 }
+
+// WxPayNotify 微信支付结果通知, 按微信支付的约定返回应答, 应答失败时微信会重复通知
+func WxPayNotify(c *gin.Context) {
+	rawPost, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger.New(c).Error("WxPayNotifyReadBodyError", "err", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": "FAIL", "message": "读取通知失败"})
+		return
+	}
+	orderAppSvc := appservice.NewOrderAppSvc(c)
+	err = orderAppSvc.HandleWxPayNotify(c.GetHeader("Wechatpay-Timestamp"), c.GetHeader("Wechatpay-Nonce"),
+		c.GetHeader("Wechatpay-Signature"), string(rawPost))
+	if err != nil {
+		logger.New(c).Error("WxPayNotifyError", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "FAIL", "message": "处理失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": ""})
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func ReviewCreate(c *gin.Context) {
+	request := new(request.ReviewCreate)
+	if err := c.ShouldBindJSON(request); err != nil {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	created, err := appservice.NewReviewAppSvc(c).CreateReview(request, c.GetInt64("userId"))
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	app.NewResponse(c).Success(created)
+}
+
+func CommodityReviews(c *gin.Context) {
+	commodityId, _ := strconv.ParseInt(c.Param("commodity_id"), 10, 64)
+	request := new(request.CommodityReviewList)
+	if err := c.ShouldBindQuery(request); err != nil || commodityId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	pagination := app.NewPagination(c)
+	reviewList, err := appservice.NewReviewAppSvc(c).CommodityReviews(commodityId, request, pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(reviewList)
+}
+
+func AdminReviewReply(c *gin.Context) {
+	reviewId, _ := strconv.ParseInt(c.Param("review_id"), 10, 64)
+	request := new(request.AdminReviewReply)
+	if err := c.ShouldBindJSON(request); err != nil || reviewId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	if err := appservice.NewReviewAppSvc(c).AdminReplyReview(reviewId, request.Content); err != nil {
+		reviewError(c, err)
+		return
+	}
+	app.NewResponse(c).SuccessOk()
+}
+
+// reviewError 把评价的业务错误原样返回, 其他错误作为服务器错误
+func reviewError(c *gin.Context, err error) {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrReviewNotAllowed,
+		errcode.ErrReviewDuplicated,
+		errcode.ErrReviewNotExists,
+	} {
+		if errors.Is(err, appErr) {
+			app.NewResponse(c).Error(appErr)
+			return
+		}
+	}
+	app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func SeckillCampaigns(c *gin.Context) {
+	campaigns, err := appservice.NewSeckillAppSvc(c).GetActiveCampaigns()
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(campaigns)
+}
+
+func SeckillCampaignInfo(c *gin.Context) {
+	campaignId, _ := strconv.ParseInt(c.Param("campaign_id"), 10, 64)
+	if campaignId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams)
+		return
+	}
+	campaign, err := appservice.NewSeckillAppSvc(c).GetCampaignInfo(campaignId)
+	if err != nil {
+		if errors.Is(err, errcode.ErrSeckillNotExists) {
+			app.NewResponse(c).Error(errcode.ErrSeckillNotExists)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+	app.NewResponse(c).Success(campaign)
+}
+
+func SeckillOrderSubmit(c *gin.Context) {
+	campaignId, _ := strconv.ParseInt(c.Param("campaign_id"), 10, 64)
+	request := new(request.SeckillOrderSubmit)
+	if err := c.ShouldBindJSON(request); err != nil || campaignId <= 0 {
+		app.NewResponse(c).Error(errcode.ErrParams.WithCause(err))
+		return
+	}
+	reply, err := appservice.NewSeckillAppSvc(c).SubmitOrder(campaignId, request, c.GetInt64("userId"))
+	if err != nil {
+		for _, appErr := range []*errcode.AppError{
+			errcode.ErrTooManyRequests,
+			errcode.ErrSeckillNotExists,
+			errcode.ErrSeckillNotStarted,
+			errcode.ErrSeckillEnded,
+			errcode.ErrSeckillSoldOut,
+			errcode.ErrSeckillUserLimit,
+		} {
+			if errors.Is(err, appErr) {
+				app.NewResponse(c).Error(appErr)
+				return
+			}
+		}
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(reply)
+}
+
+func SeckillResult(c *gin.Context) {
+	requestId := c.Param("request_id")
+	result, err := appservice.NewSeckillAppSvc(c).GetResult(requestId, c.GetInt64("userId"))
+	if err != nil {
+		if errors.Is(err, errcode.ErrSeckillResultNotFound) {
+			app.NewResponse(c).Error(errcode.ErrSeckillResultNotFound)
+		} else {
+			app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		}
+		return
+	}
+	app.NewResponse(c).Success(result)
+}
@@ -133,6 +133,27 @@ func UserInfo(c *gin.Context) {
 	app.NewResponse(c).Success(userInfoReply)
 }
 
+// UserVip 用户的会员等级和成长值
+func UserVip(c *gin.Context) {
+	vipReply, err := appservice.NewUserAppSvc(c).GetUserVip(c.GetInt64("userId"))
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).Success(vipReply)
+}
+
+// UserPoints 用户的积分余额和积分流水
+func UserPoints(c *gin.Context) {
+	pagination := app.NewPagination(c)
+	pointsReply, err := appservice.NewUserAppSvc(c).GetUserPoints(c.GetInt64("userId"), pagination)
+	if err != nil {
+		app.NewResponse(c).Error(errcode.ErrServer.WithCause(err))
+		return
+	}
+	app.NewResponse(c).SetPagination(pagination).Success(pointsReply)
+}
+
 func UpdateUserInfo(c *gin.Context) {
 	request := new(request.UserInfoUpdate)
 	if err := c.ShouldBindJSON(request); err != nil {
@@ -0,0 +1,33 @@
+package reply
+
+type AdminCommodity struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Intro         string `json:"intro"`
+	CategoryId    int64  `json:"category_id"`
+	CoverImg      string `json:"cover_img"`
+	OriginalPrice int    `json:"original_price"`
+	SellingPrice  int    `json:"selling_price"`
+	StockNum      int    `json:"stock_num"`
+	SalesNum      int    `json:"sales_num"`
+	PurchaseLimit int    `json:"purchase_limit"`
+	Tag           string `json:"tag"`
+	SellStatus    int    `json:"sell_status"`
+	IsDel         uint   `json:"is_del"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+type AdminCommodityCreated struct {
+	CommodityId int64              `json:"commodity_id"`
+	Skus        []*AdminCreatedSku `json:"skus"`
+}
+
+type AdminCreatedSku struct {
+	SkuId    int64  `json:"sku_id"`
+	SpecDesc string `json:"spec_desc"`
+}
+
+type AdminCategoryCreated struct {
+	CategoryId int64 `json:"category_id"`
+}
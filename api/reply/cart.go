@@ -4,17 +4,48 @@ type CartItem struct {
 	CartItemId            int64  `json:"cart_item_id"`
 	UserId                int64  `json:"user_id"`
 	CommodityId           int64  `json:"commodity_id"`
+	SkuId                 int64  `json:"sku_id"`
 	CommodityNum          int    `json:"commodity_num"`
+	Selected              bool   `json:"selected"`                       // 是否勾选结算
 	CommodityName         string `json:"commodity_name"`                 // 商品名称
 	CommodityImg          string `json:"commodity_img"`                  // 商品图片
 	CommoditySellingPrice int    `json:"commodity_selling_price"`        // 商品售价
+	AddedPrice            int    `json:"added_price"`                    // 加购时的售价, 状态为 price_changed 时和当前售价不同
+	AvailableStock        int    `json:"available_stock"`                // 当前可售库存
+	SkuSpecDesc           string `json:"sku_spec_desc"`                  // SKU规格描述
+	Status                string `json:"status"`                         // valid-可结算 price_changed-价格变动 insufficient_stock-库存不足 off_shelf-已下架 deleted-已删除
 	AddCartAt             string `json:"add_cart_at" copier:"CreatedAt"` //购物车添加时间,  把Do的CreatedAt字段用copier映射到这里
 }
+
+// UserCart 购物车列表, 失效的购物项单独分组
+type UserCart struct {
+	Items        []*CartItem `json:"items"`         // 可以结算的购物项
+	InvalidItems []*CartItem `json:"invalid_items"` // 库存不足、已下架、已删除的购物项
+}
+
+type CartInvalidCleared struct {
+	ClearedNum int `json:"cleared_num"` // 删除的失效购物项数量
+}
+
+type GuestCartToken struct {
+	CartToken string `json:"cart_token"` // 未登录用户的购物车标识, 之后的请求和登录时通过 go-mall-cart-token 请求头传入
+}
+
 type CheckedCartItemBill struct {
 	Items      []*CartItem `json:"items"`
 	TotalPrice int         `json:"total_price"`
 }
 
+// BillReduction 结算明细中的一项优惠
+type BillReduction struct {
+	Checker     string `json:"checker"` // coupon-优惠券 discount-满减活动 vip-会员折扣
+	Name        string `json:"name"`
+	Amount      int    `json:"amount"`       // 计算出的优惠金额
+	DeductMoney int    `json:"deduct_money"` // 实际抵扣的金额
+	Result      string `json:"result"`       // applied-全额抵扣 capped-受最低支付金额限制部分抵扣 no_room-未抵扣 excluded-与其他优惠互斥未使用
+	ExcludedBy  string `json:"excluded_by"`  // 因互斥未使用时, 被使用的优惠
+}
+
 type CheckedCartItemBillV2 struct {
 	Items      []*CartItem `json:"items"`
 	BillDetail struct {
@@ -28,8 +59,15 @@ type CheckedCartItemBillV2 struct {
 			DiscountName  string `json:"discount_name"`
 			DiscountMoney int    `json:"discount_money"`
 		} `json:"discount"`
-		VipDiscountMoney   int `json:"vip_discount_money"`
-		OriginalTotalPrice int `json:"original_total_price"`
-		TotalPrice         int `json:"total_price"`
+		Points struct {
+			Points      int `json:"points"` // 使用的积分
+			DeductMoney int `json:"deduct_money"`
+		} `json:"points"`
+		UsableCoupons      []*UsableCoupon           `json:"usable_coupons"`     // 可以使用的优惠券, 按优惠金额从高到低排序
+		DiscountCampaigns  []*DiscountCampaignResult `json:"discount_campaigns"` // 当前生效的满减活动是否使用及原因
+		VipDiscountMoney   int                       `json:"vip_discount_money"`
+		OriginalTotalPrice int                       `json:"original_total_price"`
+		Reductions         []*BillReduction          `json:"reductions"` // 按计算顺序排列的每项优惠, 说明支付金额是怎样计算出来的
+		TotalPrice         int                       `json:"total_price"`
 	} `json:"bill_detail"`
 }
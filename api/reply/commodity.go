@@ -34,6 +34,7 @@ type Commodity struct {
 	StockNum      int       `json:"stock_num"`
 	Tag           string    `json:"tag"`
 	SellStatus    int       `json:"sell_status"`
+	PurchaseLimit int       `json:"purchase_limit"` // 每个用户的限购数量, 0-不单独限购
 	CreatedAt     time.Time `json:"created_at"`
 }
 
@@ -47,5 +48,92 @@ type CommodityListElem struct {
 	SellingPrice  int    `json:"selling_price"`
 	Tag           string `json:"tag"`
 	SellStatus    int    `json:"sell_status"`
+	SalesNum      int    `json:"sales_num"`
 	CreatedAt     string `json:"created_at"`
+	// 搜索结果中命中关键词的部分用 <em></em> 标记, 只有搜索接口返回
+	Highlight *CommodityHighlight `json:"highlight,omitempty"`
+}
+
+type CommodityHighlight struct {
+	Name  string `json:"name"`
+	Intro string `json:"intro"`
+	Tag   string `json:"tag"`
+}
+
+// CommodityList 商品列表及其分面统计, 分面用于展示可继续筛选的分类和价格区间
+type CommodityList struct {
+	List   []*CommodityListElem `json:"list"`
+	Facets *CommodityFacets     `json:"facets"`
+}
+
+type CommodityFacets struct {
+	Categories  []*CommodityCategoryFacet `json:"categories"`
+	PriceRanges []*CommodityPriceFacet    `json:"price_ranges"`
+}
+
+type CommodityCategoryFacet struct {
+	CategoryId   int64  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Count        int64  `json:"count"`
+}
+
+type CommodityPriceFacet struct {
+	MinPrice int   `json:"min_price"`
+	MaxPrice int   `json:"max_price"` // 0表示没有上限
+	Count    int64 `json:"count"`
+}
+
+type CommodityStock struct {
+	CommodityId int64 `json:"commodity_id"`
+	SkuId       int64 `json:"sku_id"`
+	Available   int   `json:"available"` // 可售库存
+	Reserved    int   `json:"reserved"`  // 待支付订单预占的库存
+	Sold        int   `json:"sold"`      // 已售出
+}
+
+type CommoditySkuMatrix struct {
+	Specs []*CommoditySpec `json:"specs"`
+	Skus  []*CommoditySku  `json:"skus"`
+}
+
+type CommoditySpec struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Values []struct {
+		ID    int64  `json:"id"`
+		Value string `json:"value"`
+	} `json:"values"`
+}
+
+type CommoditySku struct {
+	ID            int64   `json:"sku_id"`
+	SpecValueIds  []int64 `json:"spec_value_ids" copier:"-"` // 与 specs 顺序一致的规格值ID
+	SpecDesc      string  `json:"spec_desc"`
+	CoverImg      string  `json:"cover_img"`
+	Images        string  `json:"images"`
+	OriginalPrice int     `json:"original_price"`
+	SellingPrice  int     `json:"selling_price"`
+	Available     int     `json:"available"` // 可售库存
+	SellStatus    int     `json:"sell_status"`
+}
+
+type StockDiff struct {
+	ItemID           int64  `json:"item_id"`
+	RedisMissing     bool   `json:"redis_missing"`
+	RedisStock       int    `json:"redis_stock"`
+	RedisReserved    int    `json:"redis_reserved"`
+	PendingConfirmed int    `json:"pending_confirmed"`
+	MysqlStock       int    `json:"mysql_stock"`
+	Diff             int    `json:"diff"`
+	Repaired         string `json:"repaired"`
+}
+
+type SearchSuggestion struct {
+	Text   string `json:"text"`
+	Source string `json:"source"` // query-历史搜索词 commodity-商品名称
+}
+
+type SearchQueryStat struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
 }
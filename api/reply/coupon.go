@@ -0,0 +1,42 @@
+package reply
+
+type CouponTemplate struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	DiscountType  int     `json:"discount_type"`
+	DiscountValue int     `json:"discount_value"`
+	MaxDiscount   int     `json:"max_discount"`
+	Threshold     int     `json:"threshold"`
+	ScopeType     int     `json:"scope_type"`
+	ScopeIds      []int64 `json:"scope_ids"`
+	TotalNum      int     `json:"total_num"`
+	IssuedNum     int     `json:"issued_num"`
+	PerUserLimit  int     `json:"per_user_limit"`
+	ValidStartAt  string  `json:"valid_start_at"`
+	ValidEndAt    string  `json:"valid_end_at"`
+	ValidDays     int     `json:"valid_days"`
+	Status        int     `json:"status"`
+}
+
+type UserCoupon struct {
+	ID           int64           `json:"id"`
+	TemplateId   int64           `json:"template_id"`
+	Status       int             `json:"status"` // 1-未使用 2-已锁定 3-已使用
+	OrderId      int64           `json:"order_id"`
+	ValidStartAt string          `json:"valid_start_at"`
+	ValidEndAt   string          `json:"valid_end_at"`
+	CreatedAt    string          `json:"created_at"`
+	Template     *CouponTemplate `json:"template"`
+}
+
+// UsableCoupon 结算时可以使用的优惠券
+type UsableCoupon struct {
+	UserCouponId  int64  `json:"user_coupon_id"`
+	CouponName    string `json:"coupon_name"`
+	DiscountMoney int    `json:"discount_money"`
+	ValidEndAt    string `json:"valid_end_at"`
+}
+
+type CouponIssued struct {
+	IssuedNum int `json:"issued_num"`
+}
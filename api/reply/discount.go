@@ -0,0 +1,30 @@
+package reply
+
+type DiscountCampaign struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Type      int             `json:"type"`
+	Tiers     []*DiscountTier `json:"tiers"`
+	ScopeType int             `json:"scope_type"`
+	ScopeIds  []int64         `json:"scope_ids"`
+	Priority  int             `json:"priority"`
+	StartTime string          `json:"start_time"`
+	EndTime   string          `json:"end_time"`
+	Status    int             `json:"status"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type DiscountTier struct {
+	Threshold int `json:"threshold"`
+	Value     int `json:"value"`
+}
+
+// DiscountCampaignResult 结算时满减活动的计算结果
+type DiscountCampaignResult struct {
+	CampaignId    int64  `json:"campaign_id"`
+	CampaignName  string `json:"campaign_name"`
+	Result        string `json:"result"` // applied-已使用 no_eligible_item-没有活动商品 threshold_unmet-未达到门槛 not_preferred-使用了其他活动
+	ScopeAmount   int    `json:"scope_amount"`
+	ScopeNum      int    `json:"scope_num"`
+	DiscountMoney int    `json:"discount_money"`
+}
@@ -0,0 +1,13 @@
+package reply
+
+type FavoriteItem struct {
+	CommodityId    int64  `json:"commodity_id"`
+	CommodityName  string `json:"commodity_name"`
+	CoverImg       string `json:"cover_img"`
+	SellingPrice   int    `json:"selling_price"`   // 当前售价
+	FavoritedPrice int    `json:"favorited_price"` // 降价提醒的参考价
+	PriceDropped   bool   `json:"price_dropped"`   // 当前售价低于参考价
+	StockNum       int    `json:"stock_num"`       // 当前库存
+	Status         string `json:"status"`          // valid-在售 off_shelf-已下架 deleted-已删除
+	FavoritedAt    string `json:"favorited_at"`
+}
@@ -23,6 +23,8 @@ type Order struct {
 	} `json:"address,omitempty"`
 	Items []struct {
 		CommodityId           int64  `json:"commodity_id"`
+		SkuId                 int64  `json:"sku_id"`
+		SkuSpecDesc           string `json:"sku_spec_desc"`
 		CommodityName         string `json:"commodity_name"`
 		CommodityImg          string `json:"commodity_img"`
 		CommoditySellingPrice int    `json:"commodity_selling_price"`
@@ -0,0 +1,35 @@
+package reply
+
+type ReviewCreated struct {
+	ReviewId int64 `json:"review_id"`
+}
+
+type CommodityReview struct {
+	ID            int64    `json:"id"`
+	UserNickname  string   `json:"user_nickname"`
+	UserAvatar    string   `json:"user_avatar"`
+	SkuSpecDesc   string   `json:"sku_spec_desc"`
+	Rating        int      `json:"rating"`
+	Content       string   `json:"content"`
+	Images        []string `json:"images"`
+	MerchantReply string   `json:"merchant_reply"`
+	RepliedAt     string   `json:"replied_at,omitempty"`
+	CreatedAt     string   `json:"created_at"`
+}
+
+type CommodityRatingCount struct {
+	Rating int   `json:"rating"`
+	Count  int64 `json:"count"`
+}
+
+type CommodityRatingSummary struct {
+	ReviewCount   int64                   `json:"review_count"`
+	AverageRating float64                 `json:"average_rating"`
+	GoodRate      float64                 `json:"good_rate"`
+	RatingCounts  []*CommodityRatingCount `json:"rating_counts"` // 从五星到一星
+}
+
+type CommodityReviewList struct {
+	Summary *CommodityRatingSummary `json:"summary"`
+	List    []*CommodityReview      `json:"list"`
+}
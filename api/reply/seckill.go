@@ -0,0 +1,26 @@
+package reply
+
+type SeckillCampaign struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	CommodityId  int64  `json:"commodity_id"`
+	SkuId        int64  `json:"sku_id"`
+	SeckillPrice int    `json:"seckill_price"`
+	Quota        int    `json:"quota"`
+	Remaining    int    `json:"remaining"` // 剩余名额
+	UserLimit    int    `json:"user_limit"`
+	StartTime    string `json:"start_time"`
+	EndTime      string `json:"end_time"`
+}
+
+type SeckillSubmitReply struct {
+	RequestId string `json:"request_id"`
+}
+
+type SeckillResult struct {
+	RequestId  string `json:"request_id"`
+	CampaignId int64  `json:"campaign_id"`
+	Status     int    `json:"status"` // 1-排队中 2-下单成功 3-下单失败
+	OrderNo    string `json:"order_no"`
+	Reason     string `json:"reason"`
+}
@@ -36,3 +36,32 @@ type UserAddress struct {
 	DetailAddress string `json:"detail_address"`
 	CreatedAt     string `json:"created_at"`
 }
+
+// UserPoints 用户的积分余额和分页的积分流水
+type UserPoints struct {
+	Balance int            `json:"balance"` // 可用的积分
+	Entries []*PointsEntry `json:"entries"` // 积分流水, 最近的在前
+}
+
+type PointsEntry struct {
+	ID        int64  `json:"id"`
+	Type      int    `json:"type"`   // 1-订单获得 2-下单抵扣 3-过期 4-调整
+	Points    int    `json:"points"` // 增加为正数, 减少为负数
+	OrderId   int64  `json:"order_id"`
+	Remark    string `json:"remark"`
+	ExpireAt  string `json:"expire_at"` // 增加的积分的过期时间, 减少积分的流水为空
+	CreatedAt string `json:"created_at"`
+}
+
+// UserVip 用户的会员等级和成长值
+type UserVip struct {
+	Level           int    `json:"level"` // 当前有效的会员等级, 0-普通用户
+	LevelName       string `json:"level_name"`
+	DiscountRate    int    `json:"discount_rate"`   // 会员折扣减免的百分比
+	LevelExpireAt   string `json:"level_expire_at"` // 会员等级的到期时间, 普通用户为空
+	Growth          int    `json:"growth"`          // 累计获得的成长值
+	WindowGrowth    int    `json:"window_growth"`   // 计算会员等级的统计周期内获得的成长值
+	NextLevel       int    `json:"next_level"`      // 下一个等级, 已是最高等级时为 0
+	NextLevelName   string `json:"next_level_name"`
+	NextLevelGrowth int    `json:"next_level_growth"` // 达到下一个等级需要的成长值
+}
@@ -0,0 +1,82 @@
+package request
+
+type AdminCommoditySpec struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required,min=1,dive,required"`
+}
+
+type AdminCommoditySkuCreate struct {
+	SpecValues    []string `json:"spec_values"` // 按规格项顺序, 每个规格项选一个值
+	CoverImg      string   `json:"cover_img"`
+	Images        string   `json:"images"`
+	OriginalPrice int      `json:"original_price" binding:"required,min=1"`
+	SellingPrice  int      `json:"selling_price" binding:"required,min=1"`
+	StockNum      int      `json:"stock_num" binding:"min=0"`
+	SellStatus    int      `json:"sell_status" binding:"omitempty,oneof=1 2"`
+}
+
+type AdminCommodityCreate struct {
+	Name          string                     `json:"name" binding:"required,max=200"`
+	Intro         string                     `json:"intro" binding:"max=200"`
+	CategoryId    int64                      `json:"category_id" binding:"required"`
+	CoverImg      string                     `json:"cover_img" binding:"required"`
+	Images        string                     `json:"images"`
+	DetailContent string                     `json:"detail_content"`
+	Tag           string                     `json:"tag" binding:"max=50"`
+	PurchaseLimit int                        `json:"purchase_limit" binding:"min=0"` // 每个用户的限购数量, 0-不单独限购
+	SellStatus    int                        `json:"sell_status" binding:"omitempty,oneof=1 2"`
+	Specs         []*AdminCommoditySpec      `json:"specs" binding:"dive"`
+	Skus          []*AdminCommoditySkuCreate `json:"skus" binding:"required,min=1,dive"`
+}
+
+type AdminCommoditySkuUpdate struct {
+	SkuId         int64  `json:"sku_id" binding:"required"`
+	CoverImg      string `json:"cover_img"`
+	Images        string `json:"images"`
+	OriginalPrice int    `json:"original_price" binding:"required,min=1"`
+	SellingPrice  int    `json:"selling_price" binding:"required,min=1"`
+	StockDelta    int    `json:"stock_delta"` // 可售库存的增减量, 负数为减少
+	SellStatus    int    `json:"sell_status" binding:"required,oneof=1 2"`
+}
+
+type AdminCommodityUpdate struct {
+	Name          string                     `json:"name" binding:"required,max=200"`
+	Intro         string                     `json:"intro" binding:"max=200"`
+	CategoryId    int64                      `json:"category_id" binding:"required"`
+	CoverImg      string                     `json:"cover_img" binding:"required"`
+	Images        string                     `json:"images"`
+	DetailContent string                     `json:"detail_content"`
+	Tag           string                     `json:"tag" binding:"max=50"`
+	PurchaseLimit int                        `json:"purchase_limit" binding:"min=0"` // 每个用户的限购数量, 0-不单独限购
+	Skus          []*AdminCommoditySkuUpdate `json:"skus" binding:"dive"`            // 只修改列出的SKU
+}
+
+type AdminCommoditySellStatus struct {
+	SellStatus int `json:"sell_status" binding:"required,oneof=1 2"`
+}
+
+type AdminCommodityList struct {
+	Keyword    string `form:"keyword"`
+	CategoryId int64  `form:"category_id"`
+	SellStatus int    `form:"sell_status" binding:"omitempty,oneof=1 2"`
+	Deleted    bool   `form:"deleted"` // 只查已删除的商品
+	// 下面两个参数由Pagination组件使用
+	Page     int `form:"page" binding:"min=0"`
+	PageSize int `form:"page_size" binding:"max=100"`
+}
+
+type AdminCategory struct {
+	ParentId int64  `json:"parent_id" binding:"min=0"` // 0表示一级分类
+	Name     string `json:"name" binding:"required,max=50"`
+	IconImg  string `json:"icon_img"`
+	Rank     int    `json:"rank"`
+}
+
+type AdminCategoryRank struct {
+	CategoryId int64 `json:"category_id" binding:"required"`
+	Rank       int   `json:"rank"`
+}
+
+type AdminCategoryRerank struct {
+	Ranks []*AdminCategoryRank `json:"ranks" binding:"required,min=1,dive"`
+}
@@ -1,16 +1,40 @@
 package request
 
 type AddCartItem struct {
-	CommodityId  int64 `json:"commodity_id" binding:"required"`
+	SkuId        int64 `json:"sku_id" binding:"required"`
 	CommodityNum int   `json:"commodity_num" binding:"required" binding:"required,min=1,max=5"`
 }
 
+type GuestCartHeader struct {
+	CartToken string `header:"go-mall-cart-token" binding:"omitempty,len=32,alphanum"`
+}
+
+type GuestCartItem struct {
+	SkuId        int64 `json:"sku_id" binding:"required"`
+	CommodityNum int   `json:"commodity_num" binding:"required,min=1,max=5"`
+}
+
 type CartItemUpdate struct {
 	CartItemId   int64 `json:"item_id" binding:"required"`
 	CommodityNum int   `json:"commodity_num" binding:"required" binding:"required,min=1,max=5"`
 }
 
+type CartItemSelect struct {
+	CartItemIds []int64 `json:"cart_item_ids" binding:"required,min=1,max=100"`
+	Selected    *bool   `json:"selected" binding:"required"`
+}
+
+type CartSelectAll struct {
+	Selected *bool `json:"selected" binding:"required"`
+}
+
+type CartItemBatchDelete struct {
+	CartItemIds []int64 `json:"cart_item_ids" binding:"required,min=1,max=100"`
+}
+
 type OrderCreate struct {
-	CartItemIdList []int64 `json:"cart_item_id_list" binding:"required"`
+	CartItemIdList []int64 `json:"cart_item_id_list"` // 为空时使用购物车中当前勾选的购物项
 	UserAddressId  int64   `json:"user_address_id" binding:"required"`
+	UserCouponId   int64   `json:"user_coupon_id"`             // 使用的优惠券, 为空时自动使用优惠金额最高的优惠券
+	UsePoints      int     `json:"use_points" binding:"min=0"` // 使用积分抵扣的积分数量, 为空时不使用积分
 }
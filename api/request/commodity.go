@@ -1,8 +1,40 @@
 package request
 
+// CommodityListFilter 商品列表的筛选和排序参数, 分类列表和搜索共用
+type CommodityListFilter struct {
+	MinPrice int    `form:"min_price" binding:"min=0"` // 最低售价(分), 0表示不限
+	MaxPrice int    `form:"max_price" binding:"min=0"` // 最高售价(分), 0表示不限
+	Tags     string `form:"tags"`                      // 标签, 多个用逗号分隔, 商品需要包含所有标签
+	InStock  bool   `form:"in_stock"`                  // 只看有货
+	Sort     string `form:"sort" binding:"omitempty,oneof=price_asc price_desc newest sales"`
+}
+
+type CommodityInCategory struct {
+	CategoryId int64 `form:"category_id" binding:"required"`
+	CommodityListFilter
+	// 下面两个参数由Pagination组件使用
+	Page     int `form:"page" binding:"min=0"`
+	PageSize int `form:"page_size" binding:"max=100"`
+}
+
 type CommoditySearch struct {
-	Keyword string `form:"keyword" binding:"required"`
+	Keyword    string `form:"keyword" binding:"required"`
+	CategoryId int64  `form:"category_id"` // 限定搜索的分类, 可以是任意级别的分类
+	CommodityListFilter
 	// 下面两个参数由Pagination组件使用
 	Page     int `form:"page" binding:"min=1"`
 	PageSize int `form:"page_size" binding:"max=100"`
 }
+
+type SearchSuggest struct {
+	Keyword string `form:"keyword" binding:"required"`
+}
+
+type HotSearch struct {
+	Date string `form:"date"` // 日期 2006-01-02, 为空时为当天
+	Size int    `form:"size" binding:"min=0,max=50"`
+}
+
+type RelatedCommodities struct {
+	Size int `form:"size" binding:"min=0,max=20"` // 为0时返回默认数量
+}
@@ -0,0 +1,29 @@
+package request
+
+// CouponTemplateCreate 后台创建优惠券模板, 金额的单位为分, 时间格式为 2006-01-02 15:04:05
+type CouponTemplateCreate struct {
+	Name          string  `json:"name" binding:"required,max=64"`
+	DiscountType  int     `json:"discount_type" binding:"required,oneof=1 2"` // 1-满减 2-折扣
+	DiscountValue int     `json:"discount_value" binding:"required,min=1"`    // 满减时为减免金额, 折扣时为减免的百分比
+	MaxDiscount   int     `json:"max_discount" binding:"min=0"`               // 折扣最多减免的金额, 0-不限
+	Threshold     int     `json:"threshold" binding:"min=0"`                  // 使用范围内的商品金额满多少可用, 0-无门槛
+	ScopeType     int     `json:"scope_type" binding:"oneof=0 1 2"`           // 0-全场 1-指定三级分类 2-指定商品
+	ScopeIds      []int64 `json:"scope_ids" binding:"max=100"`
+	TotalNum      int     `json:"total_num" binding:"min=0"` // 发行数量, 0-不限
+	PerUserLimit  int     `json:"per_user_limit" binding:"omitempty,min=1"`
+	ValidStartAt  string  `json:"valid_start_at" binding:"required,datetime=2006-01-02 15:04:05"`
+	ValidEndAt    string  `json:"valid_end_at" binding:"required,datetime=2006-01-02 15:04:05"`
+	ValidDays     int     `json:"valid_days" binding:"min=0"` // 领取后多少天内有效, 0-到结束时间为止
+}
+
+type CouponTemplateStatus struct {
+	Status int `json:"status" binding:"required,oneof=1 2"` // 1-可领取 2-停止领取
+}
+
+type CouponIssue struct {
+	UserIds []int64 `json:"user_ids" binding:"required,min=1,max=1000"`
+}
+
+type UserCouponList struct {
+	Status int `form:"status" binding:"omitempty,oneof=1 2 3"` // 1-未使用 2-已锁定 3-已使用, 不传时返回全部
+}
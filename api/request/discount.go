@@ -0,0 +1,23 @@
+package request
+
+// DiscountCampaignCreate 后台创建满减活动, 金额的单位为分, 时间格式为 2006-01-02 15:04:05
+type DiscountCampaignCreate struct {
+	Name      string          `json:"name" binding:"required,max=64"`
+	Type      int             `json:"type" binding:"required,oneof=1 2 3"` // 1-阶梯满减 2-满件折扣 3-组合一口价
+	Tiers     []*DiscountTier `json:"tiers" binding:"required,min=1,max=10,dive"`
+	ScopeType int             `json:"scope_type" binding:"oneof=0 1 2"` // 0-全场 1-指定三级分类 2-指定商品
+	ScopeIds  []int64         `json:"scope_ids" binding:"max=100"`
+	Priority  int             `json:"priority"` // 数值大的优先使用
+	StartTime string          `json:"start_time" binding:"required,datetime=2006-01-02 15:04:05"`
+	EndTime   string          `json:"end_time" binding:"required,datetime=2006-01-02 15:04:05"`
+}
+
+// DiscountTier 阶梯满减时为金额门槛和减免金额, 满件折扣时为件数门槛和减免的百分比, 组合一口价时为组合件数和组合价格
+type DiscountTier struct {
+	Threshold int `json:"threshold" binding:"required,min=1"`
+	Value     int `json:"value" binding:"required,min=1"`
+}
+
+type DiscountCampaignStatus struct {
+	Status int `json:"status" binding:"required,oneof=1 2"` // 1-生效 2-下线
+}
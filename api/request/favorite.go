@@ -0,0 +1,10 @@
+package request
+
+type FavoriteAdd struct {
+	CommodityId int64 `json:"commodity_id" binding:"required"`
+}
+
+type FavoriteMoveToCart struct {
+	SkuId        int64 `json:"sku_id" binding:"required"`
+	CommodityNum int   `json:"commodity_num" binding:"required,min=1,max=5"`
+}
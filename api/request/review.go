@@ -0,0 +1,17 @@
+package request
+
+type ReviewCreate struct {
+	OrderNo string   `json:"order_no" binding:"required"`
+	SkuId   int64    `json:"sku_id" binding:"required"`
+	Rating  int      `json:"rating" binding:"required,min=1,max=5"`
+	Content string   `json:"content" binding:"max=500"`
+	Images  []string `json:"images" binding:"max=9,dive,required,url"`
+}
+
+type CommodityReviewList struct {
+	Rating int `form:"rating" binding:"omitempty,min=1,max=5"` // 只看某一星级的评价
+}
+
+type AdminReviewReply struct {
+	Content string `json:"content" binding:"required,max=500"`
+}
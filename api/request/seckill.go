@@ -0,0 +1,6 @@
+package request
+
+type SeckillOrderSubmit struct {
+	Num           int   `json:"num" binding:"required,min=1"`
+	UserAddressId int64 `json:"user_address_id" binding:"required"`
+}
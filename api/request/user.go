@@ -15,7 +15,8 @@ type UserLogin struct {
 		Password  string `json:"password" binding:"required,min=8"`
 	}
 	Header struct {
-		Platform string `json:"platform" header:"platform" binding:"required,oneof=H5 APP"`
+		Platform  string `json:"platform" header:"platform" binding:"required,oneof=H5 APP"`
+		CartToken string `header:"go-mall-cart-token" binding:"omitempty,len=32,alphanum"` // 未登录时使用的购物车标识, 登录后合并购物车
 	}
 }
 
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"github.com/Ian-zy0329/go-mall/api/controller"
+	"github.com/Ian-zy0329/go-mall/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// 后台管理接口, 只有配置在 app.admin.user_ids 中的用户可以访问
+func registerAdminRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/admin/")
+	g.Use(middleware.AuthUser(), middleware.AuthAdmin())
+	g.GET("commodities", controller.AdminCommodityList)
+	g.POST("commodity", controller.AdminCommodityCreate)
+	g.PUT("commodity/:commodity_id", controller.AdminCommodityUpdate)
+	g.PUT("commodity/:commodity_id/sell-status", controller.AdminCommoditySellStatus)
+	g.DELETE("commodity/:commodity_id", controller.AdminCommodityDelete)
+	g.POST("commodity/:commodity_id/restore", controller.AdminCommodityRestore)
+	g.POST("category", controller.AdminCategoryCreate)
+	g.PUT("category/:category_id", controller.AdminCategoryUpdate)
+	g.PUT("categories/rank", controller.AdminCategoryRerank)
+	g.DELETE("category/:category_id", controller.AdminCategoryDelete)
+	g.POST("review/:review_id/reply", controller.AdminReviewReply)
+	g.POST("coupon/template", controller.AdminCouponTemplateCreate)
+	g.PUT("coupon/template/:template_id/status", controller.AdminCouponTemplateStatus)
+	g.POST("coupon/template/:template_id/issue", controller.AdminCouponIssue)
+	g.GET("discount/campaigns", controller.AdminDiscountCampaigns)
+	g.POST("discount/campaign", controller.AdminDiscountCampaignCreate)
+	g.PUT("discount/campaign/:campaign_id/status", controller.AdminDiscountCampaignStatus)
+	g.POST("order/:order_no/close", controller.AdminOrderClose)
+}
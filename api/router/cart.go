@@ -14,4 +14,15 @@ func registerCartRouter(rg *gin.RouterGroup) {
 	g.PATCH("update-item", controller.UpdateCartItem)
 	g.GET("item", controller.UserCartItems)
 	g.DELETE("item/:item_id", controller.DeleteCartItem)
+	g.PATCH("item/select", controller.SelectCartItems)
+	g.PATCH("item/select-all", controller.SelectAllCartItems)
+	g.POST("item/batch-delete", controller.BatchDeleteCartItems)
+	g.DELETE("invalid-item", controller.ClearInvalidCartItems)
+
+	// 未登录用户的购物车, 通过 go-mall-cart-token 请求头区分
+	guest := rg.Group("/cart/guest/")
+	guest.POST("item", controller.GuestCartAddItem)
+	guest.GET("item", controller.GuestCartItems)
+	guest.PATCH("item", controller.GuestCartUpdateItem)
+	guest.DELETE("item/:sku_id", controller.GuestCartDeleteItem)
 }
@@ -12,5 +12,11 @@ func registerCommodityRoutes(rg *gin.RouterGroup) {
 	g.GET("category/", controller.GetCategoriesWithParentId)
 	g.GET("commodity-in-cate", controller.CommoditiesInCategory)
 	g.GET("search", controller.CommoditySearch)
+	g.GET("search/suggest", controller.SearchSuggest)
+	g.GET("search/hot", controller.HotSearch)
 	g.GET(":commodity_id/info", controller.CommodityInfo)
+	g.GET(":commodity_id/skus", controller.CommoditySkus)
+	g.GET(":commodity_id/stock", controller.CommodityStock)
+	g.GET(":commodity_id/reviews", controller.CommodityReviews)
+	g.GET(":commodity_id/related", controller.RelatedCommodities)
 }
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"github.com/Ian-zy0329/go-mall/api/controller"
+	"github.com/Ian-zy0329/go-mall/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func registerCouponRouter(rg *gin.RouterGroup) {
+	g := rg.Group("/coupon/")
+	g.Use(middleware.AuthUser())
+	g.GET("template", controller.CouponTemplates)
+	g.POST("template/:template_id/claim", controller.CouponClaim)
+	g.GET("mine", controller.UserCoupons)
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/Ian-zy0329/go-mall/api/controller"
+	"github.com/Ian-zy0329/go-mall/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func registerFavoriteRouter(rg *gin.RouterGroup) {
+	g := rg.Group("/favorite/")
+	g.Use(middleware.AuthUser())
+	g.POST("item", controller.FavoriteAdd)
+	g.DELETE("item/:commodity_id", controller.FavoriteRemove)
+	g.GET("item", controller.UserFavorites)
+	g.POST("item/:commodity_id/move-to-cart", controller.FavoriteMoveToCart)
+}
@@ -7,11 +7,15 @@ import (
 )
 
 func registerOrderRouter(rg *gin.RouterGroup) {
+	// 支付平台的结果通知不携带用户Token
+	rg.POST("/order/wxpay-notify", controller.WxPayNotify)
 	g := rg.Group("/order")
 	g.Use(middleware.AuthUser())
 	g.POST("create", controller.OrderCreate)
 	g.GET("user-order", controller.UserOrders)
 	g.GET(":order_no/info", controller.OrderInfo)
 	g.PATCH(":order_no/cancel", controller.CancelOrder)
+	g.PATCH(":order_no/confirm-receipt", controller.ConfirmReceipt)
+	g.POST("review", controller.ReviewCreate)
 	g.POST("create-pay", controller.CreateOrderPay)
 }
@@ -13,7 +13,11 @@ func RegisterRoutes(engine *gin.Engine) {
 	registerUserRoutes(routeGroup)
 	registerCommodityRoutes(routeGroup)
 	registerCartRouter(routeGroup)
+	registerFavoriteRouter(routeGroup)
+	registerCouponRouter(routeGroup)
 	registerOrderRouter(routeGroup)
+	registerSeckillRouter(routeGroup)
+	registerAdminRoutes(routeGroup)
 }
 
 func registerBuildingRoutes(routeGroup *gin.RouterGroup) {
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/Ian-zy0329/go-mall/api/controller"
+	"github.com/Ian-zy0329/go-mall/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func registerSeckillRouter(rg *gin.RouterGroup) {
+	g := rg.Group("/seckill/")
+	g.GET("campaigns", controller.SeckillCampaigns)
+	g.GET("campaign/:campaign_id", controller.SeckillCampaignInfo)
+	// 抢到名额后异步创建订单, 通过 request_id 轮询下单结果
+	g.POST("campaign/:campaign_id/order", middleware.AuthUser(), controller.SeckillOrderSubmit)
+	g.GET("result/:request_id", middleware.AuthUser(), controller.SeckillResult)
+}
@@ -16,6 +16,8 @@ func registerUserRoutes(rg *gin.RouterGroup) {
 	g.POST("password/reset", controller.PasswordReset)
 	g.GET("info", middleware.AuthUser(), controller.UserInfo)
 	g.PATCH("info", middleware.AuthUser(), controller.UpdateUserInfo)
+	g.GET("vip", middleware.AuthUser(), controller.UserVip)
+	g.GET("points", middleware.AuthUser(), controller.UserPoints)
 	g.POST("address", middleware.AuthUser(), controller.AddUserAddress)
 	g.GET("address", middleware.AuthUser(), controller.GetUserAddresses)
 	g.PATCH("address/:address_id", middleware.AuthUser(), controller.UpdateUserAddress)
@@ -0,0 +1,25 @@
+package main
+
+// 迁移用户购物车的存储, 可以重复执行
+// 用法: env=dev go run ./cmd/cartmigrate [-to-redis]
+//   1. 为购物车表增加勾选字段 selected 和加购价格字段 added_price
+//   2. 指定 -to-redis 时把MySQL中的购物项复制到Redis, 购物项ID不变; 复制完成后再把配置 cart.storage 改为 redis
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	toRedis := flag.Bool("to-redis", false, "copy cart items from mysql to redis")
+	flag.Parse()
+	copied, err := appservice.NewCartAppSvc(context.Background()).MigrateCartStorage(*toRedis)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cart migration failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("cart schema migrated, %d cart item(s) copied to redis\n", copied)
+}
@@ -0,0 +1,20 @@
+package main
+
+// 根据最近的订单重新计算"一起购买"的相关商品并写入Redis, 服务中的定时任务也会定期执行
+// 用法: env=dev go run ./cmd/relatedrefresh
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	count, err := appservice.NewCommodityAppSvc(context.Background()).RefreshRelatedCommodities()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "related refresh failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d commodity(s) with related commodities\n", count)
+}
@@ -0,0 +1,19 @@
+package main
+
+// 为商品表增加销量字段和限购字段, 并创建搜索和列表筛选依赖的全文索引、普通索引, 可以重复执行
+// 用法: env=dev go run ./cmd/searchindex
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	if err := appservice.NewCommodityAppSvc(context.Background()).MigrateSearchSchema(); err != nil {
+		fmt.Fprintln(os.Stderr, "search index migration failed:", err)
+		os.Exit(1)
+	}
+	fmt.Println("search index ready")
+}
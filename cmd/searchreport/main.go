@@ -0,0 +1,27 @@
+package main
+
+// 输出搜索次数最多的无结果搜索词, 供运营补充商品或调整商品名称、标签
+// 用法: env=dev go run ./cmd/searchreport -limit=100
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	limit := flag.Int("limit", 100, "输出的搜索词数量")
+	flag.Parse()
+
+	queries, err := appservice.NewCommodityAppSvc(context.Background()).ZeroResultSearchQueries(*limit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "search report failed:", err)
+		os.Exit(1)
+	}
+	for _, query := range queries {
+		fmt.Printf("%d\t%s\n", query.Count, query.Query)
+	}
+	fmt.Printf("%d zero-result query(s)\n", len(queries))
+}
@@ -0,0 +1,24 @@
+package main
+
+// 把旧的"一个颜色/容量一个商品"的数据迁移到 SPU/SKU 模型, 可以重复执行, 已迁移的商品会被跳过
+// 用法: env=dev go run ./cmd/skumigrate
+//   1. 创建规格、规格值、SKU 表, 为购物车、订单购物项、秒杀活动表增加 sku_id 字段
+//   2. 同一分类下同名的旧商品合并为一个 SPU, 每个旧商品成为一个 SKU, SKU ID 沿用旧商品ID,
+//      Redis 中以旧商品ID为key的库存直接成为对应 SKU 的库存
+//   3. 购物车、订单购物项、秒杀活动中的 commodity_id 改为 SPU ID, sku_id 为原来的商品ID
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	migrated, err := appservice.NewCommodityAppSvc(context.Background()).MigrateToSku()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sku migration failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d commodity(s) migrated to sku\n", migrated)
+}
@@ -0,0 +1,33 @@
+package main
+
+// 对账 Redis 与 MySQL 中的商品库存, 输出不一致的商品
+// 用法: env=dev go run ./cmd/stockcheck -repair=redis
+//   -repair 为空时只报告; redis 以MySQL为准修复Redis; mysql 以Redis为准修复MySQL
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"os"
+)
+
+func main() {
+	repair := flag.String("repair", "", "修复不一致库存的一方: redis | mysql, 为空时只报告")
+	flag.Parse()
+
+	diffs, err := appservice.NewCommodityAppSvc(context.Background()).CheckStockConsistency(*repair)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "stock check failed:", err)
+		os.Exit(1)
+	}
+	for _, diff := range diffs {
+		line, _ := json.Marshal(diff)
+		fmt.Println(string(line))
+	}
+	fmt.Printf("%d inconsistent item(s)\n", len(diffs))
+	if len(diffs) > 0 {
+		os.Exit(2)
+	}
+}
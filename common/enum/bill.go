@@ -0,0 +1,25 @@
+package enum
+
+// 结算时可以配置的优惠计算插件
+const (
+	BillCheckerCoupon   = "coupon"   // 优惠券
+	BillCheckerDiscount = "discount" // 满减活动
+	BillCheckerVip      = "vip"      // 会员折扣
+	BillCheckerPoints   = "points"   // 积分抵扣
+)
+
+// BillCheckers 所有可以在 bill_check.chain 和 bill_check.exclusive 中配置的优惠插件
+var BillCheckers = []string{BillCheckerCoupon, BillCheckerDiscount, BillCheckerVip, BillCheckerPoints}
+
+// DefaultBillCheckChain 未配置时结算依次计算的优惠
+var DefaultBillCheckChain = []string{BillCheckerCoupon, BillCheckerDiscount, BillCheckerVip, BillCheckerPoints}
+
+const DefaultMinPayMoney = 1 // 默认订单最低支付金额(分)
+
+// 结算明细中每项优惠的结果
+const (
+	BillReductionApplied  = "applied"  // 全额抵扣
+	BillReductionCapped   = "capped"   // 为保证最低支付金额只抵扣了一部分
+	BillReductionNoRoom   = "no_room"  // 支付金额已是最低支付金额, 没有抵扣
+	BillReductionExcluded = "excluded" // 与同组中优惠金额更高的优惠互斥, 没有使用
+)
@@ -0,0 +1,11 @@
+package enum
+
+import "time"
+
+// 商品详情和分类树缓存
+const (
+	CommodityDetailCacheTTL = 30 * time.Minute
+	CategoryTreeCacheTTL    = 6 * time.Hour
+	CacheMissingTTL         = time.Minute // 不存在的数据只缓存较短时间, 挡住对不存在ID的反复查询
+	CacheTTLJitterRatio     = 0.2         // 过期时间随机延长的最大比例, 避免大量缓存同时过期
+)
@@ -0,0 +1,29 @@
+package enum
+
+import "time"
+
+// 购物车限制, 用户购物车和订单的限制可以在配置中修改
+const (
+	DefaultCartMaxItems    = 100                // 默认用户购物车最多容纳的SKU数量
+	DefaultCommodityMaxNum = 99                 // 默认每个用户的购物车或单个订单中同一商品的最大数量
+	DefaultOrderMaxNum     = 200                // 默认单个订单最多购买的商品件数
+	GuestCartMaxItems      = 50                 // 未登录用户的购物车最多容纳的SKU数量
+	GuestCartTokenLength   = 32                 // 未登录用户购物车标识的长度
+	DefaultGuestCartExpire = 7 * 24 * time.Hour // 未登录用户的购物车默认保留时长
+	CartMigrateBatchSize   = 1000               // 迁移购物车存储时每批读取的购物项数量
+)
+
+// 用户购物车的存储方式, 通过配置 cart.storage 选择
+const (
+	CartStorageMySQL = "mysql"
+	CartStorageRedis = "redis"
+)
+
+// 购物车和收藏列表中商品的状态, 价格变动的购物项仍可结算, 其余非 valid 状态的购物项为失效购物项
+const (
+	CartItemStatusValid             = "valid"              // 可以结算
+	CartItemStatusPriceChanged      = "price_changed"      // 加购后价格有变动, 可以结算
+	CartItemStatusInsufficientStock = "insufficient_stock" // 库存不足购物项的数量
+	CartItemStatusOffShelf          = "off_shelf"          // 商品已下架
+	CartItemStatusDeleted           = "deleted"            // 商品已删除
+)
@@ -0,0 +1,26 @@
+package enum
+
+// 商品和SKU的上架状态
+const (
+	CommoditySellStatusOn  = 1 // 上架
+	CommoditySellStatusOff = 2 // 下架
+)
+
+// SKU按统一的可售规则得到的售卖状态
+const (
+	SkuSellStateOnSale   = 1 // 商品和SKU都未删除且都已上架
+	SkuSellStateOffShelf = 2 // 商品或SKU已下架
+	SkuSellStateDeleted  = 3 // 商品或SKU已删除
+)
+
+// 商品列表的排序方式
+const (
+	CommoditySortDefault   = ""           // 分类列表按ID排序, 搜索按相关度排序
+	CommoditySortPriceAsc  = "price_asc"  // 价格从低到高
+	CommoditySortPriceDesc = "price_desc" // 价格从高到低
+	CommoditySortNewest    = "newest"     // 最新上架
+	CommoditySortSales     = "sales"      // 销量从高到低
+)
+
+// CommodityPriceFacetBounds 商品列表价格区间分面的分界点(单位: 分), 最后一个区间没有上限
+var CommodityPriceFacetBounds = []int{0, 100000, 300000, 500000, 800000}
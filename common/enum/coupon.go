@@ -0,0 +1,22 @@
+package enum
+
+// 优惠券的优惠方式
+const (
+	CouponDiscountTypeAmount  = 1 // 满减, 优惠值为减免的金额(分)
+	CouponDiscountTypePercent = 2 // 折扣, 优惠值为减免的百分比, 可以设置最多减免的金额
+)
+
+// 优惠券模板的状态
+const (
+	CouponTemplateStatusOn  = 1 // 可以领取和发放
+	CouponTemplateStatusOff = 2 // 停止领取和发放, 已领取的优惠券仍可使用
+)
+
+// 用户优惠券的状态
+const (
+	UserCouponStatusUnused = 1 // 未使用
+	UserCouponStatusLocked = 2 // 已被待支付的订单锁定
+	UserCouponStatusUsed   = 3 // 订单已支付
+)
+
+const CouponIssueMaxUsers = 1000 // 后台一次最多给多少个用户发放优惠券
@@ -0,0 +1,7 @@
+package enum
+
+// 收藏商品降价提醒
+const (
+	FavoritePriceDropBatchSize   = 500    // 每批处理的收藏数量
+	FavoritePriceDropQueueMaxLen = 100000 // 降价事件队列的最大长度, 消费不及时时丢弃最早的事件
+)
@@ -20,6 +20,7 @@ const (
 	OrderStatusUserQuit              // 用户取消
 	OrderStatusUnpaidClose           // 超时未支付
 	OrderStatusMerchantClose         // 商家关闭订单
+	OrderStatusPaidToRefund          // 订单已关闭或库存已释放且不足时才收到支付结果, 等待商家关闭订单退款
 )
 
 // OrderFrontStatus 用户在前台看到的订单状态
@@ -36,6 +37,7 @@ var OrderFrontStatus = map[int]string{
 	OrderStatusUserQuit:       "已取消",
 	OrderStatusUnpaidClose:    "已取消",
 	OrderStatusMerchantClose:  "已取消",
+	OrderStatusPaidToRefund:   "待退款",
 }
 
 const (
@@ -0,0 +1,21 @@
+package enum
+
+import "time"
+
+// 积分流水的类型
+const (
+	PointsEntryEarn   = 1 // 订单完成获得
+	PointsEntrySpend  = 2 // 下单抵扣
+	PointsEntryExpire = 3 // 过期
+	PointsEntryAdjust = 4 // 调整, 包括订单取消退回抵扣的积分和订单退款收回获得的积分
+)
+
+const (
+	DefaultPointsEarnUnit       = 100       // 默认订单每支付多少金额(分)获得1积分
+	DefaultPointsRedeemValue    = 1         // 默认每积分抵扣的金额(分)
+	DefaultPointsMaxRedeemRate  = 50        // 默认积分最多抵扣商品金额的百分比
+	DefaultPointsExpireDays     = 365       // 默认获得的积分多少天后过期
+	PointsReturnGraceDays       = 30        // 订单关闭退回的积分至少还能使用的天数
+	DefaultPointsExpireInterval = time.Hour // 处理过期积分的定时任务的默认执行间隔
+	PointsExpireBatchSize       = 500       // 处理过期积分时每批读取的积分数量
+)
@@ -0,0 +1,29 @@
+package enum
+
+// 优惠券和满减活动的适用范围
+const (
+	PromotionScopeAll       = 0 // 全场通用
+	PromotionScopeCategory  = 1 // 指定的三级分类
+	PromotionScopeCommodity = 2 // 指定的商品
+)
+
+// 满减活动的类型
+const (
+	DiscountTypeFullReduction = 1 // 阶梯满减, 范围内商品金额满门槛减对应金额
+	DiscountTypeBuyN          = 2 // 满件折扣, 范围内商品满门槛件数减免对应百分比
+	DiscountTypeBundle        = 3 // 组合一口价, 范围内商品每满门槛件数按对应金额计价
+)
+
+// 满减活动的状态
+const (
+	DiscountStatusOn  = 1 // 生效
+	DiscountStatusOff = 2 // 下线
+)
+
+// 结算时每个满减活动的计算结果
+const (
+	DiscountResultApplied        = "applied"          // 使用了该活动
+	DiscountResultNoEligibleItem = "no_eligible_item" // 没有活动范围内的商品
+	DiscountResultThresholdUnmet = "threshold_unmet"  // 范围内商品未达到活动门槛
+	DiscountResultNotPreferred   = "not_preferred"    // 同时满足的其他活动优先级更高或优惠更多
+)
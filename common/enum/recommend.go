@@ -0,0 +1,16 @@
+package enum
+
+import "time"
+
+// "一起购买"相关商品推荐
+const (
+	DefaultRelatedRefreshInterval = 6 * time.Hour       // 重新计算相关商品的定时任务的默认执行间隔
+	RelatedOrderWindow            = 90 * 24 * time.Hour // 只统计最近90天的已支付订单
+	RelatedOrderBatchSize         = 1000                // 每批读取的订单数量
+	RelatedOrderMaxCommodities    = 50                  // 商品种类超过该数量的订单不参与统计, 避免批量采购订单产生大量组合
+	RelatedKeepSize               = 20                  // 每个商品保留的相关商品数量, 也是接口最多返回的数量
+	DefaultRelatedSize            = 10                  // 接口默认返回的相关商品数量
+	RelatedCacheExpire            = 48 * time.Hour      // 相关商品的过期时间, 商品不再有一起购买的记录时自然过期
+	RelatedRefreshLockExpire      = time.Hour           // 多个实例同时只有一个执行计算
+	CategoryBestSellersCacheTTL   = time.Hour           // 分类热销商品的缓存时间, 相关商品不足时用于补充
+)
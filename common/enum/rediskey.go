@@ -17,4 +17,60 @@ const (
 	STOCK_LOCK_KEY_PREFIX = "mall:stock:lock:" // 库存锁 key
 	STOCK_LOG_KEY_PREFIX  = "mall:stock:log:"  // 库存流水 key
 	STOCK_INIT_SETKEY     = "mall:stock:init"  // 已初始化商品集合
+
+	STOCK_WARMUP_LOCK_KEY_PREFIX = "mall:stock:warmup:" // 库存预热锁 key
+
+	STOCK_RESERVE_KEY_PREFIX   = "mall:stock:reserve:"         // 订单库存预占明细 key
+	STOCK_RESERVE_DEADLINE_KEY = "mall:stock:reserve:deadline" // 库存预占到期时间有序集合
+)
+
+// Redis 秒杀数据结构
+const (
+	SECKILL_CAMPAIGN_KEY_PREFIX = "mall:seckill:campaign:"     // 秒杀活动名额 key
+	SECKILL_LOG_KEY_PREFIX      = "mall:seckill:log:"          // 秒杀名额流水 key
+	SECKILL_USER_KEY_PREFIX     = "mall:seckill:user:"         // 活动内每个用户已抢数量 key
+	SECKILL_RATE_KEY_PREFIX     = "mall:seckill:rate:"         // 活动每秒请求计数 key
+	SECKILL_RESULT_KEY_PREFIX   = "mall:seckill:result:"       // 秒杀请求处理结果 key
+	SECKILL_QUEUE_KEY           = "mall:seckill:queue"         // 待创建订单的秒杀请求队列
+	SECKILL_PROCESSING_KEY      = "mall:seckill:processing"    // 已从队列取出、还没写入处理结果的秒杀请求
+	SECKILL_PROCESSING_AT_KEY   = "mall:seckill:processing:at" // 处理中的秒杀请求从队列取出的时间
+)
+
+// Redis 搜索词数据结构
+const (
+	SEARCH_TREND_KEY          = "mall:search:trend"        // 搜索词热度有序集合, 分数按时间衰减
+	SEARCH_QUERY_LEX_KEY      = "mall:search:query:lex"    // 搜索词字典序集合, 分数都为0, 用于前缀补全
+	SEARCH_RESULT_COUNT_KEY   = "mall:search:result:count" // 搜索词最近一次的结果数量
+	SEARCH_ZERO_RESULT_KEY    = "mall:search:zero_result"  // 无结果搜索词及其搜索次数
+	SEARCH_HOT_DAY_KEY_PREFIX = "mall:search:hot:"         // 每日热搜有序集合 key, 后缀为日期 20060102
+)
+
+// Redis 购物车数据结构
+const (
+	GUEST_CART_KEY_PREFIX = "mall:cart:guest:"  // 未登录用户的购物车哈希 key, 后缀为购物车标识, field 为SKU ID, value 为数量
+	USER_CART_KEY_PREFIX  = "mall:cart:user:"   // 用户购物车哈希 key, 后缀为用户ID, field 为SKU ID, value 为购物项JSON
+	CART_ITEM_ID_KEY      = "mall:cart:item:id" // 购物车使用Redis存储时生成购物项ID的计数器
+)
+
+// Redis 商品缓存
+const (
+	COMMODITY_DETAIL_KEY_PREFIX = "mall:commodity:detail:" // 商品详情缓存 key, 后缀为商品ID
+	CATEGORY_TREE_KEY           = "mall:category:tree"     // 层级分类树缓存
+)
+
+// Redis 收藏数据结构
+const (
+	FAVORITE_PRICE_DROP_QUEUE_KEY = "mall:favorite:price_drop" // 收藏商品降价事件队列, 由通知服务消费
+)
+
+// Redis 商品推荐数据结构
+const (
+	RELATED_COMMODITY_KEY_PREFIX     = "mall:recommend:related:"      // 相关商品有序集合 key, 后缀为商品ID, 分数为一起购买的订单数
+	RELATED_REFRESH_LOCK_KEY         = "mall:recommend:refresh_lock"  // 计算相关商品的任务锁
+	CATEGORY_BEST_SELLERS_KEY_PREFIX = "mall:recommend:best_sellers:" // 分类热销商品缓存 key, 后缀为分类ID
+)
+
+// Redis 定时任务
+const (
+	JOB_LOCK_KEY_PREFIX = "mall:job:lock:" // 定时任务执行锁 key, 后缀为任务名
 )
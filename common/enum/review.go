@@ -0,0 +1,8 @@
+package enum
+
+// 评价星级
+const (
+	ReviewRatingMin     = 1 // 最低星级
+	ReviewRatingMax     = 5 // 最高星级
+	ReviewGoodRatingMin = 4 // 好评的最低星级, 用于计算好评率
+)
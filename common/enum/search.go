@@ -0,0 +1,22 @@
+package enum
+
+import "time"
+
+const SearchQueryMaxLength = 32                // 超过这个长度(字符数)的搜索词不记录
+const SearchTrendHalfLife = 7 * 24 * time.Hour // 搜索词热度的半衰期
+const SearchTrendKeepSize = 10000              // 搜索词热度集合保留的数量, 超出的冷门词定期清理
+const SearchHotDayExpire = 8 * 24 * time.Hour  // 每日热搜的保留时长
+const SearchSuggestLimit = 10                  // 搜索补全返回的最大数量
+const SearchSuggestScanSize = 50               // 按前缀取搜索词候选的数量, 候选再按热度排序
+const DefaultHotKeywordsSize = 10              // 热搜榜默认返回的数量
+const DefaultSearchTrimInterval = time.Hour    // 清理冷门搜索词的定时任务的执行间隔
+
+// SearchTrendEpoch 搜索词热度的时间起点, 每次搜索给热度增加 2^((当前时间-起点)/半衰期),
+// 越近的搜索权重越大, 相当于旧的搜索按半衰期衰减, 不需要定时重算全部分数
+var SearchTrendEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+// 搜索补全的来源
+const (
+	SearchSuggestSourceQuery     = "query"     // 其他用户搜索过的词
+	SearchSuggestSourceCommodity = "commodity" // 商品名称
+)
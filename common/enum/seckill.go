@@ -0,0 +1,24 @@
+package enum
+
+import "time"
+
+const (
+	SeckillCampaignStatusOnline  = 1 // 活动生效
+	SeckillCampaignStatusOffline = 2 // 活动下线
+)
+
+// 秒杀请求的处理结果, 买家轮询获取
+const (
+	SeckillResultQueued  = iota + 1 // 已抢到名额, 排队创建订单
+	SeckillResultSuccess            // 订单创建成功
+	SeckillResultFailed             // 订单创建失败, 名额已退回
+)
+
+const SeckillResultExpire = 30 * time.Minute         // 秒杀结果在Redis中的保留时长
+const SeckillCampaignKeyExtraExpire = 24 * time.Hour // 活动结束后活动库存key的保留时长
+const SeckillCampaignPreloadAhead = 10 * time.Minute // 定时任务提前把即将开始的活动加载到Redis
+const SeckillQueuePopTimeout = time.Second           // 消费秒杀队列时单次阻塞等待的时长
+const SeckillProcessingTimeout = 2 * time.Minute     // 请求从队列取出超过这个时长仍未写入处理结果时, 认为处理它的协程已经退出, 重新放回队列
+const SeckillRecoverInterval = time.Minute           // 检查未完成处理的秒杀请求的间隔
+const DefaultSeckillWorkers = 2                      // 默认消费秒杀队列的协程数
+const DefaultSeckillQueueMax = 10000                 // 默认秒杀排队队列的最大长度, 超过后直接拒绝请求
@@ -0,0 +1,17 @@
+package enum
+
+import "time"
+
+// 库存对账发现不一致时修复的一方
+const (
+	StockRepairNone  = ""      // 只报告不修复
+	StockRepairRedis = "redis" // 以MySQL为准修复Redis
+	StockRepairMysql = "mysql" // 以Redis为准修复MySQL
+)
+
+const StockWarmUpLockDuration = 3 * time.Second    // 从MySQL加载单个商品库存时持有锁的时长
+const StockWarmUpWaitTimeout = time.Second         // 没抢到预热锁时等待其他请求完成预热的时长
+const StockItemLockDuration = 5 * time.Second      // 对账修复单个商品库存时持有锁的时长
+const StockItemLockWaitTimeout = time.Second       // 预占、确认或调整库存时等待商品库存锁释放的时长
+const StockReservationRetention = 24 * time.Hour   // 预占确认后明细继续保留的时长, 支付通知重试时可以直接返回成功
+const DefaultStockCheckInterval = 10 * time.Minute // 库存对账定时任务的默认执行间隔
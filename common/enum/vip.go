@@ -0,0 +1,11 @@
+package enum
+
+import "time"
+
+const (
+	VipGrowthMoneyUnit         = 100       // 订单每支付多少金额(分)获得1点成长值
+	DefaultVipGrowthWindowDays = 365       // 默认按最近多少天的成长值计算会员等级
+	DefaultVipLevelDays        = 365       // 默认按成长值升级后会员等级的有效天数
+	DefaultVipRefreshInterval  = time.Hour // 重新计算会员等级的定时任务的默认执行间隔
+	VipRefreshBatchSize        = 500       // 重新计算会员等级时每批处理的会员数量
+)
@@ -25,19 +25,70 @@ var (
 
 // 商品模块相关错误码 10000200 ~ 1000299
 var (
-	ErrCommodityNotExists = newError(10000200, "商品不存在")
-	ErrCommodityStockOut  = newError(10000201, "库存不足")
+	ErrCommodityNotExists       = newError(10000200, "商品不存在")
+	ErrCommodityStockOut        = newError(10000201, "库存不足")
+	ErrCommodityCategoryInvalid = newError(10000202, "商品分类必须是已存在的三级分类")
+	ErrCommodityPriceInvalid    = newError(10000203, "商品价格不正确")
+	ErrCommoditySkuInvalid      = newError(10000204, "商品规格或SKU不正确")
+	ErrCategoryNotExists        = newError(10000205, "商品分类不存在")
+	ErrCategoryLevelInvalid     = newError(10000206, "分类层级不正确, 分类最多三级, 且比父分类低一级")
+	ErrCategoryNotEmpty         = newError(10000207, "分类下还有子分类或商品")
+	ErrCommodityOffShelf        = newError(10000208, "商品已下架")
 )
 
 // 购物车模块相关错误码 10000300 ～ 1000399
 var (
-	ErrCartItemParam = newError(10000300, "购物项参数异常")
-	ErrCartWrongUser = newError(10000301, "用户购物信息不匹配")
+	ErrCartItemParam     = newError(10000300, "购物项参数异常")
+	ErrCartWrongUser     = newError(10000301, "用户购物信息不匹配")
+	ErrCartFull          = newError(10000302, "购物车已满")
+	ErrCartPurchaseLimit = newError(10000303, "超过商品限购数量")
 )
 
 var (
 	ErrOrderParams          = newError(10000500, "订单参数异常")
 	ErrOrderCanNotBeChanged = newError(10000501, "订单不可修改")
+	ErrOrderNumLimit        = newError(10000502, "超过单笔订单的购买数量")
+)
+
+// 秒杀模块相关错误码 10000600 ~ 1000699
+var (
+	ErrSeckillNotExists      = newError(10000600, "秒杀活动不存在")
+	ErrSeckillNotStarted     = newError(10000601, "秒杀活动未开始")
+	ErrSeckillEnded          = newError(10000602, "秒杀活动已结束")
+	ErrSeckillSoldOut        = newError(10000603, "秒杀商品已抢完")
+	ErrSeckillUserLimit      = newError(10000604, "超过秒杀限购数量")
+	ErrSeckillResultNotFound = newError(10000605, "秒杀结果不存在或已过期")
+)
+
+// 评价模块相关错误码 10000700 ~ 1000799
+var (
+	ErrReviewNotAllowed = newError(10000700, "只能评价本人已确认收货订单中的商品")
+	ErrReviewDuplicated = newError(10000701, "该商品已评价")
+	ErrReviewNotExists  = newError(10000702, "评价不存在")
+)
+
+// 收藏模块相关错误码 10000800 ~ 1000899
+var (
+	ErrFavoriteNotExists = newError(10000800, "未收藏该商品")
+)
+
+// 优惠券模块相关错误码 10000900 ~ 10000999
+var (
+	ErrCouponNotExists    = newError(10000900, "优惠券不存在")
+	ErrCouponNotClaimable = newError(10000901, "优惠券不在领取时间内或已停止领取")
+	ErrCouponSoldOut      = newError(10000902, "优惠券已领完")
+	ErrCouponClaimLimit   = newError(10000903, "已达到优惠券的领取上限")
+	ErrCouponUnavailable  = newError(10000904, "优惠券不可用")
+)
+
+// 满减活动相关错误码 10001000 ~ 10001099
+var (
+	ErrDiscountNotExists = newError(10001000, "满减活动不存在")
+)
+
+// 积分相关错误码 10001100 ~ 10001199
+var (
+	ErrPointsInsufficient = newError(10001100, "积分不足")
 )
 
 func (e *AppError) HttpStatusCode() int {
@@ -3,8 +3,10 @@ package middleware
 import (
 	"github.com/Ian-zy0329/go-mall/common/app"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/config"
 	"github.com/Ian-zy0329/go-mall/logic/domainservice"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func AuthUser() gin.HandlerFunc {
@@ -32,3 +34,15 @@ func AuthUser() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AuthAdmin 校验用户是否为后台管理员, 需要在 AuthUser 之后使用
+func AuthAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !lo.Contains(config.App.Admin.UserIds, c.GetInt64("userId")) {
+			app.NewResponse(c).Error(errcode.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
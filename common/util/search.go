@@ -0,0 +1,103 @@
+package util
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NormalizeSearchQuery 规范化搜索词用于记录和补全: 去掉首尾空白, 连续空白合并为一个空格, 英文转为小写
+func NormalizeSearchQuery(keyword string) string {
+	return strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+}
+
+// SearchTerms 把搜索关键词拆分为用于匹配和高亮的词:
+// 英文和数字按连续的字母数字切分并转为小写, 中文按相邻两个字切分(与 MySQL ngram 分词一致), 单个汉字保留原样
+func SearchTerms(keyword string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]struct{})
+	addTerm := func(term string) {
+		if _, exists := seen[term]; term == "" || exists {
+			return
+		}
+		seen[term] = struct{}{}
+		terms = append(terms, term)
+	}
+	var run []rune
+	runIsHan := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runIsHan && len(run) > 1 {
+			for i := 0; i+1 < len(run); i++ {
+				addTerm(string(run[i : i+2]))
+			}
+		} else {
+			addTerm(strings.ToLower(string(run)))
+		}
+		run = run[:0]
+	}
+	for _, r := range keyword {
+		isHan := unicode.Is(unicode.Han, r)
+		if !isHan && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(run) > 0 && isHan != runIsHan {
+			flush()
+		}
+		runIsHan = isHan
+		run = append(run, r)
+	}
+	flush()
+	return terms
+}
+
+// HighlightTerms 用 <em></em> 标记文本中出现的搜索词(不区分大小写), 相邻或重叠的命中合并为一段,
+// 文本的其余部分会做HTML转义, 结果可以直接作为HTML展示
+func HighlightTerms(text string, terms []string) string {
+	textRunes := []rune(text)
+	lowerRunes := make([]rune, len(textRunes))
+	for i, r := range textRunes {
+		lowerRunes[i] = unicode.ToLower(r)
+	}
+	type hitRange struct{ start, end int }
+	hits := make([]hitRange, 0)
+	for _, term := range terms {
+		termRunes := []rune(strings.ToLower(term))
+		if len(termRunes) == 0 {
+			continue
+		}
+		for i := 0; i+len(termRunes) <= len(lowerRunes); i++ {
+			if string(lowerRunes[i:i+len(termRunes)]) == string(termRunes) {
+				hits = append(hits, hitRange{i, i + len(termRunes)})
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return html.EscapeString(text)
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].start < hits[j].start })
+	merged := []hitRange{hits[0]}
+	for _, hit := range hits[1:] {
+		last := &merged[len(merged)-1]
+		if hit.start <= last.end {
+			last.end = max(last.end, hit.end)
+			continue
+		}
+		merged = append(merged, hit)
+	}
+	var builder strings.Builder
+	cursor := 0
+	for _, hit := range merged {
+		builder.WriteString(html.EscapeString(string(textRunes[cursor:hit.start])))
+		builder.WriteString("<em>")
+		builder.WriteString(html.EscapeString(string(textRunes[hit.start:hit.end])))
+		builder.WriteString("</em>")
+		cursor = hit.end
+	}
+	builder.WriteString(html.EscapeString(string(textRunes[cursor:])))
+	return builder.String()
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"bytes"
 	"embed"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 	"os"
 )
@@ -28,4 +31,24 @@ func init() {
 	vp.UnmarshalKey("redis", &Redis)
 	vp.UnmarshalKey("redis_stock_service", &RedisStockServiceConfig)
 	RedisStockServiceConfig.Addr = redisStockAddr
+	if err = checkBillCheck(); err != nil {
+		panic(err)
+	}
+}
+
+// checkBillCheck 校验结算优惠的配置, 配置了不存在的优惠插件时启动失败, 而不是等到结算时才报错
+func checkBillCheck() error {
+	for _, name := range App.BillCheck.Chain {
+		if !lo.Contains(enum.BillCheckers, name) {
+			return fmt.Errorf("bill_check.chain: unknown bill checker %q", name)
+		}
+	}
+	for _, group := range App.BillCheck.Exclusive {
+		for _, name := range group {
+			if !lo.Contains(enum.BillCheckers, name) {
+				return fmt.Errorf("bill_check.exclusive: unknown bill checker %q", name)
+			}
+		}
+	}
+	return nil
 }
@@ -35,6 +35,47 @@ type appConfig struct {
 		DefaultSize int `mapstructure:"default_size"`
 		MaxSize     int `mapstructure:"max_size"`
 	}
+	Order struct {
+		StockReserveTTL time.Duration `mapstructure:"stock_reserve_ttl"` // 下单后库存预占的有效期, 超时未支付自动释放
+		MaxNum          int           `mapstructure:"max_num"`           // 单个订单最多购买的商品件数
+	}
+	StockCheck struct {
+		Interval time.Duration `mapstructure:"interval"` // 库存对账定时任务的执行间隔
+		Repair   string        `mapstructure:"repair"`   // 定时对账发现不一致时修复的一方: 空-只报告 redis mysql
+	} `mapstructure:"stock_check"`
+	Seckill struct {
+		RateLimit int   `mapstructure:"rate_limit"` // 单个活动每秒接收的秒杀请求数, 0-不限制
+		QueueMax  int64 `mapstructure:"queue_max"`  // 排队创建订单的请求数上限
+		Workers   int   `mapstructure:"workers"`    // 消费秒杀队列创建订单的协程数
+	}
+	Cart struct {
+		Storage     string        `mapstructure:"storage"`      // 用户购物车的存储: mysql(默认) redis
+		GuestExpire time.Duration `mapstructure:"guest_expire"` // 未登录用户的购物车在最后一次修改后保留的时长
+		MaxItems    int           `mapstructure:"max_items"`    // 用户购物车最多容纳的SKU数量
+		// 每个用户的购物车或单个订单中同一商品的最大数量, 商品设置了限购数量时以商品的为准并计入历史订单
+		CommodityMaxNum int `mapstructure:"commodity_max_num"`
+	}
+	BillCheck struct {
+		Chain       []string   `mapstructure:"chain"`         // 结算时依次计算的优惠, 取值见 enum.BillChecker*, 靠前的优惠在最低支付金额限制下优先抵扣
+		Exclusive   [][]string `mapstructure:"exclusive"`     // 互斥的优惠组, 同一组中只使用优惠金额最高的一项
+		MinPayMoney int        `mapstructure:"min_pay_money"` // 订单最低支付金额(分), 优惠不能让支付金额低于该金额
+	} `mapstructure:"bill_check"`
+	Vip struct {
+		Levels           []*VipLevelConfig `mapstructure:"levels"`
+		GrowthWindowDays int               `mapstructure:"growth_window_days"` // 按最近多少天的成长值计算会员等级
+		LevelDays        int               `mapstructure:"level_days"`         // 按成长值升级后会员等级的有效天数, 到期后按成长值重新计算
+		RefreshInterval  time.Duration     `mapstructure:"refresh_interval"`   // 重新计算会员等级的定时任务的执行间隔
+	}
+	Points struct {
+		EarnUnit       int           `mapstructure:"earn_unit"`       // 订单每支付多少金额(分)获得1积分
+		RedeemValue    int           `mapstructure:"redeem_value"`    // 每积分抵扣的金额(分)
+		MaxRedeemRate  int           `mapstructure:"max_redeem_rate"` // 积分最多抵扣商品金额的百分比
+		ExpireDays     int           `mapstructure:"expire_days"`     // 获得的积分多少天后过期
+		ExpireInterval time.Duration `mapstructure:"expire_interval"` // 处理过期积分的定时任务的执行间隔
+	}
+	Admin struct {
+		UserIds []int64 `mapstructure:"user_ids"` // 可以访问后台管理接口的用户ID
+	}
 	WechatPay struct {
 		AppId           string `mapstructure:"appid"`
 		MchId           string `mapstructure:"mchid"`
@@ -44,6 +85,16 @@ type appConfig struct {
 	}
 }
 
+// VipLevelConfig 会员等级, 等级数值越大权益越高
+type VipLevelConfig struct {
+	Level        int    `mapstructure:"level"`
+	Name         string `mapstructure:"name"`
+	DiscountRate int    `mapstructure:"discount_rate"` // 会员折扣减免的百分比
+	MinGrowth    int    `mapstructure:"min_growth"`    // 达到该等级需要的成长值, 0-只能购买
+	SkuId        int64  `mapstructure:"sku_id"`        // 购买该等级的商品SKU, 0-不能购买
+	Days         int    `mapstructure:"days"`          // 购买一件获得的会员天数
+}
+
 type databaseConfig struct {
 	Type   string          `mapstructure:"type"`
 	Master DbConnectOption `mapstructure:"master"`
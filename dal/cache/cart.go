@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GetGuestCartItems 获取未登录用户购物车中每个SKU的数量
+func GetGuestCartItems(ctx context.Context, cartToken string) (map[int64]int, error) {
+	values, err := Redis().HGetAll(ctx, enum.GUEST_CART_KEY_PREFIX+cartToken).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[int64]int, len(values))
+	for field, value := range values {
+		skuId, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		num, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		items[skuId] = num
+	}
+	return items, nil
+}
+
+// SetGuestCartItem 设置未登录用户购物车中SKU的数量, 并重新计算购物车的过期时间
+func SetGuestCartItem(ctx context.Context, cartToken string, skuId int64, num int, expire time.Duration) error {
+	redisKey := enum.GUEST_CART_KEY_PREFIX + cartToken
+	_, err := Redis().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisKey, strconv.FormatInt(skuId, 10), num)
+		pipe.Expire(ctx, redisKey, expire)
+		return nil
+	})
+	return err
+}
+
+// DelGuestCartItem 从未登录用户的购物车中删除SKU, 返回是否删除了数据
+func DelGuestCartItem(ctx context.Context, cartToken string, skuId int64) (bool, error) {
+	deleted, err := Redis().HDel(ctx, enum.GUEST_CART_KEY_PREFIX+cartToken, strconv.FormatInt(skuId, 10)).Result()
+	return deleted > 0, err
+}
+
+// DelGuestCart 删除未登录用户的整个购物车, 合并到用户购物车后调用
+func DelGuestCart(ctx context.Context, cartToken string) error {
+	return Redis().Del(ctx, enum.GUEST_CART_KEY_PREFIX+cartToken).Err()
+}
+
+// userCartEntry 用户购物车哈希中每个SKU对应的值
+type userCartEntry struct {
+	CartItemId   int64     `json:"cart_item_id"`
+	CommodityId  int64     `json:"commodity_id"`
+	CommodityNum int       `json:"commodity_num"`
+	AddedPrice   int       `json:"added_price"`
+	Selected     bool      `json:"selected"`
+	AddedAt      time.Time `json:"added_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// NewUserCartItemId 生成用户购物项的ID
+func NewUserCartItemId(ctx context.Context) (int64, error) {
+	return Redis().Incr(ctx, enum.CART_ITEM_ID_KEY).Result()
+}
+
+// EnsureUserCartItemIdAtLeast 保证生成的购物项ID大于 minId, 从MySQL迁移购物车后调用, 避免ID重复
+func EnsureUserCartItemIdAtLeast(ctx context.Context, minId int64) error {
+	current, err := Redis().Get(ctx, enum.CART_ITEM_ID_KEY).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if current >= minId {
+		return nil
+	}
+	return Redis().Set(ctx, enum.CART_ITEM_ID_KEY, minId, 0).Err()
+}
+
+// GetUserCartItems 获取用户购物车中的购物项, 按购物项ID排序
+func GetUserCartItems(ctx context.Context, userId int64) ([]*do.ShoppingCartItem, error) {
+	values, err := Redis().HGetAll(ctx, enum.USER_CART_KEY_PREFIX+strconv.FormatInt(userId, 10)).Result()
+	if err != nil {
+		return nil, err
+	}
+	cartItems := make([]*do.ShoppingCartItem, 0, len(values))
+	for field, value := range values {
+		skuId, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		cartItem, err := decodeUserCartEntry(userId, skuId, value)
+		if err != nil {
+			return nil, err
+		}
+		cartItems = append(cartItems, cartItem)
+	}
+	sort.Slice(cartItems, func(i, j int) bool { return cartItems[i].CartItemId < cartItems[j].CartItemId })
+	return cartItems, nil
+}
+
+// GetUserCartItem 获取用户购物车中指定SKU的购物项, 不存在时返回 nil
+func GetUserCartItem(ctx context.Context, userId, skuId int64) (*do.ShoppingCartItem, error) {
+	value, err := Redis().HGet(ctx, enum.USER_CART_KEY_PREFIX+strconv.FormatInt(userId, 10),
+		strconv.FormatInt(skuId, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserCartEntry(userId, skuId, value)
+}
+
+// SetUserCartItem 保存用户的购物项, 用户购物车不设置过期时间
+func SetUserCartItem(ctx context.Context, cartItem *do.ShoppingCartItem) error {
+	return SetUserCartItems(ctx, cartItem.UserId, []*do.ShoppingCartItem{cartItem})
+}
+
+// SetUserCartItems 批量保存同一用户的购物项
+func SetUserCartItems(ctx context.Context, userId int64, cartItems []*do.ShoppingCartItem) error {
+	if len(cartItems) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(cartItems)*2)
+	for _, cartItem := range cartItems {
+		data, err := json.Marshal(&userCartEntry{
+			CartItemId:   cartItem.CartItemId,
+			CommodityId:  cartItem.CommodityId,
+			CommodityNum: cartItem.CommodityNum,
+			AddedPrice:   cartItem.AddedPrice,
+			Selected:     cartItem.Selected,
+			AddedAt:      cartItem.CreatedAt,
+			UpdatedAt:    cartItem.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		values = append(values, strconv.FormatInt(cartItem.SkuId, 10), data)
+	}
+	return Redis().HSet(ctx, enum.USER_CART_KEY_PREFIX+strconv.FormatInt(userId, 10), values...).Err()
+}
+
+// DelUserCartItems 从用户的购物车中删除SKU
+func DelUserCartItems(ctx context.Context, userId int64, skuIds []int64) error {
+	if len(skuIds) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(skuIds))
+	for _, skuId := range skuIds {
+		fields = append(fields, strconv.FormatInt(skuId, 10))
+	}
+	return Redis().HDel(ctx, enum.USER_CART_KEY_PREFIX+strconv.FormatInt(userId, 10), fields...).Err()
+}
+
+func decodeUserCartEntry(userId, skuId int64, value string) (*do.ShoppingCartItem, error) {
+	entry := new(userCartEntry)
+	if err := json.Unmarshal([]byte(value), entry); err != nil {
+		return nil, err
+	}
+	return &do.ShoppingCartItem{
+		CartItemId:   entry.CartItemId,
+		UserId:       userId,
+		CommodityId:  entry.CommodityId,
+		SkuId:        skuId,
+		CommodityNum: entry.CommodityNum,
+		AddedPrice:   entry.AddedPrice,
+		Selected:     entry.Selected,
+		CreatedAt:    entry.AddedAt,
+		UpdatedAt:    entry.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// cacheMissingValue 数据不存在时写入缓存的值, 即 nil 的 JSON 编码
+const cacheMissingValue = "null"
+
+var loadGroup singleflight.Group
+
+// GetCommodityDetail 从缓存读取商品详情(包含详情内容), 未命中时调用 load 从MySQL加载并写回缓存,
+// 商品不存在时返回 nil
+func GetCommodityDetail(ctx context.Context, commodityId int64, load func() (*do.Commodity, error)) (*do.Commodity, error) {
+	redisKey := enum.COMMODITY_DETAIL_KEY_PREFIX + strconv.FormatInt(commodityId, 10)
+	return getOrLoad(ctx, redisKey, enum.CommodityDetailCacheTTL, load)
+}
+
+// DelCommodityDetail 删除商品详情缓存, 后台修改商品后调用
+func DelCommodityDetail(ctx context.Context, commodityIds ...int64) error {
+	if len(commodityIds) == 0 {
+		return nil
+	}
+	redisKeys := make([]string, 0, len(commodityIds))
+	for _, commodityId := range commodityIds {
+		redisKeys = append(redisKeys, enum.COMMODITY_DETAIL_KEY_PREFIX+strconv.FormatInt(commodityId, 10))
+	}
+	return Redis().Del(ctx, redisKeys...).Err()
+}
+
+// GetCategoryTree 从缓存读取层级分类树, 未命中时调用 load 重新构建并写回缓存
+func GetCategoryTree(ctx context.Context, load func() ([]*do.HierarchicCommodityCategory, error)) ([]*do.HierarchicCommodityCategory, error) {
+	return getOrLoad(ctx, enum.CATEGORY_TREE_KEY, enum.CategoryTreeCacheTTL, load)
+}
+
+// DelCategoryTree 删除分类树缓存, 后台修改分类后调用
+func DelCategoryTree(ctx context.Context) error {
+	return Redis().Del(ctx, enum.CATEGORY_TREE_KEY).Err()
+}
+
+// getOrLoad 按 cache-aside 方式读取缓存: 命中时直接解码返回; 未命中时同一个 key 的并发请求
+// 通过 singleflight 共享一次 load, 结果以JSON写回缓存, load 得到 nil 时按 CacheMissingTTL 缓存不存在.
+// Redis 读写出错只记录日志, 不影响从MySQL加载数据
+func getOrLoad[T any](ctx context.Context, redisKey string, ttl time.Duration, load func() (T, error)) (T, error) {
+	var value T
+	log := logger.New(ctx)
+	cached, err := Redis().Get(ctx, redisKey).Result()
+	if err == nil {
+		if err = json.Unmarshal([]byte(cached), &value); err == nil {
+			return value, nil
+		}
+		log.Error("CacheDecodeError", "key", redisKey, "err", err)
+	} else if !errors.Is(err, redis.Nil) {
+		log.Error("CacheGetError", "key", redisKey, "err", err)
+	}
+
+	loaded, err, _ := loadGroup.Do(redisKey, func() (interface{}, error) {
+		loadedValue, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loadedValue)
+		if err != nil {
+			log.Error("CacheEncodeError", "key", redisKey, "err", err)
+			return loadedValue, nil
+		}
+		expiration := jitterTTL(ttl)
+		if string(data) == cacheMissingValue {
+			expiration = jitterTTL(enum.CacheMissingTTL)
+		}
+		if err = Redis().Set(ctx, redisKey, data, expiration).Err(); err != nil {
+			log.Error("CacheSetError", "key", redisKey, "err", err)
+		}
+		return loadedValue, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	return loaded.(T), nil
+}
+
+// jitterTTL 在 ttl 的基础上随机延长最多 CacheTTLJitterRatio 比例的时间
+func jitterTTL(ttl time.Duration) time.Duration {
+	maxJitter := int64(float64(ttl) * enum.CacheTTLJitterRatio)
+	if maxJitter <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(maxJitter))
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+)
+
+// PushFavoritePriceDrops 把降价事件写入队列, 队列超过最大长度时丢弃最早的事件
+func PushFavoritePriceDrops(ctx context.Context, events []*do.FavoritePriceDrop) error {
+	if len(events) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+	_, err := Redis().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, enum.FAVORITE_PRICE_DROP_QUEUE_KEY, values...)
+		pipe.LTrim(ctx, enum.FAVORITE_PRICE_DROP_QUEUE_KEY, 0, enum.FavoritePriceDropQueueMaxLen-1)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"time"
+)
+
+// LockJob 获取定时任务的执行锁, 锁在 expire 后过期, 锁被其他实例持有时返回错误
+func LockJob(ctx context.Context, name string, expire time.Duration) (string, error) {
+	return acquireLock(ctx, Redis(), enum.JOB_LOCK_KEY_PREFIX+name, expire)
+}
+
+func UnlockJob(ctx context.Context, name, token string) error {
+	return releaseLock(ctx, Redis(), enum.JOB_LOCK_KEY_PREFIX+name, token)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// SetRelatedCommodities 用新计算的结果整体替换这些商品的相关商品
+func SetRelatedCommodities(ctx context.Context, relatedMap map[int64][]*do.RelatedCommodity) error {
+	_, err := Redis().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for commodityId, relatedList := range relatedMap {
+			redisKey := enum.RELATED_COMMODITY_KEY_PREFIX + strconv.FormatInt(commodityId, 10)
+			members := make([]redis.Z, 0, len(relatedList))
+			for _, related := range relatedList {
+				members = append(members, redis.Z{Score: float64(related.OrderCount), Member: related.CommodityId})
+			}
+			pipe.Del(ctx, redisKey)
+			pipe.ZAdd(ctx, redisKey, members...)
+			pipe.Expire(ctx, redisKey, enum.RelatedCacheExpire)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetRelatedCommodityIds 按一起购买的订单数从多到少获取商品的相关商品ID
+func GetRelatedCommodityIds(ctx context.Context, commodityId int64, limit int64) ([]int64, error) {
+	redisKey := enum.RELATED_COMMODITY_KEY_PREFIX + strconv.FormatInt(commodityId, 10)
+	members, err := Redis().ZRevRange(ctx, redisKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	commodityIds := make([]int64, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+			commodityIds = append(commodityIds, id)
+		}
+	}
+	return commodityIds, nil
+}
+
+// GetCategoryBestSellers 从缓存读取分类的热销商品, 未命中时调用 load 从MySQL加载
+func GetCategoryBestSellers(ctx context.Context, categoryId int64, load func() ([]*do.Commodity, error)) ([]*do.Commodity, error) {
+	redisKey := enum.CATEGORY_BEST_SELLERS_KEY_PREFIX + strconv.FormatInt(categoryId, 10)
+	return getOrLoad(ctx, redisKey, enum.CategoryBestSellersCacheTTL, load)
+}
+
+// LockRelatedRefresh 获取计算相关商品的任务锁, 其他实例正在计算时返回错误
+func LockRelatedRefresh(ctx context.Context) (string, error) {
+	return acquireLock(ctx, Redis(), enum.RELATED_REFRESH_LOCK_KEY, enum.RelatedRefreshLockExpire)
+}
+
+func UnlockRelatedRefresh(ctx context.Context, token string) error {
+	return releaseLock(ctx, Redis(), enum.RELATED_REFRESH_LOCK_KEY, token)
+}
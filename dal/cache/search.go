@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"strconv"
+	"time"
+)
+
+// RecordSearchQuery 记录一次搜索: 累加搜索词的衰减热度和当日搜索次数, 保存结果数量,
+// 无结果的搜索词记入无结果集合, 之后有了结果再从集合中移除
+func RecordSearchQuery(ctx context.Context, query string, resultCount int64, searchedAt time.Time) error {
+	weight := math.Exp2(float64(searchedAt.Sub(enum.SearchTrendEpoch)) / float64(enum.SearchTrendHalfLife))
+	hotDayKey := enum.SEARCH_HOT_DAY_KEY_PREFIX + searchedAt.Format(enum.TimeFormatYMD)
+	_, err := Redis().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZIncrBy(ctx, enum.SEARCH_TREND_KEY, weight, query)
+		pipe.ZAdd(ctx, enum.SEARCH_QUERY_LEX_KEY, redis.Z{Score: 0, Member: query})
+		pipe.HSet(ctx, enum.SEARCH_RESULT_COUNT_KEY, query, resultCount)
+		pipe.ZIncrBy(ctx, hotDayKey, 1, query)
+		pipe.Expire(ctx, hotDayKey, enum.SearchHotDayExpire)
+		if resultCount == 0 {
+			pipe.ZIncrBy(ctx, enum.SEARCH_ZERO_RESULT_KEY, 1, query)
+		} else {
+			pipe.ZRem(ctx, enum.SEARCH_ZERO_RESULT_KEY, query)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetSearchQueriesWithPrefix 按字典序取以 prefix 开头的搜索词
+func GetSearchQueriesWithPrefix(ctx context.Context, prefix string, limit int64) ([]string, error) {
+	return Redis().ZRangeByLex(ctx, enum.SEARCH_QUERY_LEX_KEY, &redis.ZRangeBy{
+		Min:   "[" + prefix,
+		Max:   "[" + prefix + "\xff",
+		Count: limit,
+	}).Result()
+}
+
+// GetSearchQueryStats 获取搜索词的热度和最近一次的结果数量, 没有记录的搜索词热度为0
+func GetSearchQueryStats(ctx context.Context, queries []string) (trends []float64, resultCounts []int64, err error) {
+	if len(queries) == 0 {
+		return nil, nil, nil
+	}
+	pipe := Redis().Pipeline()
+	trendCmd := pipe.ZMScore(ctx, enum.SEARCH_TREND_KEY, queries...)
+	countCmd := pipe.HMGet(ctx, enum.SEARCH_RESULT_COUNT_KEY, queries...)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, nil, err
+	}
+	trends = trendCmd.Val()
+	resultCounts = make([]int64, len(queries))
+	for i, value := range countCmd.Val() {
+		if str, ok := value.(string); ok {
+			resultCounts[i], _ = strconv.ParseInt(str, 10, 64)
+		}
+	}
+	return trends, resultCounts, nil
+}
+
+// GetHotSearchQueries 获取某一天搜索次数最多的搜索词
+func GetHotSearchQueries(ctx context.Context, day time.Time, limit int64) ([]*do.SearchQueryStat, error) {
+	hotDayKey := enum.SEARCH_HOT_DAY_KEY_PREFIX + day.Format(enum.TimeFormatYMD)
+	members, err := Redis().ZRevRangeWithScores(ctx, hotDayKey, 0, limit-1).Result()
+	return toSearchQueryStats(members), err
+}
+
+// GetZeroResultQueries 获取搜索次数最多的无结果搜索词
+func GetZeroResultQueries(ctx context.Context, limit int64) ([]*do.SearchQueryStat, error) {
+	members, err := Redis().ZRevRangeWithScores(ctx, enum.SEARCH_ZERO_RESULT_KEY, 0, limit-1).Result()
+	return toSearchQueryStats(members), err
+}
+
+// TrimSearchQueries 只保留热度最高的 keepSize 个搜索词, 返回清理的数量; 无结果集合不清理, 留给运营处理
+func TrimSearchQueries(ctx context.Context, keepSize int64) (int, error) {
+	coldQueries, err := Redis().ZRange(ctx, enum.SEARCH_TREND_KEY, 0, -keepSize-1).Result()
+	if err != nil || len(coldQueries) == 0 {
+		return 0, err
+	}
+	members := make([]interface{}, 0, len(coldQueries))
+	for _, query := range coldQueries {
+		members = append(members, query)
+	}
+	_, err = Redis().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, enum.SEARCH_TREND_KEY, members...)
+		pipe.ZRem(ctx, enum.SEARCH_QUERY_LEX_KEY, members...)
+		pipe.HDel(ctx, enum.SEARCH_RESULT_COUNT_KEY, coldQueries...)
+		return nil
+	})
+	return len(coldQueries), err
+}
+
+func toSearchQueryStats(members []redis.Z) []*do.SearchQueryStat {
+	stats := make([]*do.SearchQueryStat, 0, len(members))
+	for _, member := range members {
+		query, _ := member.Member.(string)
+		stats = append(stats, &do.SearchQueryStat{Query: query, Count: int64(member.Score)})
+	}
+	return stats
+}
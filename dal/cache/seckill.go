@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+var (
+	seckillDeductScript  = loadLuaScript("seckill_deduct.lua")
+	seckillRevertScript  = loadLuaScript("seckill_revert.lua")
+	seckillRequeueScript = loadLuaScript("seckill_requeue.lua")
+)
+
+func seckillCampaignKeys(campaignId int64) (campaignKey, logKey, userKey string) {
+	campaignKey = fmt.Sprintf("%s%d", enum.SECKILL_CAMPAIGN_KEY_PREFIX, campaignId)
+	logKey = fmt.Sprintf("%s%d", enum.SECKILL_LOG_KEY_PREFIX, campaignId)
+	userKey = fmt.Sprintf("%s%d", enum.SECKILL_USER_KEY_PREFIX, campaignId)
+	return
+}
+
+// InitSeckillCampaign 把活动名额加载到Redis, 只补齐不存在的字段, 不会覆盖活动进行中已被抢走的名额
+func InitSeckillCampaign(ctx context.Context, campaign *do.SeckillCampaign) error {
+	campaignKey, _, _ := seckillCampaignKeys(campaign.ID)
+	initFields := map[string]interface{}{
+		"id":        campaign.ID,
+		"quota":     campaign.Quota,
+		"sold":      0,
+		"userLimit": campaign.UserLimit,
+		"startAt":   campaign.StartTime.Unix(),
+		"endAt":     campaign.EndTime.Unix(),
+		"version":   1,
+		"modified":  time.Now().Format(time.RFC3339),
+	}
+	pipeline := RedisStockService().Pipeline()
+	for field, value := range initFields {
+		pipeline.HSetNX(ctx, campaignKey, field, value)
+	}
+	pipeline.ExpireAt(ctx, campaignKey, campaign.EndTime.Add(enum.SeckillCampaignKeyExtraExpire))
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// DeductSeckillQuota 预扣秒杀名额, 成功后请求进入下单队列, 结果为排队中
+func DeductSeckillQuota(ctx context.Context, request *do.SeckillRequest, rateLimit int, queueMax int64) error {
+	campaignKey, logKey, userKey := seckillCampaignKeys(request.CampaignId)
+	now := time.Now()
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resultData, err := json.Marshal(&do.SeckillResult{
+		RequestId:  request.RequestId,
+		CampaignId: request.CampaignId,
+		UserId:     request.UserId,
+		Status:     enum.SeckillResultQueued,
+	})
+	if err != nil {
+		return err
+	}
+	keys := []string{
+		campaignKey,
+		logKey,
+		userKey,
+		enum.SECKILL_QUEUE_KEY,
+		enum.SECKILL_RESULT_KEY_PREFIX + request.RequestId,
+		fmt.Sprintf("%s%d:%d", enum.SECKILL_RATE_KEY_PREFIX, request.CampaignId, now.Unix()),
+	}
+	result, err := seckillDeductScript.Run(ctx, RedisStockService(), keys,
+		request.UserId,
+		request.Num,
+		now.Unix(),
+		now.Format(time.RFC3339),
+		request.RequestId,
+		requestData,
+		resultData,
+		int64(enum.SeckillResultExpire.Seconds()),
+		rateLimit,
+		queueMax,
+	).Result()
+	if err != nil {
+		return err
+	}
+	return parseStockScriptResult(result)
+}
+
+// RevertSeckillQuota 退回秒杀请求预扣的名额
+func RevertSeckillQuota(ctx context.Context, request *do.SeckillRequest) error {
+	campaignKey, logKey, userKey := seckillCampaignKeys(request.CampaignId)
+	keys := []string{campaignKey, logKey, userKey}
+	result, err := seckillRevertScript.Run(ctx, RedisStockService(), keys,
+		request.UserId, request.Num, time.Now().Format(time.RFC3339), request.RequestId).Result()
+	if err != nil {
+		return err
+	}
+	return parseStockScriptResult(result)
+}
+
+// GetSeckillQuota 获取活动在Redis中的名额, 活动未加载时返回 nil
+func GetSeckillQuota(ctx context.Context, campaignId int64) (*do.SeckillQuota, error) {
+	campaignKey, _, _ := seckillCampaignKeys(campaignId)
+	fields, err := RedisStockService().HGetAll(ctx, campaignKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	quota := &do.SeckillQuota{CampaignId: campaignId}
+	quota.Remaining, _ = strconv.Atoi(fields["quota"])
+	quota.Sold, _ = strconv.Atoi(fields["sold"])
+	return quota, nil
+}
+
+// PopSeckillRequest 从下单队列取出一个秒杀请求并同时放入处理中列表, 记录取出的时间, timeout 内队列为空时返回 nil;
+// 写入处理结果后需要调用 AckSeckillRequest 从处理中列表移除, 返回的 payload 为请求在列表中的原始数据
+func PopSeckillRequest(ctx context.Context, timeout time.Duration) (*do.SeckillRequest, string, error) {
+	payload, err := RedisStockService().BLMove(ctx, enum.SECKILL_QUEUE_KEY, enum.SECKILL_PROCESSING_KEY, "RIGHT", "LEFT", timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	// 取出时间记录失败时, 由 GetProcessingSeckillRequests 第一次看到请求时补记
+	_ = RedisStockService().ZAdd(ctx, enum.SECKILL_PROCESSING_AT_KEY, redis.Z{Score: float64(time.Now().Unix()), Member: payload}).Err()
+	request := new(do.SeckillRequest)
+	if err = json.Unmarshal([]byte(payload), request); err != nil {
+		// 无法解析的请求不会处理成功, 直接移除
+		_ = AckSeckillRequest(ctx, payload)
+		return nil, "", err
+	}
+	return request, payload, nil
+}
+
+// AckSeckillRequest 秒杀请求已经写入处理结果, 从处理中列表移除
+func AckSeckillRequest(ctx context.Context, payload string) error {
+	_, err := RedisStockService().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, enum.SECKILL_PROCESSING_KEY, 1, payload)
+		pipe.ZRem(ctx, enum.SECKILL_PROCESSING_AT_KEY, payload)
+		return nil
+	})
+	return err
+}
+
+// GetProcessingSeckillRequests 获取处理中列表里的秒杀请求和它们从队列取出的时间, 无法解析的请求会被移除;
+// 没有记录取出时间的请求以本次检查的时间补记
+func GetProcessingSeckillRequests(ctx context.Context) ([]*do.ProcessingSeckillRequest, error) {
+	payloads, err := RedisStockService().LRange(ctx, enum.SECKILL_PROCESSING_KEY, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	requests := make([]*do.ProcessingSeckillRequest, 0, len(payloads))
+	for _, payload := range payloads {
+		request := new(do.SeckillRequest)
+		if err = json.Unmarshal([]byte(payload), request); err != nil {
+			_ = AckSeckillRequest(ctx, payload)
+			continue
+		}
+		poppedAt, err := RedisStockService().ZScore(ctx, enum.SECKILL_PROCESSING_AT_KEY, payload).Result()
+		if errors.Is(err, redis.Nil) {
+			poppedAt = float64(now.Unix())
+			err = RedisStockService().ZAddNX(ctx, enum.SECKILL_PROCESSING_AT_KEY, redis.Z{Score: poppedAt, Member: payload}).Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, &do.ProcessingSeckillRequest{
+			Payload:  payload,
+			Request:  request,
+			PoppedAt: time.Unix(int64(poppedAt), 0),
+		})
+	}
+	return requests, nil
+}
+
+// RequeueSeckillRequest 把处理中列表里的请求放回下单队列的出队端, 请求已经不在处理中列表时不放回
+func RequeueSeckillRequest(ctx context.Context, payload string) error {
+	keys := []string{enum.SECKILL_PROCESSING_KEY, enum.SECKILL_PROCESSING_AT_KEY, enum.SECKILL_QUEUE_KEY}
+	return seckillRequeueScript.Run(ctx, RedisStockService(), keys, payload).Err()
+}
+
+// SetSeckillResult 写入秒杀请求的最终处理结果
+func SetSeckillResult(ctx context.Context, result *do.SeckillResult) error {
+	resultData, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return RedisStockService().Set(ctx, enum.SECKILL_RESULT_KEY_PREFIX+result.RequestId, resultData, enum.SeckillResultExpire).Err()
+}
+
+// GetSeckillResult 获取秒杀请求的处理结果, 不存在或已过期时返回 nil
+func GetSeckillResult(ctx context.Context, requestId string) (*do.SeckillResult, error) {
+	resultData, err := RedisStockService().Get(ctx, enum.SECKILL_RESULT_KEY_PREFIX+requestId).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := new(do.SeckillResult)
+	if err = json.Unmarshal(resultData, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
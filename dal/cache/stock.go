@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/resources"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StockSettleConfirm = "confirm" // 支付成功, 预占转为已售
+	StockSettleRelease = "release" // 取消或超时, 预占退回可售
+)
+
+var (
+	reserveStockScript = loadLuaScript("reserve_stock.lua")
+	settleStockScript  = loadLuaScript("settle_stock_reservation.lua")
+	repairStockScript  = loadLuaScript("repair_stock.lua")
+	adjustStockScript  = loadLuaScript("adjust_stock.lua")
+)
+
+// ErrStockReservationNotFound 订单的库存预占不存在(已被确认、释放或从未预占)
+var ErrStockReservationNotFound = errors.New("stock reservation not found")
+
+// StockScriptError 库存 Lua 脚本返回的业务错误, ItemId 为出错的商品ID(脚本未指明时为0)
+type StockScriptError struct {
+	Code   string
+	Msg    string
+	ItemId int64
+}
+
+func (e *StockScriptError) Error() string {
+	return fmt.Sprintf("%s: %s, itemId: %d", e.Code, e.Msg, e.ItemId)
+}
+
+func loadLuaScript(fileName string) *redis.Script {
+	fileReader, err := resources.LoadResourceFile(fileName)
+	if err != nil {
+		panic(err)
+	}
+	scriptContent, err := io.ReadAll(fileReader)
+	if err != nil {
+		panic(err)
+	}
+	return redis.NewScript(string(scriptContent))
+}
+
+// stockItemKeys 库存脚本中每个商品依次传入 库存key, 库存流水key, 库存锁key
+func stockItemKeys(itemIds []int64) []string {
+	keys := make([]string, 0, len(itemIds)*3)
+	for _, itemId := range itemIds {
+		keys = append(keys,
+			fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, itemId),
+			fmt.Sprintf("%s%d", enum.STOCK_LOG_KEY_PREFIX, itemId),
+			fmt.Sprintf("%s%d", enum.STOCK_LOCK_KEY_PREFIX, itemId),
+		)
+	}
+	return keys
+}
+
+// parseStockScriptResult 解析库存脚本的返回 {"SUCCESS", ...} 或 {"err", code, msg, stockKey}
+func parseStockScriptResult(result interface{}) error {
+	resultList, ok := result.([]interface{})
+	if !ok || len(resultList) == 0 {
+		return errors.New("unknown stock script result")
+	}
+	if resultList[0] == "SUCCESS" {
+		return nil
+	}
+	scriptErr := new(StockScriptError)
+	if len(resultList) > 1 {
+		scriptErr.Code, _ = resultList[1].(string)
+	}
+	if len(resultList) > 2 {
+		scriptErr.Msg, _ = resultList[2].(string)
+	}
+	if len(resultList) > 3 {
+		stockKey, _ := resultList[3].(string)
+		scriptErr.ItemId, _ = strconv.ParseInt(strings.TrimPrefix(stockKey, enum.STOCK_KEY_PREFIX), 10, 64)
+	}
+	return scriptErr
+}
+
+// ReserveOrderStock 为订单预占库存, deadline 之前未确认的预占会被定时任务释放
+func ReserveOrderStock(ctx context.Context, orderNo string, userId int64, items []*do.OrderItem, deadline time.Time) error {
+	itemIds := make([]int64, 0, len(items))
+	args := []interface{}{
+		orderNo,
+		userId,
+		deadline.Unix(),
+		time.Now().Format(time.RFC3339),
+	}
+	for _, item := range items {
+		itemIds = append(itemIds, item.SkuId)
+		args = append(args, item.CommodityNum)
+	}
+	keys := append([]string{
+		enum.STOCK_RESERVE_KEY_PREFIX + orderNo,
+		enum.STOCK_RESERVE_DEADLINE_KEY,
+	}, stockItemKeys(itemIds)...)
+	result, err := reserveStockScript.Run(ctx, RedisStockService(), keys, args...).Result()
+	if err != nil {
+		return err
+	}
+	return parseStockScriptResult(result)
+}
+
+// SettleOrderStockReservation 确认或释放订单的库存预占, 预占不存在时返回 ErrStockReservationNotFound
+func SettleOrderStockReservation(ctx context.Context, orderNo string, settleType string) error {
+	reserveKey := enum.STOCK_RESERVE_KEY_PREFIX + orderNo
+	// 预占涉及的商品以预占明细为准, 订单数据写入失败时也能正确释放
+	itemFields, err := RedisStockService().HKeys(ctx, reserveKey).Result()
+	if err != nil {
+		return err
+	}
+	itemIds := make([]int64, 0, len(itemFields))
+	for _, itemField := range itemFields {
+		// 除商品ID外, 已确认的预占还有一个 settled 标记字段
+		if itemId, err := strconv.ParseInt(itemField, 10, 64); err == nil {
+			itemIds = append(itemIds, itemId)
+		}
+	}
+	keys := append([]string{reserveKey, enum.STOCK_RESERVE_DEADLINE_KEY}, stockItemKeys(itemIds)...)
+	result, err := settleStockScript.Run(ctx, RedisStockService(), keys, orderNo, settleType, time.Now().Format(time.RFC3339),
+		int64(enum.StockReservationRetention.Seconds())).Result()
+	if err != nil {
+		return err
+	}
+	err = parseStockScriptResult(result)
+	var scriptErr *StockScriptError
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_RESERVATION_NOT_FOUND" {
+		return ErrStockReservationNotFound
+	}
+	return err
+}
+
+// GetConfirmedStockReservations 返回 orderNos 中库存预占已经确认的订单号
+func GetConfirmedStockReservations(ctx context.Context, orderNos []string) (map[string]bool, error) {
+	cmds := make([]*redis.StringCmd, len(orderNos))
+	_, err := RedisStockService().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, orderNo := range orderNos {
+			cmds[i] = pipe.HGet(ctx, enum.STOCK_RESERVE_KEY_PREFIX+orderNo, "settled")
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	confirmed := make(map[string]bool, len(orderNos))
+	for i, cmd := range cmds {
+		if cmd.Val() == StockSettleConfirm {
+			confirmed[orderNos[i]] = true
+		}
+	}
+	return confirmed, nil
+}
+
+// GetExpiredStockReservations 获取预占已到期的订单号
+func GetExpiredStockReservations(ctx context.Context, now time.Time, limit int64) ([]string, error) {
+	return RedisStockService().ZRangeByScore(ctx, enum.STOCK_RESERVE_DEADLINE_KEY, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: limit,
+	}).Result()
+}
+
+// InitStockItems 初始化商品的Redis库存并登记到 mall:stock:init
+// 只补齐不存在的字段, 不能用MySQL的库存覆盖Redis中正在扣减的库存, 两边的差异交给库存对账处理
+func InitStockItems(ctx context.Context, stockItems []*do.StockItem) error {
+	pipeline := RedisStockService().Pipeline()
+	for _, stockItem := range stockItems {
+		stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, stockItem.ItemID)
+		initFields := map[string]interface{}{
+			"id":        stockItem.ItemID,
+			"stock":     stockItem.Stock,
+			"reserved":  0,
+			"sold":      0,
+			"version":   stockItem.Version,
+			"modified":  stockItem.Modified.Format(time.RFC3339),
+			"initStock": stockItem.InitStock,
+		}
+		for field, value := range initFields {
+			pipeline.HSetNX(ctx, stockKey, field, value)
+		}
+		// 库存key不设置过期时间, 过期后重新预热只能拿到MySQL库存, 会丢掉未结算的预占
+		pipeline.SAdd(ctx, enum.STOCK_INIT_SETKEY, stockItem.ItemID)
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// StockItemExists 商品的Redis库存key是否存在
+func StockItemExists(ctx context.Context, itemId int64) (bool, error) {
+	stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, itemId)
+	n, err := RedisStockService().Exists(ctx, stockKey).Result()
+	return n > 0, err
+}
+
+// GetStockItem 获取商品在Redis中的库存数据, 库存key不存在时返回 nil
+func GetStockItem(ctx context.Context, itemId int64) (*do.StockItem, error) {
+	stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, itemId)
+	fields, err := RedisStockService().HGetAll(ctx, stockKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	stockItem := &do.StockItem{ItemID: itemId}
+	stockItem.Stock, _ = strconv.Atoi(fields["stock"])
+	stockItem.Reserved, _ = strconv.Atoi(fields["reserved"])
+	stockItem.Sold, _ = strconv.Atoi(fields["sold"])
+	stockItem.InitStock, _ = strconv.Atoi(fields["initStock"])
+	stockItem.Version, _ = strconv.ParseInt(fields["version"], 10, 64)
+	stockItem.Modified, _ = time.Parse(time.RFC3339, fields["modified"])
+	return stockItem, nil
+}
+
+// GetStockNums 批量获取商品在Redis中的当前库存, 库存key不存在的商品不在返回结果中
+func GetStockNums(ctx context.Context, itemIds []int64) (map[int64]int, error) {
+	cmds := make([]*redis.StringCmd, len(itemIds))
+	_, err := RedisStockService().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, itemId := range itemIds {
+			cmds[i] = pipe.HGet(ctx, fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, itemId), "stock")
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	stockNums := make(map[int64]int, len(itemIds))
+	for i, cmd := range cmds {
+		stock, err := cmd.Int()
+		if err != nil {
+			continue
+		}
+		stockNums[itemIds[i]] = stock
+	}
+	return stockNums, nil
+}
+
+// GetInitializedStockItemIds 获取已经初始化过Redis库存的商品ID
+func GetInitializedStockItemIds(ctx context.Context) ([]int64, error) {
+	members, err := RedisStockService().SMembers(ctx, enum.STOCK_INIT_SETKEY).Result()
+	if err != nil {
+		return nil, err
+	}
+	itemIds := make([]int64, 0, len(members))
+	for _, member := range members {
+		if itemId, err := strconv.ParseInt(member, 10, 64); err == nil {
+			itemIds = append(itemIds, itemId)
+		}
+	}
+	return itemIds, nil
+}
+
+// RepairRedisStock 把Redis中商品的 可售+预占 修复为 expectedStock, 调用方需要持有商品的库存锁
+func RepairRedisStock(ctx context.Context, itemId int64, expectedStock int, lockToken string) error {
+	keys := stockItemKeys([]int64{itemId})
+	result, err := repairStockScript.Run(ctx, RedisStockService(), keys, expectedStock, time.Now().Format(time.RFC3339), lockToken).Result()
+	if err != nil {
+		return err
+	}
+	return parseStockScriptResult(result)
+}
+
+// AdjustRedisStock 把Redis中商品的可售库存增加 delta(可以为负), 调整后的库存不能小于0, 调用方需要持有商品的库存锁
+func AdjustRedisStock(ctx context.Context, itemId int64, delta int, lockToken string) error {
+	keys := stockItemKeys([]int64{itemId})
+	result, err := adjustStockScript.Run(ctx, RedisStockService(), keys, delta, time.Now().Format(time.RFC3339), lockToken).Result()
+	if err != nil {
+		return err
+	}
+	return parseStockScriptResult(result)
+}
+
+// LockStockItem 获取单个商品的库存锁, 未抢到锁时返回错误
+func LockStockItem(ctx context.Context, itemId int64, expire time.Duration) (string, error) {
+	lockKey := fmt.Sprintf("%s%d", enum.STOCK_LOCK_KEY_PREFIX, itemId)
+	return acquireLock(ctx, RedisStockService(), lockKey, expire)
+}
+
+// LockStockWarmUp 获取单个商品库存预热的锁, 同一商品同时只有一个请求从MySQL加载库存
+func LockStockWarmUp(ctx context.Context, itemId int64, expire time.Duration) (string, error) {
+	lockKey := fmt.Sprintf("%s%d", enum.STOCK_WARMUP_LOCK_KEY_PREFIX, itemId)
+	return acquireLock(ctx, RedisStockService(), lockKey, expire)
+}
+
+func UnlockStockWarmUp(ctx context.Context, itemId int64, token string) error {
+	lockKey := fmt.Sprintf("%s%d", enum.STOCK_WARMUP_LOCK_KEY_PREFIX, itemId)
+	return releaseLock(ctx, RedisStockService(), lockKey, token)
+}
+
+func UnlockStockItem(ctx context.Context, itemId int64, token string) error {
+	lockKey := fmt.Sprintf("%s%d", enum.STOCK_LOCK_KEY_PREFIX, itemId)
+	return releaseLock(ctx, RedisStockService(), lockKey, token)
+}
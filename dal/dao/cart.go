@@ -19,32 +19,41 @@ func NewCartDao(ctx context.Context) *CartDao {
 	}
 }
 
-func (cd *CartDao) GetUserCartItemWithCommodityId(userId, commodityId int64) (*model.ShoppingCartItem, error) {
+// GetUserCartItemWithSkuId 获取用户购物车中指定SKU的购物项, 加购前读取, 从主库读避免刚写入的数据还未同步
+func (cd *CartDao) GetUserCartItemWithSkuId(userId, skuId int64) (*model.ShoppingCartItem, error) {
 	cartItemModel := new(model.ShoppingCartItem)
-	err := DB().WithContext(cd.ctx).Where(model.ShoppingCartItem{UserId: userId, CommodityId: commodityId},
-		"UserId", "CommodityId").Find(cartItemModel).Error
+	err := DBMaster().WithContext(cd.ctx).Where(model.ShoppingCartItem{UserId: userId, SkuId: skuId},
+		"UserId", "SkuId").Find(cartItemModel).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	return cartItemModel, nil
 }
 
+// UpdateCartItem 更新购物项的数量和勾选状态
 func (cd *CartDao) UpdateCartItem(cartItem *model.ShoppingCartItem) error {
-	return DB().WithContext(cd.ctx).Model(cartItem).Updates(cartItem).Error
+	return DBMaster().WithContext(cd.ctx).Model(cartItem).Select("CommodityNum", "Selected").Updates(cartItem).Error
 }
 
+// AddCartItem 添加购物项, 创建后回填购物项ID
 func (cd *CartDao) AddCartItem(cartItem *do.ShoppingCartItem) error {
 	cartItemModel := new(model.ShoppingCartItem)
 	err := util.CopyProperties(&cartItemModel, cartItem)
 	if err != nil {
 		return errcode.ErrCoverData.WithCause(err)
 	}
-	return DB().WithContext(cd.ctx).Create(cartItemModel).Error
+	if err = DBMaster().WithContext(cd.ctx).Create(cartItemModel).Error; err != nil {
+		return err
+	}
+	cartItem.CartItemId = cartItemModel.CartItemId
+	return nil
 }
 
-func (cd *CartDao) FindCartItems(cartItemIds []int64) ([]*model.ShoppingCartItem, error) {
+// FindUserCartItems 获取用户购物车中指定ID的购物项, 不属于该用户的购物项不会返回
+func (cd *CartDao) FindUserCartItems(userId int64, cartItemIds []int64) ([]*model.ShoppingCartItem, error) {
 	items := make([]*model.ShoppingCartItem, 0)
-	err := DB().WithContext(cd.ctx).Find(&items, cartItemIds).Error
+	err := DB().WithContext(cd.ctx).Where("user_id = ? AND cart_item_id IN ?", userId, cartItemIds).
+		Find(&items).Error
 	return items, err
 }
 
@@ -56,14 +65,49 @@ func (cd *CartDao) GetCartItemById(cartItemId int64) (*model.ShoppingCartItem, e
 
 func (cd *CartDao) GetUserCartItems(userId int64) ([]*model.ShoppingCartItem, error) {
 	cartItemModels := make([]*model.ShoppingCartItem, 0)
-	err := DB().WithContext(cd.ctx).Where(model.ShoppingCartItem{UserId: userId}, "UserId").Find(&cartItemModels).Error
+	err := DB().WithContext(cd.ctx).Where(model.ShoppingCartItem{UserId: userId}, "UserId").
+		Order("cart_item_id").Find(&cartItemModels).Error
 	return cartItemModels, err
 }
 
-func (cd *CartDao) DeleteCartItem(cartItem *model.ShoppingCartItem) error {
-	return DB().WithContext(cd.ctx).Delete(cartItem).Error
+// UpdateUserCartItemsSelected 设置用户购物车中指定ID的购物项是否勾选
+func (cd *CartDao) UpdateUserCartItemsSelected(userId int64, cartItemIds []int64, selected bool) error {
+	return DBMaster().WithContext(cd.ctx).Model(&model.ShoppingCartItem{}).
+		Where("user_id = ? AND cart_item_id IN ?", userId, cartItemIds).
+		Update("selected", selected).Error
+}
+
+// DeleteUserCartItems 删除用户购物车中指定ID的购物项
+func (cd *CartDao) DeleteUserCartItems(userId int64, cartItemIds []int64) error {
+	return DBMaster().WithContext(cd.ctx).Where("user_id = ? AND cart_item_id IN ?", userId, cartItemIds).
+		Delete(&model.ShoppingCartItem{}).Error
+}
+
+// GetCartItemsAfter 按购物项ID顺序分批获取所有用户的购物项, 迁移购物车存储时使用
+func (cd *CartDao) GetCartItemsAfter(afterId int64, limit int) ([]*model.ShoppingCartItem, error) {
+	cartItemModels := make([]*model.ShoppingCartItem, 0, limit)
+	err := DB().WithContext(cd.ctx).Where("cart_item_id > ?", afterId).
+		Order("cart_item_id").Limit(limit).Find(&cartItemModels).Error
+	return cartItemModels, err
 }
 
-func (cd *CartDao) DeleteMultiCartItemInTx(tx *gorm.DB, cartIdList []int64) error {
-	return tx.WithContext(cd.ctx).Delete(&model.ShoppingCartItem{}, cartIdList).Error
+// MigrateCartSchema 为购物车表增加勾选和加购价格字段, 已存在的字段会跳过
+func (cd *CartDao) MigrateCartSchema() error {
+	migrator := DBMaster().WithContext(cd.ctx).Migrator()
+	newColumns := []struct {
+		model interface{}
+		field string
+	}{
+		{&model.ShoppingCartItem{}, "Selected"},
+		{&model.ShoppingCartItem{}, "AddedPrice"},
+	}
+	for _, column := range newColumns {
+		if migrator.HasColumn(column.model, column.field) {
+			continue
+		}
+		if err := migrator.AddColumn(column.model, column.field); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -15,7 +15,6 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"io"
-	"strconv"
 	"time"
 )
 
@@ -105,6 +104,12 @@ func (cd *CommodityDao) GetCategoryById(categoryId int64) (*model.CommodityCateg
 	return category, err
 }
 
+func (cd *CommodityDao) FindCategories(categoryIds []int64) ([]*model.CommodityCategory, error) {
+	categories := make([]*model.CommodityCategory, 0)
+	err := DB().WithContext(cd.ctx).Find(&categories, categoryIds).Error
+	return categories, err
+}
+
 func (cd *CommodityDao) getSubCategoryIdList(parentCategoryIds []int64) (categoryIds []int64, err error) {
 	err = DB().WithContext(cd.ctx).Model(model.CommodityCategory{}).
 		Where("parent_id IN (?)", parentCategoryIds).
@@ -113,27 +118,6 @@ func (cd *CommodityDao) getSubCategoryIdList(parentCategoryIds []int64) (categor
 	return
 }
 
-func (cd *CommodityDao) GetCommoditiesListInCategory(categoryIds []int64, offset, size int) (commodityModels []*model.Commodity, totalRows int64, err error) {
-	err = DB().WithContext(cd.ctx).Omit("detail_content").
-		Where("category_id IN (?)", categoryIds).
-		Offset(offset).
-		Limit(size).
-		Find(&commodityModels).Error
-
-	DB().WithContext(cd.ctx).Model(model.Commodity{}).Where("category_id IN (?)", categoryIds).Count(&totalRows)
-	return
-}
-
-func (cd *CommodityDao) FindCommodityWithNameKeyword(keyword string, offset, returnSize int) (commodityList []*model.Commodity, totalRows int64, err error) {
-	err = DB().WithContext(cd.ctx).Omit("detail_content").
-		Where("name LIKE ?", "%"+keyword+"%").
-		Offset(offset).Limit(returnSize).
-		Find(&commodityList).Error
-	DB().WithContext(cd.ctx).Model(model.Commodity{}).Where("name LIKE ?", "%"+keyword+"%").Count(&totalRows)
-
-	return
-}
-
 func (cd *CommodityDao) FindCommodityById(commodityId int64) (*model.Commodity, error) {
 	commodity := new(model.Commodity)
 	err := DB().WithContext(cd.ctx).Where("id = ?", commodityId).Find(commodity).Error
@@ -146,24 +130,6 @@ func (cd *CommodityDao) FindCommodities(commodityIdList []int64) ([]*model.Commo
 	return commodities, err
 }
 
-func (cd *CommodityDao) ReduceStuckInOrderCreate(tx *gorm.DB, orderItems []*do.OrderItem) error {
-	for _, orderItem := range orderItems {
-		commodity := new(model.Commodity)
-		tx.Clauses(clause.Locking{Strength: "UPDATE"}).WithContext(cd.ctx).
-			Find(commodity, orderItem.CommodityId)
-		newStock := commodity.StockNum - orderItem.CommodityNum
-		if newStock < 0 {
-			return errcode.ErrCommodityStockOut.WithCause(errors.New("商品缺少库存，商品ID:" + strconv.FormatInt(commodity.ID, 10)))
-		}
-		commodity.StockNum = newStock
-		err := tx.WithContext(cd.ctx).Model(commodity).Update("stock_num", newStock).Error
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // ReduceStuckInOrderCreateByLua 库存原子扣减
 func (cd *CommodityDao) ReduceStuckInOrderCreateByLua(tx *gorm.DB, orderItems []*do.OrderItem, userId int64) error {
 	redisStockService := cache.RedisStockService()
@@ -179,9 +145,9 @@ func (cd *CommodityDao) ReduceStuckInOrderCreateByLua(tx *gorm.DB, orderItems []
 		return errcode.Wrap("failed to load lua script: %w", err)
 	}
 	for _, orderItem := range orderItems {
-		stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, orderItem.CommodityId)
-		logKey := fmt.Sprintf("%s%d", enum.STOCK_LOG_KEY_PREFIX, orderItem.CommodityId)
-		lockKey := fmt.Sprintf("%s%d", enum.STOCK_LOCK_KEY_PREFIX, orderItem.CommodityId)
+		stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, orderItem.SkuId)
+		logKey := fmt.Sprintf("%s%d", enum.STOCK_LOG_KEY_PREFIX, orderItem.SkuId)
+		lockKey := fmt.Sprintf("%s%d", enum.STOCK_LOCK_KEY_PREFIX, orderItem.SkuId)
 		// 准备参数
 		args := []interface{}{
 			orderItem.CommodityNum,
@@ -214,13 +180,13 @@ func (cd *CommodityDao) ReduceStuckInOrderCreateByLua(tx *gorm.DB, orderItems []
 func (cd *CommodityDao) RecoverOrderCommodityStuck(orderItems []*do.OrderItem) error {
 	err := DBMaster().Transaction(func(tx *gorm.DB) error {
 		for _, orderItem := range orderItems {
-			commodity := new(model.Commodity)
-			tx.Clauses(clause.Locking{Strength: "UPDATE"}).WithContext(cd.ctx).Find(commodity, orderItem.CommodityId)
-			if commodity.ID == 0 {
-				return errcode.ErrNotFound.WithCause(errors.New(fmt.Sprintf("商品未找到，ID：%d", orderItem.CommodityId)))
+			sku := new(model.CommoditySku)
+			tx.Clauses(clause.Locking{Strength: "UPDATE"}).WithContext(cd.ctx).Find(sku, orderItem.SkuId)
+			if sku.ID == 0 {
+				return errcode.ErrNotFound.WithCause(errors.New(fmt.Sprintf("SKU未找到，ID：%d", orderItem.SkuId)))
 			}
-			newStock := commodity.StockNum + orderItem.CommodityNum
-			err := tx.WithContext(cd.ctx).Model(commodity).Update("stock_num", newStock).Error
+			newStock := sku.StockNum + orderItem.CommodityNum
+			err := tx.WithContext(cd.ctx).Model(sku).Update("stock_num", newStock).Error
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"gorm.io/gorm"
+)
+
+// CreateCommodityWithSkus 在一个事务内写入商品及其规格项、规格值和SKU, 写入后回填商品和SKU的ID
+func (cd *CommodityDao) CreateCommodityWithSkus(commodity *model.Commodity, creation *do.CommodityCreation, skuSpecValues [][]*do.CommoditySpecValue) error {
+	return DBMaster().WithContext(cd.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(commodity).Error; err != nil {
+			return err
+		}
+		skuModels, err := createSpecsAndSkusInTx(tx, commodity.ID, creation.Specs, creation.Skus, skuSpecValues)
+		if err != nil {
+			return err
+		}
+		for i, skuModel := range skuModels {
+			creation.Skus[i].ID = skuModel.ID
+			creation.Skus[i].CommodityId = commodity.ID
+		}
+		return refreshCommoditySummaryInTx(tx, commodity.ID)
+	})
+}
+
+// UpdateCommodityWithSkus 在一个事务内修改商品的基本信息和SKU, SKU库存按增减量调整,
+// 调整后库存小于0时返回 false 并回滚
+func (cd *CommodityDao) UpdateCommodityWithSkus(commodityId int64, commodityFields map[string]interface{}, skuUpdates []*do.CommoditySkuUpdate) (bool, error) {
+	stockEnough := true
+	err := DBMaster().WithContext(cd.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(model.Commodity{}).Where("id = ?", commodityId).Updates(commodityFields).Error; err != nil {
+			return err
+		}
+		for _, skuUpdate := range skuUpdates {
+			result := tx.Model(model.CommoditySku{}).
+				Where("id = ? AND commodity_id = ? AND stock_num + ? >= 0", skuUpdate.SkuId, commodityId, skuUpdate.StockDelta).
+				Updates(map[string]interface{}{
+					"cover_img":      skuUpdate.CoverImg,
+					"images":         skuUpdate.Images,
+					"original_price": skuUpdate.OriginalPrice,
+					"selling_price":  skuUpdate.SellingPrice,
+					"sell_status":    skuUpdate.SellStatus,
+					"stock_num":      gorm.Expr("stock_num + ?", skuUpdate.StockDelta),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				stockEnough = false
+				return gorm.ErrInvalidData
+			}
+		}
+		return refreshCommoditySummaryInTx(tx, commodityId)
+	})
+	if !stockEnough {
+		return false, nil
+	}
+	return true, err
+}
+
+func (cd *CommodityDao) UpdateCommoditySellStatus(commodityId int64, sellStatus int) error {
+	return DBMaster().WithContext(cd.ctx).Model(model.Commodity{}).
+		Where("id = ?", commodityId).
+		Update("sell_status", sellStatus).Error
+}
+
+func (cd *CommodityDao) DeleteCommodity(commodityId int64) error {
+	return DBMaster().WithContext(cd.ctx).Delete(&model.Commodity{}, commodityId).Error
+}
+
+// RestoreCommodity 恢复被软删除的商品
+func (cd *CommodityDao) RestoreCommodity(commodityId int64) error {
+	return DBMaster().WithContext(cd.ctx).Unscoped().Model(model.Commodity{}).
+		Where("id = ?", commodityId).
+		Update("is_del", 0).Error
+}
+
+// FindCommodityByIdUnscoped 按ID获取商品, 包括已删除的商品
+func (cd *CommodityDao) FindCommodityByIdUnscoped(commodityId int64) (*model.Commodity, error) {
+	commodity := new(model.Commodity)
+	err := DB().WithContext(cd.ctx).Unscoped().Where("id = ?", commodityId).Find(commodity).Error
+	return commodity, err
+}
+
+// AdminListCommodities 后台商品列表, 按ID倒序, 最新创建的在前
+func (cd *CommodityDao) AdminListCommodities(query *do.AdminCommodityQuery, offset, size int) (commodityList []*model.Commodity, totalRows int64, err error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if query.Deleted {
+			db = db.Unscoped().Where("is_del = 1")
+		}
+		if query.Keyword != "" {
+			db = db.Where("name LIKE ?", "%"+query.Keyword+"%")
+		}
+		if query.CategoryId > 0 {
+			db = db.Where("category_id = ?", query.CategoryId)
+		}
+		if query.SellStatus > 0 {
+			db = db.Where("sell_status = ?", query.SellStatus)
+		}
+		return db
+	}
+	err = DB().WithContext(cd.ctx).Omit("detail_content").
+		Scopes(scope).
+		Order("id DESC").
+		Offset(offset).Limit(size).
+		Find(&commodityList).Error
+	if err != nil {
+		return
+	}
+	err = DB().WithContext(cd.ctx).Model(model.Commodity{}).Scopes(scope).Count(&totalRows).Error
+	return
+}
+
+func (cd *CommodityDao) CreateCategory(category *model.CommodityCategory) error {
+	return DBMaster().WithContext(cd.ctx).Create(category).Error
+}
+
+func (cd *CommodityDao) UpdateCategory(categoryId int64, fields map[string]interface{}) error {
+	return DBMaster().WithContext(cd.ctx).Model(model.CommodityCategory{}).
+		Where("id = ?", categoryId).
+		Updates(fields).Error
+}
+
+// UpdateCategoryRanks 在一个事务内批量修改分类的排序值
+func (cd *CommodityDao) UpdateCategoryRanks(ranks map[int64]int) error {
+	return DBMaster().WithContext(cd.ctx).Transaction(func(tx *gorm.DB) error {
+		for categoryId, rank := range ranks {
+			err := tx.Model(model.CommodityCategory{}).Where("id = ?", categoryId).Update("rank", rank).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cd *CommodityDao) DeleteCategory(categoryId int64) error {
+	return DBMaster().WithContext(cd.ctx).Delete(&model.CommodityCategory{}, categoryId).Error
+}
+
+func (cd *CommodityDao) CountSubCategories(categoryId int64) (count int64, err error) {
+	err = DB().WithContext(cd.ctx).Model(model.CommodityCategory{}).
+		Where("parent_id = ?", categoryId).
+		Count(&count).Error
+	return
+}
+
+func (cd *CommodityDao) CountCommoditiesInCategory(categoryId int64) (count int64, err error) {
+	err = DB().WithContext(cd.ctx).Model(model.Commodity{}).
+		Where("category_id = ?", categoryId).
+		Count(&count).Error
+	return
+}
+
+// FindCommoditiesUnscoped 按ID获取商品, 包括已删除的商品
+func (cd *CommodityDao) FindCommoditiesUnscoped(commodityIdList []int64) ([]*model.Commodity, error) {
+	commodities := make([]*model.Commodity, 0)
+	err := DB().WithContext(cd.ctx).Unscoped().Omit("detail_content").Find(&commodities, commodityIdList).Error
+	return commodities, err
+}
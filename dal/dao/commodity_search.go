@@ -0,0 +1,194 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// 搜索相关度中各字段的权重, 名称命中最重要, 其次是标签, 最后是简介
+const (
+	searchNameWeight  = 3
+	searchTagWeight   = 2
+	searchIntroWeight = 1
+)
+
+// 商品搜索和列表筛选依赖的索引, 全文索引使用 ngram 分词器以支持中文检索
+var commodityListIndexes = []struct {
+	model      interface{}
+	table      string
+	name       string
+	definition string
+}{
+	{&model.Commodity{}, "commodities", "idx_name", "INDEX idx_name (name)"},
+	{&model.Commodity{}, "commodities", "idx_ft_name", "FULLTEXT INDEX idx_ft_name (name) WITH PARSER ngram"},
+	{&model.Commodity{}, "commodities", "idx_ft_intro", "FULLTEXT INDEX idx_ft_intro (intro) WITH PARSER ngram"},
+	{&model.Commodity{}, "commodities", "idx_ft_tag", "FULLTEXT INDEX idx_ft_tag (tag) WITH PARSER ngram"},
+	{&model.Commodity{}, "commodities", "idx_category_price", "INDEX idx_category_price (category_id, selling_price)"},
+	{&model.Commodity{}, "commodities", "idx_category_sales", "INDEX idx_category_sales (category_id, sales_num)"},
+	{&model.Commodity{}, "commodities", "idx_category_created", "INDEX idx_category_created (category_id, created_at)"},
+	{&model.CommoditySku{}, "commodity_skus", "idx_commodity_stock", "INDEX idx_commodity_stock (commodity_id, stock_num)"},
+}
+
+// MigrateSearchSchema 为商品表增加销量和限购字段, 并创建搜索和列表筛选使用的索引, 已存在的字段和索引会跳过
+func (cd *CommodityDao) MigrateSearchSchema() error {
+	db := DBMaster().WithContext(cd.ctx)
+	migrator := db.Migrator()
+	for _, field := range []string{"SalesNum", "PurchaseLimit"} {
+		if migrator.HasColumn(&model.Commodity{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&model.Commodity{}, field); err != nil {
+			return err
+		}
+	}
+	for _, index := range commodityListIndexes {
+		if !migrator.HasTable(index.model) || migrator.HasIndex(index.model, index.name) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s", index.table, index.definition)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCommodities 按查询条件获取一页商品, 同时返回满足条件的商品总数
+func (cd *CommodityDao) ListCommodities(query *do.CommodityListQuery, offset, size int) (commodityList []*model.Commodity, totalRows int64, err error) {
+	scope := commodityListScope(query, true, true)
+	err = DB().WithContext(cd.ctx).Omit("detail_content").
+		Scopes(scope).
+		Clauses(commodityListOrder(query)).
+		Offset(offset).Limit(size).
+		Find(&commodityList).Error
+	if err != nil {
+		return
+	}
+	err = DB().WithContext(cd.ctx).Model(model.Commodity{}).Scopes(scope).Count(&totalRows).Error
+	return
+}
+
+// CountCategoryFacets 按三级分类统计满足查询条件的商品数量, 不计入用户筛选的分类条件
+func (cd *CommodityDao) CountCategoryFacets(query *do.CommodityListQuery) ([]*do.CommodityCategoryFacet, error) {
+	facets := make([]*do.CommodityCategoryFacet, 0)
+	err := DB().WithContext(cd.ctx).Model(model.Commodity{}).
+		Scopes(commodityListScope(query, false, true)).
+		Select("category_id, COUNT(*) AS count").
+		Group("category_id").
+		Order("count DESC, category_id").
+		Scan(&facets).Error
+	return facets, err
+}
+
+// CountPriceFacets 按价格区间统计满足查询条件的商品数量, 不计入价格条件本身,
+// 返回的 map 以区间下标为key, 区间为 [bounds[i], bounds[i+1])
+func (cd *CommodityDao) CountPriceFacets(query *do.CommodityListQuery, bounds []int) (map[int]int64, error) {
+	rows := make([]struct {
+		Bucket int
+		Count  int64
+	}, 0)
+	// INTERVAL(N, N1, N2, ...) 返回N落在第几个分界点之后, 分界点必须升序
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(bounds)-1), ", ")
+	bucketVars := make([]interface{}, 0, len(bounds)-1)
+	for _, bound := range bounds[1:] {
+		bucketVars = append(bucketVars, bound)
+	}
+	bucketExpr := "0"
+	if len(bucketVars) > 0 {
+		bucketExpr = "INTERVAL(selling_price, " + placeholders + ")"
+	}
+	err := DB().WithContext(cd.ctx).Model(model.Commodity{}).
+		Scopes(commodityListScope(query, true, false)).
+		Select(bucketExpr+" AS bucket, COUNT(*) AS count", bucketVars...).
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+	return counts, nil
+}
+
+// FindCommodityNamesWithPrefix 获取以 prefix 开头的商品名称, 用于搜索补全
+func (cd *CommodityDao) FindCommodityNamesWithPrefix(prefix string, limit int) (names []string, err error) {
+	err = DB().WithContext(cd.ctx).Model(model.Commodity{}).
+		Distinct("name").
+		Where("name LIKE ? AND sell_status = ?", prefix+"%", enum.CommoditySellStatusOn).
+		Order("name").Limit(limit).
+		Pluck("name", &names).Error
+	return
+}
+
+// commodityListScope 生成商品列表的筛选条件, 统计分面时通过 withCategory、withPrice 去掉用户筛选的分类和价格条件
+func commodityListScope(query *do.CommodityListQuery, withCategory, withPrice bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// 前台列表只展示上架的商品, 已删除的商品由软删除条件过滤
+		db = db.Where("sell_status = ?", enum.CommoditySellStatusOn)
+		if len(query.CategoryIds) > 0 {
+			db = db.Where("category_id IN (?)", query.CategoryIds)
+		}
+		if withCategory && len(query.FilterCategoryIds) > 0 {
+			db = db.Where("category_id IN (?)", query.FilterCategoryIds)
+		}
+		if query.Keyword != "" {
+			if query.FullTextSearch {
+				db = db.Where("(MATCH(name) AGAINST(?) OR MATCH(tag) AGAINST(?) OR MATCH(intro) AGAINST(?))",
+					query.Keyword, query.Keyword, query.Keyword)
+			} else {
+				db = db.Where("name LIKE ?", "%"+query.Keyword+"%")
+			}
+		}
+		if withPrice && query.MinPrice > 0 {
+			db = db.Where("selling_price >= ?", query.MinPrice)
+		}
+		if withPrice && query.MaxPrice > 0 {
+			db = db.Where("selling_price <= ?", query.MaxPrice)
+		}
+		for _, tag := range query.Tags {
+			db = db.Where("tag LIKE ?", "%"+tag+"%")
+		}
+		if query.InStockOnly {
+			db = db.Where("EXISTS (?)", DB().Model(model.CommoditySku{}).Select("1").
+				Where("commodity_skus.commodity_id = commodities.id AND commodity_skus.stock_num > 0 AND commodity_skus.sell_status = ?", enum.CommoditySellStatusOn))
+		}
+		return db
+	}
+}
+
+// commodityListOrder 按排序方式生成排序子句, 未指定排序时搜索按相关度、分类列表按ID排序
+func commodityListOrder(query *do.CommodityListQuery) clause.OrderBy {
+	switch query.Sort {
+	case enum.CommoditySortPriceAsc:
+		return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "selling_price"}}, {Column: clause.Column{Name: "id"}}}}
+	case enum.CommoditySortPriceDesc:
+		return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "selling_price"}, Desc: true}, {Column: clause.Column{Name: "id"}}}}
+	case enum.CommoditySortNewest:
+		return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "created_at"}, Desc: true}, {Column: clause.Column{Name: "id"}, Desc: true}}}
+	case enum.CommoditySortSales:
+		return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "sales_num"}, Desc: true}, {Column: clause.Column{Name: "id"}}}}
+	}
+	if query.Keyword != "" && query.FullTextSearch {
+		relevanceSQL := fmt.Sprintf("(MATCH(name) AGAINST(?)*%d + MATCH(tag) AGAINST(?)*%d + MATCH(intro) AGAINST(?)*%d) DESC, id DESC",
+			searchNameWeight, searchTagWeight, searchIntroWeight)
+		return clause.OrderBy{Expression: clause.Expr{SQL: relevanceSQL, Vars: []interface{}{query.Keyword, query.Keyword, query.Keyword}, WithoutParentheses: true}}
+	}
+	return clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "id"}}}}
+}
+
+// FindCategoryBestSellers 分类下在售商品按销量倒序排列的前 limit 个
+func (cd *CommodityDao) FindCategoryBestSellers(categoryId int64, limit int) ([]*model.Commodity, error) {
+	commodities := make([]*model.Commodity, 0, limit)
+	err := DB().WithContext(cd.ctx).Omit("detail_content").
+		Where("category_id = ? AND sell_status = ?", categoryId, enum.CommoditySellStatusOn).
+		Order("sales_num DESC, id").
+		Limit(limit).
+		Find(&commodities).Error
+	return commodities, err
+}
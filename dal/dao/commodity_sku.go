@@ -0,0 +1,256 @@
+package dao
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"strings"
+)
+
+func (cd *CommodityDao) GetCommoditySpecs(commodityId int64) ([]*model.CommoditySpec, error) {
+	specs := make([]*model.CommoditySpec, 0)
+	err := DB().WithContext(cd.ctx).Where("commodity_id = ?", commodityId).
+		Order("`rank`, id").Find(&specs).Error
+	return specs, err
+}
+
+func (cd *CommodityDao) GetCommoditySpecValues(commodityId int64) ([]*model.CommoditySpecValue, error) {
+	specValues := make([]*model.CommoditySpecValue, 0)
+	err := DB().WithContext(cd.ctx).Where("commodity_id = ?", commodityId).
+		Order("`rank`, id").Find(&specValues).Error
+	return specValues, err
+}
+
+func (cd *CommodityDao) GetCommoditySkus(commodityId int64) ([]*model.CommoditySku, error) {
+	skus := make([]*model.CommoditySku, 0)
+	err := DB().WithContext(cd.ctx).Where("commodity_id = ?", commodityId).Order("id").Find(&skus).Error
+	return skus, err
+}
+
+func (cd *CommodityDao) FindSkuById(skuId int64) (*model.CommoditySku, error) {
+	sku := new(model.CommoditySku)
+	err := DB().WithContext(cd.ctx).Where("id = ?", skuId).Find(sku).Error
+	return sku, err
+}
+
+func (cd *CommodityDao) FindSkus(skuIdList []int64) ([]*model.CommoditySku, error) {
+	skus := make([]*model.CommoditySku, 0)
+	err := DB().WithContext(cd.ctx).Find(&skus, skuIdList).Error
+	return skus, err
+}
+
+func (cd *CommodityDao) GetAllSkus() ([]*model.CommoditySku, error) {
+	skus := make([]*model.CommoditySku, 0)
+	err := DB().WithContext(cd.ctx).Find(&skus).Error
+	return skus, err
+}
+
+// LockSkuInTx 在事务内锁住SKU行, SKU不存在时返回的 ID 为 0
+func (cd *CommodityDao) LockSkuInTx(tx *gorm.DB, skuId int64) (*model.CommoditySku, error) {
+	sku := new(model.CommoditySku)
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).WithContext(cd.ctx).
+		Find(sku, skuId).Error
+	return sku, err
+}
+
+// UpdateSkuStockNumInTx 直接设置SKU的库存数量, 用于库存对账修复
+func (cd *CommodityDao) UpdateSkuStockNumInTx(tx *gorm.DB, skuId int64, stockNum int) error {
+	return tx.WithContext(cd.ctx).Model(model.CommoditySku{}).
+		Where("id = ?", skuId).
+		Update("stock_num", stockNum).Error
+}
+
+// MigrateSkuSchema 创建规格和SKU的表, 并为引用商品的表增加 sku_id 等字段
+func (cd *CommodityDao) MigrateSkuSchema() error {
+	migrator := DBMaster().WithContext(cd.ctx).Migrator()
+	for _, table := range []interface{}{&model.CommoditySpec{}, &model.CommoditySpecValue{}, &model.CommoditySku{}} {
+		if !migrator.HasTable(table) {
+			if err := migrator.CreateTable(table); err != nil {
+				return err
+			}
+		}
+	}
+	// 已有的表只增加字段, 不修改原有字段
+	newColumns := []struct {
+		model interface{}
+		field string
+	}{
+		{&model.ShoppingCartItem{}, "SkuId"},
+		{&model.OrderItem{}, "SkuId"},
+		{&model.OrderItem{}, "SkuSpecDesc"},
+		{&model.SeckillCampaign{}, "SkuId"},
+	}
+	for _, column := range newColumns {
+		if !migrator.HasTable(column.model) || migrator.HasColumn(column.model, column.field) {
+			continue
+		}
+		if err := migrator.AddColumn(column.model, column.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommodityIdsWithoutSku 获取还没有SKU的商品ID, 即还未迁移到SPU/SKU模型的旧商品
+func (cd *CommodityDao) GetCommodityIdsWithoutSku() (commodityIds []int64, err error) {
+	err = DB().WithContext(cd.ctx).Model(model.Commodity{}).
+		Where("NOT EXISTS (?)", DB().Model(model.CommoditySku{}).Select("1").
+			Where("commodity_skus.commodity_id = commodities.id")).
+		Order("id").
+		Pluck("id", &commodityIds).Error
+	return
+}
+
+// MigrateCommodityToSku 在一个事务内写入SPU的规格和SKU, 删除被合并的旧商品,
+// 并把购物车、订单购物项、秒杀活动中的旧商品ID改为 SPU ID + SKU ID
+func (cd *CommodityDao) MigrateCommodityToSku(migration *do.CommoditySkuMigration) error {
+	return DBMaster().WithContext(cd.ctx).Transaction(func(tx *gorm.DB) error {
+		skuModels, err := createSpecsAndSkusInTx(tx, migration.CommodityId, migration.Specs, migration.Skus, migration.SkuSpecValues)
+		if err != nil {
+			return err
+		}
+		if err = refreshCommoditySummaryInTx(tx, migration.CommodityId); err != nil {
+			return err
+		}
+		if len(migration.FoldedIds) > 0 {
+			if err := tx.Delete(&model.Commodity{}, migration.FoldedIds).Error; err != nil {
+				return err
+			}
+		}
+
+		// 旧商品ID就是SKU ID, 引用旧商品ID的数据改为 commodity_id=SPU ID, sku_id=旧商品ID
+		oldIds := lo.Map(skuModels, func(sku *model.CommoditySku, _ int) int64 { return sku.ID })
+		for _, table := range []string{"shopping_cart_items", "order_items", "seckill_campaigns"} {
+			err := tx.Table(table).
+				Where("sku_id = 0 AND commodity_id IN (?)", oldIds).
+				Updates(map[string]interface{}{
+					"sku_id":       gorm.Expr("commodity_id"),
+					"commodity_id": migration.CommodityId,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReduceSkuStockInTx 订单支付结算时扣减SKU的库存, 并累加所属商品的销量
+func (cd *CommodityDao) ReduceSkuStockInTx(tx *gorm.DB, orderItems []*do.OrderItem) error {
+	for _, orderItem := range orderItems {
+		sku := new(model.CommoditySku)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).WithContext(cd.ctx).
+			Find(sku, orderItem.SkuId).Error
+		if err != nil {
+			return errcode.Wrap("ReduceSkuStockError", err)
+		}
+		if sku.ID == 0 {
+			return errcode.ErrCommodityNotExists.WithCause(errors.New("SKU不存在, SKU ID:" + strconv.FormatInt(orderItem.SkuId, 10)))
+		}
+		newStock := sku.StockNum - orderItem.CommodityNum
+		if newStock < 0 {
+			return errcode.ErrCommodityStockOut.WithCause(errors.New("SKU缺少库存, SKU ID:" + strconv.FormatInt(sku.ID, 10)))
+		}
+		err = tx.WithContext(cd.ctx).Model(sku).Update("stock_num", newStock).Error
+		if err != nil {
+			return err
+		}
+		err = tx.WithContext(cd.ctx).Model(model.Commodity{}).Where("id = ?", sku.CommodityId).
+			Update("sales_num", gorm.Expr("sales_num + ?", orderItem.CommodityNum)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreSkuStockInTx 已支付的订单关闭时把扣减的SKU库存加回, 并减去所属商品的销量
+func (cd *CommodityDao) RestoreSkuStockInTx(tx *gorm.DB, orderItems []*do.OrderItem) error {
+	for _, orderItem := range orderItems {
+		err := tx.WithContext(cd.ctx).Model(model.CommoditySku{}).Where("id = ?", orderItem.SkuId).
+			Update("stock_num", gorm.Expr("stock_num + ?", orderItem.CommodityNum)).Error
+		if err != nil {
+			return err
+		}
+		err = tx.WithContext(cd.ctx).Model(model.Commodity{}).Where("id = ? AND sales_num >= ?", orderItem.CommodityId, orderItem.CommodityNum).
+			Update("sales_num", gorm.Expr("sales_num - ?", orderItem.CommodityNum)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSpecsAndSkusInTx 写入商品的规格项、规格值和SKU, skuSpecValues[i] 为第i个SKU按规格项顺序的规格值,
+// 写入后回填 specs 和规格值的ID
+func createSpecsAndSkusInTx(tx *gorm.DB, commodityId int64, specs []*do.CommoditySpec, skus []*do.CommoditySku, skuSpecValues [][]*do.CommoditySpecValue) ([]*model.CommoditySku, error) {
+	for _, spec := range specs {
+		specModel := &model.CommoditySpec{CommodityId: commodityId, Name: spec.Name, Rank: spec.Rank}
+		if err := tx.Create(specModel).Error; err != nil {
+			return nil, err
+		}
+		spec.ID = specModel.ID
+		spec.CommodityId = commodityId
+		for _, value := range spec.Values {
+			valueModel := &model.CommoditySpecValue{
+				CommodityId: commodityId,
+				SpecId:      spec.ID,
+				Value:       value.Value,
+				Rank:        value.Rank,
+			}
+			if err := tx.Create(valueModel).Error; err != nil {
+				return nil, err
+			}
+			value.ID = valueModel.ID
+			value.SpecId = spec.ID
+		}
+	}
+
+	skuModels := make([]*model.CommoditySku, 0, len(skus))
+	if err := util.CopyProperties(&skuModels, &skus); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	for i, skuModel := range skuModels {
+		skuModel.CommodityId = commodityId
+		if i < len(skuSpecValues) {
+			skuModel.SpecValueIds = strings.Join(lo.Map(skuSpecValues[i], func(value *do.CommoditySpecValue, _ int) string {
+				return strconv.FormatInt(value.ID, 10)
+			}), ",")
+		}
+	}
+	if err := tx.Create(skuModels).Error; err != nil {
+		return nil, err
+	}
+	return skuModels, nil
+}
+
+// refreshCommoditySummaryInTx 按SKU刷新SPU展示的价格和库存: 价格为最低的SKU售价, 库存为所有SKU的合计
+func refreshCommoditySummaryInTx(tx *gorm.DB, commodityId int64) error {
+	skuModels := make([]*model.CommoditySku, 0)
+	if err := tx.Where("commodity_id = ?", commodityId).Find(&skuModels).Error; err != nil {
+		return err
+	}
+	if len(skuModels) == 0 {
+		return nil
+	}
+	lowestSku := lo.MinBy(skuModels, func(a, b *model.CommoditySku) bool { return a.SellingPrice < b.SellingPrice })
+	totalStock := lo.SumBy(skuModels, func(sku *model.CommoditySku) int { return sku.StockNum })
+	return tx.Model(model.Commodity{}).Where("id = ?", commodityId).Updates(map[string]interface{}{
+		"selling_price":  lowestSku.SellingPrice,
+		"original_price": lowestSku.OriginalPrice,
+		"stock_num":      totalStock,
+	}).Error
+}
+
+// FindSkusUnscoped 按ID获取SKU, 包括已删除的SKU
+func (cd *CommodityDao) FindSkusUnscoped(skuIdList []int64) ([]*model.CommoditySku, error) {
+	skus := make([]*model.CommoditySku, 0)
+	err := DB().WithContext(cd.ctx).Unscoped().Find(&skus, skuIdList).Error
+	return skus, err
+}
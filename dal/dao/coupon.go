@@ -0,0 +1,144 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type CouponDao struct {
+	ctx context.Context
+}
+
+func NewCouponDao(ctx context.Context) *CouponDao {
+	return &CouponDao{ctx: ctx}
+}
+
+func (cd *CouponDao) CreateTemplate(template *model.CouponTemplate) error {
+	return DBMaster().WithContext(cd.ctx).Create(template).Error
+}
+
+func (cd *CouponDao) GetTemplateById(templateId int64) (*model.CouponTemplate, error) {
+	template := new(model.CouponTemplate)
+	err := DB().WithContext(cd.ctx).Where("id = ?", templateId).Find(template).Error
+	return template, err
+}
+
+func (cd *CouponDao) FindTemplates(templateIds []int64) ([]*model.CouponTemplate, error) {
+	templates := make([]*model.CouponTemplate, 0, len(templateIds))
+	err := DB().WithContext(cd.ctx).Unscoped().Find(&templates, templateIds).Error
+	return templates, err
+}
+
+func (cd *CouponDao) UpdateTemplateStatus(templateId int64, status int) (int64, error) {
+	result := DBMaster().WithContext(cd.ctx).Model(model.CouponTemplate{}).
+		Where("id = ?", templateId).
+		Update("status", status)
+	return result.RowsAffected, result.Error
+}
+
+// GetClaimableTemplates 当前可以领取且还有剩余数量的优惠券模板, 最早结束的在前
+func (cd *CouponDao) GetClaimableTemplates(now time.Time) ([]*model.CouponTemplate, error) {
+	templates := make([]*model.CouponTemplate, 0)
+	err := DB().WithContext(cd.ctx).
+		Where("status = ? AND valid_start_at <= ? AND valid_end_at > ?", enum.CouponTemplateStatusOn, now, now).
+		Where("total_num = 0 OR issued_num < total_num").
+		Order("valid_end_at").
+		Find(&templates).Error
+	return templates, err
+}
+
+// IncrTemplateIssuedInTx 增加模板的已发放数量, 超过发行数量时不更新, 返回更新的行数.
+// 更新会锁住模板记录, 同一模板的领取和发放在事务内串行执行
+func (cd *CouponDao) IncrTemplateIssuedInTx(tx *gorm.DB, templateId int64, num int) (int64, error) {
+	result := tx.WithContext(cd.ctx).Model(model.CouponTemplate{}).
+		Where("id = ? AND (total_num = 0 OR issued_num + ? <= total_num)", templateId, num).
+		Update("issued_num", gorm.Expr("issued_num + ?", num))
+	return result.RowsAffected, result.Error
+}
+
+// CountUserTemplateCouponsInTx 统计每个用户已领取的某个模板的优惠券数量
+func (cd *CouponDao) CountUserTemplateCouponsInTx(tx *gorm.DB, templateId int64, userIds []int64) (map[int64]int, error) {
+	rows := make([]struct {
+		UserId int64
+		Num    int
+	}, 0, len(userIds))
+	err := tx.WithContext(cd.ctx).Model(model.UserCoupon{}).
+		Select("user_id, COUNT(*) AS num").
+		Where("template_id = ? AND user_id IN ?", templateId, userIds).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		counts[row.UserId] = row.Num
+	}
+	return counts, nil
+}
+
+func (cd *CouponDao) CreateUserCouponsInTx(tx *gorm.DB, coupons []*model.UserCoupon) error {
+	return tx.WithContext(cd.ctx).Create(&coupons).Error
+}
+
+// GetUserCoupons 用户的优惠券列表, status 为 0 时不限状态, 最近领取的在前
+func (cd *CouponDao) GetUserCoupons(userId int64, status, offset, size int) (coupons []*model.UserCoupon, totalRows int64, err error) {
+	query := DB().WithContext(cd.ctx).Model(model.UserCoupon{}).Where("user_id = ?", userId)
+	if status != 0 {
+		query = query.Where("status = ?", status)
+	}
+	if err = query.Count(&totalRows).Error; err != nil {
+		return
+	}
+	err = query.Order("id DESC").Offset(offset).Limit(size).Find(&coupons).Error
+	return
+}
+
+// GetUsableUserCoupons 用户未使用且在有效期内的优惠券
+func (cd *CouponDao) GetUsableUserCoupons(userId int64, now time.Time) ([]*model.UserCoupon, error) {
+	coupons := make([]*model.UserCoupon, 0)
+	err := DB().WithContext(cd.ctx).
+		Where("user_id = ? AND status = ? AND valid_start_at <= ? AND valid_end_at > ?",
+			userId, enum.UserCouponStatusUnused, now, now).
+		Order("valid_end_at").
+		Find(&coupons).Error
+	return coupons, err
+}
+
+// LockUserCouponInTx 订单锁定用户未使用且在有效期内的优惠券, 返回更新的行数
+func (cd *CouponDao) LockUserCouponInTx(tx *gorm.DB, userCouponId, userId, orderId int64, now time.Time) (int64, error) {
+	result := tx.WithContext(cd.ctx).Model(model.UserCoupon{}).
+		Where("id = ? AND user_id = ? AND status = ? AND valid_start_at <= ? AND valid_end_at > ?",
+			userCouponId, userId, enum.UserCouponStatusUnused, now, now).
+		Updates(map[string]interface{}{
+			"status":   enum.UserCouponStatusLocked,
+			"order_id": orderId,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// UseOrderCouponInTx 订单支付后把订单锁定的优惠券标记为已使用
+func (cd *CouponDao) UseOrderCouponInTx(tx *gorm.DB, orderId int64, usedAt time.Time) error {
+	return tx.WithContext(cd.ctx).Model(model.UserCoupon{}).
+		Where("order_id = ? AND status = ?", orderId, enum.UserCouponStatusLocked).
+		Updates(map[string]interface{}{
+			"status":  enum.UserCouponStatusUsed,
+			"used_at": usedAt,
+		}).Error
+}
+
+// ReleaseOrderCoupon 订单取消、关闭或退款后退回订单锁定或使用的优惠券, 返回退回的数量
+func (cd *CouponDao) ReleaseOrderCoupon(orderId int64) (int64, error) {
+	result := DBMaster().WithContext(cd.ctx).Model(model.UserCoupon{}).
+		Where("order_id = ? AND status IN ?", orderId,
+			[]int{enum.UserCouponStatusLocked, enum.UserCouponStatusUsed}).
+		Updates(map[string]interface{}{
+			"status":   enum.UserCouponStatusUnused,
+			"order_id": 0,
+			"used_at":  time.Unix(0, 0),
+		})
+	return result.RowsAffected, result.Error
+}
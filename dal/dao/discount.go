@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"time"
+)
+
+type DiscountDao struct {
+	ctx context.Context
+}
+
+func NewDiscountDao(ctx context.Context) *DiscountDao {
+	return &DiscountDao{ctx: ctx}
+}
+
+func (dd *DiscountDao) CreateCampaign(campaign *model.DiscountCampaign) error {
+	return DBMaster().WithContext(dd.ctx).Create(campaign).Error
+}
+
+func (dd *DiscountDao) GetCampaignById(campaignId int64) (*model.DiscountCampaign, error) {
+	campaign := new(model.DiscountCampaign)
+	err := DB().WithContext(dd.ctx).Where("id = ?", campaignId).Find(campaign).Error
+	return campaign, err
+}
+
+func (dd *DiscountDao) UpdateCampaignStatus(campaignId int64, status int) error {
+	return DBMaster().WithContext(dd.ctx).Model(model.DiscountCampaign{}).
+		Where("id = ?", campaignId).
+		Update("status", status).Error
+}
+
+// GetCampaigns 后台的满减活动列表, 最近创建的在前
+func (dd *DiscountDao) GetCampaigns(offset, size int) (campaigns []*model.DiscountCampaign, totalRows int64, err error) {
+	query := DB().WithContext(dd.ctx).Model(model.DiscountCampaign{})
+	if err = query.Count(&totalRows).Error; err != nil {
+		return
+	}
+	err = query.Order("id DESC").Offset(offset).Limit(size).Find(&campaigns).Error
+	return
+}
+
+// GetActiveCampaigns 查询当前生效的满减活动, 优先级高的在前
+func (dd *DiscountDao) GetActiveCampaigns(now time.Time) (campaigns []*model.DiscountCampaign, err error) {
+	err = DB().WithContext(dd.ctx).
+		Where("status = ? AND start_time <= ? AND end_time > ?", enum.DiscountStatusOn, now, now).
+		Order("priority DESC, id").
+		Find(&campaigns).Error
+	return
+}
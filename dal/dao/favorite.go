@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm/clause"
+)
+
+type FavoriteDao struct {
+	ctx context.Context
+}
+
+func NewFavoriteDao(ctx context.Context) *FavoriteDao {
+	return &FavoriteDao{ctx: ctx}
+}
+
+// AddFavorite 收藏商品, 已经收藏过的商品保持原来的收藏记录
+func (fd *FavoriteDao) AddFavorite(favorite *model.UserFavorite) error {
+	return DBMaster().WithContext(fd.ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(favorite).Error
+}
+
+// DeleteFavorite 取消收藏, 返回删除的记录数
+func (fd *FavoriteDao) DeleteFavorite(userId, commodityId int64) (int64, error) {
+	result := DBMaster().WithContext(fd.ctx).
+		Where("user_id = ? AND commodity_id = ?", userId, commodityId).
+		Delete(&model.UserFavorite{})
+	return result.RowsAffected, result.Error
+}
+
+func (fd *FavoriteDao) GetUserFavorite(userId, commodityId int64) (*model.UserFavorite, error) {
+	favorite := new(model.UserFavorite)
+	err := DB().WithContext(fd.ctx).
+		Where("user_id = ? AND commodity_id = ?", userId, commodityId).
+		Find(favorite).Error
+	return favorite, err
+}
+
+// GetUserFavorites 用户的收藏列表, 最近收藏的在前
+func (fd *FavoriteDao) GetUserFavorites(userId int64, offset, size int) (favorites []*model.UserFavorite, totalRows int64, err error) {
+	err = DB().WithContext(fd.ctx).Where("user_id = ?", userId).
+		Order("id DESC").
+		Offset(offset).Limit(size).
+		Find(&favorites).Error
+	if err != nil {
+		return
+	}
+	err = DB().WithContext(fd.ctx).Model(model.UserFavorite{}).Where("user_id = ?", userId).Count(&totalRows).Error
+	return
+}
+
+// FindFavoritesPricedAbove 按ID顺序分批查询参考价高于 price 的收藏, 从 afterId 之后开始
+func (fd *FavoriteDao) FindFavoritesPricedAbove(commodityId int64, price int, afterId int64, limit int) ([]*model.UserFavorite, error) {
+	favorites := make([]*model.UserFavorite, 0, limit)
+	err := DBMaster().WithContext(fd.ctx).
+		Where("commodity_id = ? AND favorited_price > ? AND id > ?", commodityId, price, afterId).
+		Order("id").
+		Limit(limit).
+		Find(&favorites).Error
+	return favorites, err
+}
+
+// UpdateFavoritedPrice 更新收藏的降价提醒参考价
+func (fd *FavoriteDao) UpdateFavoritedPrice(favoriteIds []int64, price int) error {
+	return DBMaster().WithContext(fd.ctx).Model(model.UserFavorite{}).
+		Where("id IN (?)", favoriteIds).
+		Update("favorited_price", price).Error
+}
@@ -2,12 +2,14 @@ package dao
 
 import (
 	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
 	"github.com/Ian-zy0329/go-mall/common/util"
 	"github.com/Ian-zy0329/go-mall/dal/model"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
+	"time"
 )
 
 type OrderDao struct {
@@ -119,6 +121,14 @@ func (od *OrderDao) GetOrderByNo(orderNo string) (*model.Order, error) {
 	return order, err
 }
 
+func (od *OrderDao) GetOrdersByNos(orderNos []string) ([]*model.Order, error) {
+	orders := make([]*model.Order, 0, len(orderNos))
+	err := DB().WithContext(od.ctx).Where("order_no IN (?)", orderNos).
+		Find(&orders).Error
+
+	return orders, err
+}
+
 func (od *OrderDao) GetOrderAddress(orderId int64) (*model.OrderAddress, error) {
 	orderAddress := new(model.OrderAddress)
 	err := DB().WithContext(od.ctx).Where("order_id = ?", orderId).
@@ -144,3 +154,120 @@ func (od *OrderDao) UpdateOrderStatus(orderId int64, status int) error {
 func (od *OrderDao) UpdateOrder(orderModel *model.Order) error {
 	return DBMaster().WithContext(od.ctx).Model(orderModel).Updates(orderModel).Error
 }
+
+// UpdateOrderPaidInTx 回填订单支付信息, 只有尚未支付的订单会被更新
+func (od *OrderDao) UpdateOrderPaidInTx(tx *gorm.DB, orderModel *model.Order) (int64, error) {
+	result := tx.WithContext(od.ctx).Model(orderModel).
+		Where("order_status < ?", enum.OrderStatusPaid).
+		Updates(map[string]interface{}{
+			"pay_trans_id": orderModel.PayTransId,
+			"pay_state":    orderModel.PayState,
+			"order_status": orderModel.OrderStatus,
+			"paid_at":      orderModel.PaidAt,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// UpdateOrderPaidToRefund 订单无法继续履约时回填支付信息并标记为待退款, 只有尚未记录支付的订单会被更新
+func (od *OrderDao) UpdateOrderPaidToRefund(orderModel *model.Order) (int64, error) {
+	result := DBMaster().WithContext(od.ctx).Model(orderModel).
+		Where("pay_state <> ?", enum.PayStatePaid).
+		Updates(map[string]interface{}{
+			"pay_trans_id": orderModel.PayTransId,
+			"pay_state":    enum.PayStatePaid,
+			"order_status": enum.OrderStatusPaidToRefund,
+			"paid_at":      orderModel.PaidAt,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CloseUnpaidOrder 关闭未支付的订单, 订单已支付或已关闭时不做修改, 返回更新的行数
+func (od *OrderDao) CloseUnpaidOrder(orderId int64, status int) (int64, error) {
+	result := DBMaster().WithContext(od.ctx).Model(model.Order{}).
+		Where("id = ? AND order_status < ?", orderId, enum.OrderStatusPaid).
+		Update("order_status", status)
+	return result.RowsAffected, result.Error
+}
+
+// CloseOrderInTx 把订单关闭为 status, 只有状态仍为 fromStatus 的订单会被更新, 避免覆盖并发的状态变化
+func (od *OrderDao) CloseOrderInTx(tx *gorm.DB, orderId int64, fromStatus, status int) (int64, error) {
+	result := tx.WithContext(od.ctx).Model(model.Order{}).
+		Where("id = ? AND order_status = ?", orderId, fromStatus).
+		Update("order_status", status)
+	return result.RowsAffected, result.Error
+}
+
+// ConfirmOrderReceipt 买家确认收货, 只有已发货还未确认收货的订单会被更新
+func (od *OrderDao) ConfirmOrderReceipt(orderId int64) (int64, error) {
+	result := DBMaster().WithContext(od.ctx).Model(model.Order{}).
+		Where("id = ? AND order_status IN (?)", orderId,
+			[]int{enum.OrderStatusShipped, enum.OrderStatusOnDelivery, enum.OrderStatusDelivered}).
+		Update("order_status", enum.OrderStatusConfirmReceipt)
+	return result.RowsAffected, result.Error
+}
+
+// CompleteOrderInTx 已确认收货的订单完成评价后, 订单变为已完成, 返回更新的行数
+func (od *OrderDao) CompleteOrderInTx(tx *gorm.DB, orderId int64) (int64, error) {
+	result := tx.WithContext(od.ctx).Model(model.Order{}).
+		Where("id = ? AND order_status = ?", orderId, enum.OrderStatusConfirmReceipt).
+		Update("order_status", enum.OrderStatusCompleted)
+	return result.RowsAffected, result.Error
+}
+
+// GetPaidOrderIdsSince 按ID顺序分批查询 since 之后创建的已支付且未关闭的订单ID, 从 afterId 之后开始
+func (od *OrderDao) GetPaidOrderIdsSince(since time.Time, afterId int64, limit int) ([]int64, error) {
+	orderIds := make([]int64, 0, limit)
+	err := DB().WithContext(od.ctx).Model(model.Order{}).
+		Where("id > ? AND created_at >= ? AND order_status BETWEEN ? AND ?",
+			afterId, since, enum.OrderStatusPaid, enum.OrderStatusCompleted).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &orderIds).Error
+	return orderIds, err
+}
+
+// GetUnpaidOrderSkuNumsInTx 获取包含SKU的待支付订单中该SKU的购买数量, 返回 订单号 -> 数量
+func (od *OrderDao) GetUnpaidOrderSkuNumsInTx(tx *gorm.DB, skuId int64) (map[string]int, error) {
+	rows := make([]struct {
+		OrderNo string
+		Num     int
+	}, 0)
+	err := tx.WithContext(od.ctx).Model(model.OrderItem{}).
+		Select("orders.order_no, SUM(order_items.commodity_num) AS num").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.sku_id = ? AND orders.is_del = 0 AND orders.pay_state <> ? AND orders.order_status < ?",
+			skuId, enum.PayStatePaid, enum.OrderStatusPaid).
+		Group("orders.order_no").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	orderSkuNums := make(map[string]int, len(rows))
+	for _, row := range rows {
+		orderSkuNums[row.OrderNo] = row.Num
+	}
+	return orderSkuNums, nil
+}
+
+// SumUserPurchasedNum 统计用户未关闭的订单中每个商品的购买数量, 待支付的订单也计入
+func (od *OrderDao) SumUserPurchasedNum(userId int64, commodityIds []int64) (map[int64]int, error) {
+	rows := make([]struct {
+		CommodityId int64
+		Num         int
+	}, 0, len(commodityIds))
+	err := DB().WithContext(od.ctx).Model(model.OrderItem{}).
+		Select("order_items.commodity_id, SUM(order_items.commodity_num) AS num").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.is_del = 0 AND orders.order_status BETWEEN ? AND ? AND order_items.commodity_id IN ?",
+			userId, enum.OrderStatusCreated, enum.OrderStatusCompleted, commodityIds).
+		Group("order_items.commodity_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	purchased := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		purchased[row.CommodityId] = row.Num
+	}
+	return purchased, nil
+}
@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type PointsDao struct {
+	ctx context.Context
+}
+
+func NewPointsDao(ctx context.Context) *PointsDao {
+	return &PointsDao{ctx: ctx}
+}
+
+// GetAccount 用户的积分账户, 用户还没有获得过积分时返回的 ID 为 0
+func (pd *PointsDao) GetAccount(userId int64) (*model.UserPointsAccount, error) {
+	account := new(model.UserPointsAccount)
+	err := DB().WithContext(pd.ctx).Where("user_id = ?", userId).Find(account).Error
+	return account, err
+}
+
+// LockAccountInTx 锁住并返回用户的积分账户, 用户还没有积分账户时返回的 ID 为 0
+func (pd *PointsDao) LockAccountInTx(tx *gorm.DB, userId int64) (*model.UserPointsAccount, error) {
+	account := new(model.UserPointsAccount)
+	err := tx.WithContext(pd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).Find(account).Error
+	return account, err
+}
+
+// IncrBalanceInTx 增加用户的积分余额, 用户还没有积分账户时创建
+func (pd *PointsDao) IncrBalanceInTx(tx *gorm.DB, userId int64, points int) error {
+	return tx.WithContext(pd.ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"balance": gorm.Expr("balance + ?", points)}),
+	}).Create(&model.UserPointsAccount{UserId: userId, Balance: points}).Error
+}
+
+// DecrBalanceInTx 扣减用户的积分余额, 余额不足时不更新, 返回更新的行数
+func (pd *PointsDao) DecrBalanceInTx(tx *gorm.DB, userId int64, points int) (int64, error) {
+	result := tx.WithContext(pd.ctx).Model(model.UserPointsAccount{}).
+		Where("user_id = ? AND balance >= ?", userId, points).
+		Update("balance", gorm.Expr("balance - ?", points))
+	return result.RowsAffected, result.Error
+}
+
+// AddEntryInTx 追加积分流水, 相同 BizKey 的流水已经存在时不追加并返回 false
+func (pd *PointsDao) AddEntryInTx(tx *gorm.DB, entry *model.UserPointsEntry) (bool, error) {
+	result := tx.WithContext(pd.ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetEntryByBizKeyInTx 按业务唯一键查询积分流水, 不存在时返回的 ID 为 0
+func (pd *PointsDao) GetEntryByBizKeyInTx(tx *gorm.DB, bizKey string) (*model.UserPointsEntry, error) {
+	entry := new(model.UserPointsEntry)
+	err := tx.WithContext(pd.ctx).Where("biz_key = ?", bizKey).Find(entry).Error
+	return entry, err
+}
+
+// LockRemainingEntriesInTx 锁住用户还有剩余积分的流水, 最早过期的在前
+func (pd *PointsDao) LockRemainingEntriesInTx(tx *gorm.DB, userId int64) ([]*model.UserPointsEntry, error) {
+	entries := make([]*model.UserPointsEntry, 0)
+	err := tx.WithContext(pd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0", userId).
+		Order("expire_at, id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// LockEntryInTx 锁住并返回积分流水
+func (pd *PointsDao) LockEntryInTx(tx *gorm.DB, entryId int64) (*model.UserPointsEntry, error) {
+	entry := new(model.UserPointsEntry)
+	err := tx.WithContext(pd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", entryId).Find(entry).Error
+	return entry, err
+}
+
+// DecrEntryRemainingInTx 扣减流水中剩余的积分
+func (pd *PointsDao) DecrEntryRemainingInTx(tx *gorm.DB, entryId int64, points int) error {
+	return tx.WithContext(pd.ctx).Model(model.UserPointsEntry{}).
+		Where("id = ?", entryId).
+		Update("remaining", gorm.Expr("remaining - ?", points)).Error
+}
+
+// GetExpiredEntriesAfter 按ID顺序分批读取已过期但还有剩余积分的流水
+func (pd *PointsDao) GetExpiredEntriesAfter(afterId int64, now time.Time, limit int) ([]*model.UserPointsEntry, error) {
+	entries := make([]*model.UserPointsEntry, 0, limit)
+	err := DB().WithContext(pd.ctx).
+		Where("id > ? AND remaining > 0 AND expire_at <= ?", afterId, now).
+		Order("id").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetUserEntries 用户的积分流水, 最近的在前
+func (pd *PointsDao) GetUserEntries(userId int64, offset, size int) (entries []*model.UserPointsEntry, totalRows int64, err error) {
+	query := DB().WithContext(pd.ctx).Model(model.UserPointsEntry{}).Where("user_id = ?", userId)
+	if err = query.Count(&totalRows).Error; err != nil {
+		return
+	}
+	err = query.Order("id DESC").Offset(offset).Limit(size).Find(&entries).Error
+	return
+}
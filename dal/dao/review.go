@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ReviewDao struct {
+	ctx context.Context
+}
+
+func NewReviewDao(ctx context.Context) *ReviewDao {
+	return &ReviewDao{ctx: ctx}
+}
+
+func (rd *ReviewDao) CreateReviewInTx(tx *gorm.DB, review *model.CommodityReview) error {
+	return tx.WithContext(rd.ctx).Create(review).Error
+}
+
+// CountOrderReviewsInTx 统计订单中已评价的购物项数量
+func (rd *ReviewDao) CountOrderReviewsInTx(tx *gorm.DB, orderId int64) (count int64, err error) {
+	err = tx.WithContext(rd.ctx).Model(model.CommodityReview{}).
+		Where("order_id = ?", orderId).
+		Count(&count).Error
+	return
+}
+
+func (rd *ReviewDao) GetReviewById(reviewId int64) (*model.CommodityReview, error) {
+	review := new(model.CommodityReview)
+	err := DB().WithContext(rd.ctx).Where("id = ?", reviewId).Find(review).Error
+	return review, err
+}
+
+func (rd *ReviewDao) GetReviewByOrderItem(orderItemId int64) (*model.CommodityReview, error) {
+	review := new(model.CommodityReview)
+	err := DB().WithContext(rd.ctx).Where("order_item_id = ?", orderItemId).Find(review).Error
+	return review, err
+}
+
+// GetCommodityReviews 商品的评价列表, 最新的评价在前, rating 为0时不限星级
+func (rd *ReviewDao) GetCommodityReviews(commodityId int64, rating, offset, size int) (reviews []*model.CommodityReview, totalRows int64, err error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("commodity_id = ?", commodityId)
+		if rating > 0 {
+			db = db.Where("rating = ?", rating)
+		}
+		return db
+	}
+	err = DB().WithContext(rd.ctx).Scopes(scope).
+		Order("id DESC").
+		Offset(offset).Limit(size).
+		Find(&reviews).Error
+	if err != nil {
+		return
+	}
+	err = DB().WithContext(rd.ctx).Model(model.CommodityReview{}).Scopes(scope).Count(&totalRows).Error
+	return
+}
+
+// CountCommodityRatings 按星级统计商品的评价数量
+func (rd *ReviewDao) CountCommodityRatings(commodityId int64) (map[int]int64, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := DB().WithContext(rd.ctx).Model(model.CommodityReview{}).
+		Select("rating, COUNT(*) AS count").
+		Where("commodity_id = ?", commodityId).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	ratingCounts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		ratingCounts[row.Rating] = row.Count
+	}
+	return ratingCounts, nil
+}
+
+// UpdateMerchantReply 保存商家对评价的回复, 再次回复会覆盖之前的内容
+func (rd *ReviewDao) UpdateMerchantReply(reviewId int64, content string, repliedAt time.Time) error {
+	return DBMaster().WithContext(rd.ctx).Model(model.CommodityReview{}).
+		Where("id = ?", reviewId).
+		Updates(map[string]interface{}{
+			"merchant_reply": content,
+			"replied_at":     repliedAt,
+		}).Error
+}
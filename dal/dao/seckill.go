@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SeckillDao struct {
+	ctx context.Context
+}
+
+func NewSeckillDao(ctx context.Context) *SeckillDao {
+	return &SeckillDao{ctx: ctx}
+}
+
+func (sd *SeckillDao) GetCampaignById(campaignId int64) (*model.SeckillCampaign, error) {
+	campaign := new(model.SeckillCampaign)
+	err := DB().WithContext(sd.ctx).Where("id = ?", campaignId).Find(campaign).Error
+	return campaign, err
+}
+
+// GetActiveCampaigns 查询在 before 之前开始且尚未结束的生效活动
+func (sd *SeckillDao) GetActiveCampaigns(now, before time.Time) (campaigns []*model.SeckillCampaign, err error) {
+	err = DB().WithContext(sd.ctx).
+		Where("status = ? AND start_time <= ? AND end_time > ?", enum.SeckillCampaignStatusOnline, before, now).
+		Order("start_time").
+		Find(&campaigns).Error
+	return
+}
+
+func (sd *SeckillDao) CreateSeckillOrderInTx(tx *gorm.DB, seckillOrder *model.SeckillOrder) error {
+	return tx.WithContext(sd.ctx).Create(seckillOrder).Error
+}
+
+// GetSeckillOrderByRequestId 查询秒杀请求创建的订单, 还没有创建时返回的 ID 为 0
+func (sd *SeckillDao) GetSeckillOrderByRequestId(requestId string) (*model.SeckillOrder, error) {
+	seckillOrder := new(model.SeckillOrder)
+	err := DBMaster().WithContext(sd.ctx).Where("request_id = ?", requestId).Find(seckillOrder).Error
+	return seckillOrder, err
+}
+
+// GetSeckillOrderByOrderId 查询订单对应的秒杀记录, 不是秒杀订单时返回的 ID 为 0
+func (sd *SeckillDao) GetSeckillOrderByOrderId(orderId int64) (*model.SeckillOrder, error) {
+	seckillOrder := new(model.SeckillOrder)
+	err := DB().WithContext(sd.ctx).Where("order_id = ?", orderId).Find(seckillOrder).Error
+	return seckillOrder, err
+}
+
+// MarkSeckillOrderReverted 标记秒杀订单的名额已退回, 已经标记过时返回 0, 保证名额只退回一次
+func (sd *SeckillDao) MarkSeckillOrderReverted(seckillOrderId int64) (int64, error) {
+	result := DBMaster().WithContext(sd.ctx).Model(model.SeckillOrder{}).
+		Where("id = ? AND reverted = 0", seckillOrderId).
+		Update("reverted", 1)
+	return result.RowsAffected, result.Error
+}
@@ -55,6 +55,12 @@ func (ud *UserDao) FindUserById(userId int64) (*model.User, error) {
 	return user, nil
 }
 
+func (ud *UserDao) FindUsers(userIds []int64) ([]*model.User, error) {
+	users := make([]*model.User, 0, len(userIds))
+	err := DB().WithContext(ud.ctx).Find(&users, userIds).Error
+	return users, err
+}
+
 func (ud *UserDao) UpdateUser(user *model.User) error {
 	err := DB().Model(user).Updates(user).Error
 	return err
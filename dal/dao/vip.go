@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type VipDao struct {
+	ctx context.Context
+}
+
+func NewVipDao(ctx context.Context) *VipDao {
+	return &VipDao{ctx: ctx}
+}
+
+func (vd *VipDao) GetUserMembership(userId int64) (*model.UserMembership, error) {
+	membership := new(model.UserMembership)
+	err := DB().WithContext(vd.ctx).Where("user_id = ?", userId).Find(membership).Error
+	return membership, err
+}
+
+// LockUserMembershipInTx 锁住并返回用户的会员信息, 用户还没有会员信息时返回的 ID 为 0
+func (vd *VipDao) LockUserMembershipInTx(tx *gorm.DB, userId int64) (*model.UserMembership, error) {
+	membership := new(model.UserMembership)
+	err := tx.WithContext(vd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).Find(membership).Error
+	return membership, err
+}
+
+// SaveUserMembershipInTx 创建或更新用户的会员等级和到期时间
+func (vd *VipDao) SaveUserMembershipInTx(tx *gorm.DB, membership *model.UserMembership) error {
+	if membership.ID == 0 {
+		return tx.WithContext(vd.ctx).Create(membership).Error
+	}
+	return tx.WithContext(vd.ctx).Model(membership).
+		Select("Level", "LevelExpireAt").
+		Updates(membership).Error
+}
+
+// AddOrderGrowthInTx 记录订单获得的成长值并累加到用户的会员信息, 订单已经记录过时返回 false
+func (vd *VipDao) AddOrderGrowthInTx(tx *gorm.DB, growthLog *model.UserGrowthLog) (bool, error) {
+	result := tx.WithContext(vd.ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(growthLog)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	err := tx.WithContext(vd.ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"growth": gorm.Expr("growth + ?", growthLog.Growth)}),
+	}).Create(&model.UserMembership{
+		UserId:        growthLog.UserId,
+		Growth:        growthLog.Growth,
+		LevelExpireAt: time.Unix(0, 0),
+	}).Error
+	return err == nil, err
+}
+
+// RevokeOrderGrowthInTx 删除订单获得成长值的记录并从用户累计的成长值中扣回, 订单没有记录时返回 false
+func (vd *VipDao) RevokeOrderGrowthInTx(tx *gorm.DB, orderId int64) (bool, error) {
+	growthLog := new(model.UserGrowthLog)
+	err := tx.WithContext(vd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderId).Find(growthLog).Error
+	if err != nil || growthLog.ID == 0 {
+		return false, err
+	}
+	if err = tx.WithContext(vd.ctx).Delete(growthLog).Error; err != nil {
+		return false, err
+	}
+	err = tx.WithContext(vd.ctx).Model(model.UserMembership{}).
+		Where("user_id = ?", growthLog.UserId).
+		Update("growth", gorm.Expr("GREATEST(growth - ?, 0)", growthLog.Growth)).Error
+	return err == nil, err
+}
+
+// CreateMembershipPurchaseInTx 记录订单购买的会员等级和天数
+func (vd *VipDao) CreateMembershipPurchaseInTx(tx *gorm.DB, purchase *model.UserMembershipPurchase) error {
+	return tx.WithContext(vd.ctx).Create(purchase).Error
+}
+
+// LockMembershipPurchaseInTx 锁住并返回订单购买会员的记录, 订单没有购买会员时返回的 ID 为 0
+func (vd *VipDao) LockMembershipPurchaseInTx(tx *gorm.DB, orderId int64) (*model.UserMembershipPurchase, error) {
+	purchase := new(model.UserMembershipPurchase)
+	err := tx.WithContext(vd.ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderId).Find(purchase).Error
+	return purchase, err
+}
+
+// MarkMembershipPurchaseRevokedInTx 标记订单购买的会员已扣回
+func (vd *VipDao) MarkMembershipPurchaseRevokedInTx(tx *gorm.DB, purchaseId int64) error {
+	return tx.WithContext(vd.ctx).Model(model.UserMembershipPurchase{}).
+		Where("id = ?", purchaseId).
+		Update("revoked", 1).Error
+}
+
+// GetMembershipsAfter 按ID顺序分批读取会员信息
+func (vd *VipDao) GetMembershipsAfter(afterId int64, limit int) ([]*model.UserMembership, error) {
+	memberships := make([]*model.UserMembership, 0, limit)
+	err := DB().WithContext(vd.ctx).Where("id > ?", afterId).Order("id").Limit(limit).Find(&memberships).Error
+	return memberships, err
+}
+
+// SumUsersGrowthSince 统计每个用户 since 之后获得的成长值
+func (vd *VipDao) SumUsersGrowthSince(userIds []int64, since time.Time) (map[int64]int, error) {
+	rows := make([]struct {
+		UserId int64
+		Growth int
+	}, 0, len(userIds))
+	err := DB().WithContext(vd.ctx).Model(model.UserGrowthLog{}).
+		Select("user_id, SUM(growth) AS growth").
+		Where("user_id IN ? AND created_at >= ?", userIds, since).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	growths := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		growths[row.UserId] = row.Growth
+	}
+	return growths, nil
+}
+
+// UpdateMembershipLevel 定时任务重新计算会员等级后更新等级和到期时间, 读取之后等级被购买会员修改过时不更新
+func (vd *VipDao) UpdateMembershipLevel(membership *model.UserMembership, level int, levelExpireAt time.Time) error {
+	return DBMaster().WithContext(vd.ctx).Model(model.UserMembership{}).
+		Where("id = ? AND level = ? AND level_expire_at = ?", membership.ID, membership.Level, membership.LevelExpireAt).
+		Updates(map[string]interface{}{
+			"level":           level,
+			"level_expire_at": levelExpireAt,
+		}).Error
+}
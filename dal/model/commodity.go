@@ -14,10 +14,12 @@ type Commodity struct {
 	Images        string                `gorm:"column:images;NOT NULL"`                               // 商品细节图
 	DetailContent string                `gorm:"column:detail_content;NOT NULL"`                       // 商品详情
 	OriginalPrice int                   `gorm:"column:original_price;default:1;NOT NULL"`             // 商品原价
-	SellingPrice  int                   `gorm:"column:selling_price;default:1;NOT NULL"`              // 商品售价
-	StockNum      int                   `gorm:"column:stock_num;default:0;NOT NULL"`                  // 商品库存数量
+	SellingPrice  int                   `gorm:"column:selling_price;default:1;NOT NULL"`              // 商品售价, 有多个SKU时为最低的SKU售价
+	StockNum      int                   `gorm:"column:stock_num;default:0;NOT NULL"`                  // 所有SKU的初始库存合计, 仅用于展示, 库存扣减以SKU为准
 	Tag           string                `gorm:"column:tag;NOT NULL"`                                  // 商品标签
 	SellStatus    int                   `gorm:"column:sell_status;default:1;NOT NULL"`                // 商品上架状态 1-上架  2-下架
+	SalesNum      int                   `gorm:"column:sales_num;default:0;NOT NULL"`                  // 销量, 订单支付后累加
+	PurchaseLimit int                   `gorm:"column:purchase_limit;default:0;NOT NULL"`             // 每个用户的限购数量, 计入历史订单, 0-不单独限购
 	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 删除标识字段(0-未删除 1-已删除)
 	CreatedAt     time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
 	UpdatedAt     time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete"
+	"time"
+)
+
+// 商品评价 -- 每个订单购物项只能评价一次
+
+type CommodityReview struct {
+	ID            int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                   // 评价ID
+	UserId        int64                 `gorm:"column:user_id;NOT NULL"`                                // 评价用户ID
+	OrderId       int64                 `gorm:"column:order_id;NOT NULL"`                               // 订单ID
+	OrderItemId   int64                 `gorm:"column:order_item_id;uniqueIndex;NOT NULL"`              // 订单购物项ID
+	CommodityId   int64                 `gorm:"column:commodity_id;index;NOT NULL"`                     // 商品ID
+	SkuId         int64                 `gorm:"column:sku_id;NOT NULL"`                                 // SKU ID
+	SkuSpecDesc   string                `gorm:"column:sku_spec_desc;NOT NULL"`                          // 购买的规格描述(订单快照)
+	Rating        int                   `gorm:"column:rating;NOT NULL"`                                 // 星级 1-5
+	Content       string                `gorm:"column:content;NOT NULL"`                                // 评价内容
+	Images        string                `gorm:"column:images;NOT NULL"`                                 // 评价图片URL, JSON数组
+	MerchantReply string                `gorm:"column:merchant_reply;NOT NULL"`                         // 商家回复
+	RepliedAt     time.Time             `gorm:"column:replied_at;default:1970-01-01 00:00:00;NOT NULL"` // 商家回复时间, 未回复时为1970-01-01
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`                                        // 0-未删除 1-已删除
+	CreatedAt     time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`   // 创建时间
+	UpdatedAt     time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`   // 更新时间
+}
+
+func (CommodityReview) TableName() string {
+	return "commodity_reviews"
+}
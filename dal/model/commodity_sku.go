@@ -0,0 +1,58 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete"
+	"time"
+)
+
+// CommoditySpec 商品(SPU)的规格项, 例如 颜色、存储容量
+type CommoditySpec struct {
+	ID          int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 规格项ID
+	CommodityId int64                 `gorm:"column:commodity_id;NOT NULL"`                         // 所属商品ID
+	Name        string                `gorm:"column:name;NOT NULL"`                                 // 规格名称
+	Rank        int                   `gorm:"column:rank;default:0;NOT NULL"`                       // 排序, 越小越靠前
+	IsDel       soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 删除标识字段(0-未删除 1-已删除)
+	CreatedAt   time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt   time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (CommoditySpec) TableName() string {
+	return "commodity_specs"
+}
+
+// CommoditySpecValue 规格项的可选值, 例如 黑色、128GB
+type CommoditySpecValue struct {
+	ID          int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 规格值ID
+	CommodityId int64                 `gorm:"column:commodity_id;NOT NULL"`                         // 所属商品ID
+	SpecId      int64                 `gorm:"column:spec_id;NOT NULL"`                              // 所属规格项ID
+	Value       string                `gorm:"column:value;NOT NULL"`                                // 规格值
+	Rank        int                   `gorm:"column:rank;default:0;NOT NULL"`                       // 排序, 越小越靠前
+	IsDel       soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 删除标识字段(0-未删除 1-已删除)
+	CreatedAt   time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt   time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (CommoditySpecValue) TableName() string {
+	return "commodity_spec_values"
+}
+
+// CommoditySku 商品的可售单元, 价格、库存、图片以SKU为准
+type CommoditySku struct {
+	ID            int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // SKU ID
+	CommodityId   int64                 `gorm:"column:commodity_id;NOT NULL"`                         // 所属商品ID
+	SpecValueIds  string                `gorm:"column:spec_value_ids;NOT NULL"`                       // 按规格项排序的规格值ID, 逗号分隔
+	SpecDesc      string                `gorm:"column:spec_desc;NOT NULL"`                            // 规格描述, 例如 "黑色 128GB"
+	CoverImg      string                `gorm:"column:cover_img;NOT NULL"`                            // SKU主图
+	Images        string                `gorm:"column:images;NOT NULL"`                               // SKU细节图
+	OriginalPrice int                   `gorm:"column:original_price;default:1;NOT NULL"`             // 原价
+	SellingPrice  int                   `gorm:"column:selling_price;default:1;NOT NULL"`              // 售价
+	StockNum      int                   `gorm:"column:stock_num;default:0;NOT NULL"`                  // 库存数量
+	SellStatus    int                   `gorm:"column:sell_status;default:1;NOT NULL"`                // 上架状态 1-上架  2-下架
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 删除标识字段(0-未删除 1-已删除)
+	CreatedAt     time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt     time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (CommoditySku) TableName() string {
+	return "commodity_skus"
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete"
+	"time"
+)
+
+// CouponTemplate 优惠券模板, 用户领取或后台发放时按模板生成用户优惠券
+type CouponTemplate struct {
+	ID            int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 模板ID
+	Name          string                `gorm:"column:name;NOT NULL"`                                 // 优惠券名称
+	DiscountType  int                   `gorm:"column:discount_type;default:1;NOT NULL"`              // 优惠方式 1-满减 2-折扣
+	DiscountValue int                   `gorm:"column:discount_value;default:0;NOT NULL"`             // 满减时为减免金额(分), 折扣时为减免的百分比
+	MaxDiscount   int                   `gorm:"column:max_discount;default:0;NOT NULL"`               // 折扣最多减免的金额(分), 0-不限
+	Threshold     int                   `gorm:"column:threshold;default:0;NOT NULL"`                  // 使用范围内的商品金额满多少(分)可用, 0-无门槛
+	ScopeType     int                   `gorm:"column:scope_type;default:0;NOT NULL"`                 // 使用范围 0-全场 1-指定三级分类 2-指定商品
+	ScopeIds      string                `gorm:"column:scope_ids;NOT NULL"`                            // 使用范围内的分类或商品ID, 逗号分隔
+	TotalNum      int                   `gorm:"column:total_num;default:0;NOT NULL"`                  // 发行数量, 0-不限
+	IssuedNum     int                   `gorm:"column:issued_num;default:0;NOT NULL"`                 // 已领取和发放的数量
+	PerUserLimit  int                   `gorm:"column:per_user_limit;default:1;NOT NULL"`             // 每个用户最多领取的数量
+	ValidStartAt  time.Time             `gorm:"column:valid_start_at;NOT NULL"`                       // 领取和使用的开始时间
+	ValidEndAt    time.Time             `gorm:"column:valid_end_at;NOT NULL"`                         // 领取和使用的结束时间
+	ValidDays     int                   `gorm:"column:valid_days;default:0;NOT NULL"`                 // 领取后多少天内有效, 不超过结束时间, 0-到结束时间为止
+	Status        int                   `gorm:"column:status;default:1;NOT NULL"`                     // 状态 1-可领取 2-停止领取
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 删除标识字段(0-未删除 1-已删除)
+	CreatedAt     time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt     time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (CouponTemplate) TableName() string {
+	return "coupon_templates"
+}
+
+// UserCoupon 用户领取的优惠券, 下单时被订单锁定, 支付后变为已使用, 订单取消或关闭时解锁
+type UserCoupon struct {
+	ID           int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                   // 用户优惠券ID
+	UserId       int64     `gorm:"column:user_id;index:idx_user_status;NOT NULL"`          // 用户ID
+	TemplateId   int64     `gorm:"column:template_id;index;NOT NULL"`                      // 优惠券模板ID
+	Status       int       `gorm:"column:status;index:idx_user_status;default:1;NOT NULL"` // 状态 1-未使用 2-已锁定 3-已使用
+	OrderId      int64     `gorm:"column:order_id;index;default:0;NOT NULL"`               // 锁定或使用优惠券的订单ID
+	ValidStartAt time.Time `gorm:"column:valid_start_at;NOT NULL"`                         // 有效期开始时间
+	ValidEndAt   time.Time `gorm:"column:valid_end_at;NOT NULL"`                           // 有效期结束时间
+	UsedAt       time.Time `gorm:"column:used_at;default:1970-01-01 00:00:00;NOT NULL"`    // 使用时间, 未使用时为1970-01-01
+	CreatedAt    time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`   // 领取时间
+	UpdatedAt    time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`   // 更新时间
+}
+
+func (UserCoupon) TableName() string {
+	return "user_coupons"
+}
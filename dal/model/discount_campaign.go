@@ -0,0 +1,27 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete"
+	"time"
+)
+
+// DiscountCampaign 满减活动, 结算时按优先级从同时满足门槛的活动中选择一个使用
+type DiscountCampaign struct {
+	ID        int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 活动ID
+	Name      string                `gorm:"column:name;NOT NULL"`                                 // 活动名称
+	Type      int                   `gorm:"column:type;default:1;NOT NULL"`                       // 活动类型 1-阶梯满减 2-满件折扣 3-组合一口价
+	Tiers     string                `gorm:"column:tiers;type:varchar(1024);NOT NULL"`             // 活动门槛和优惠, JSON数组 [{"threshold":门槛,"value":优惠}]
+	ScopeType int                   `gorm:"column:scope_type;default:0;NOT NULL"`                 // 适用范围 0-全场 1-指定三级分类 2-指定商品
+	ScopeIds  string                `gorm:"column:scope_ids;NOT NULL"`                            // 适用范围内的分类或商品ID, 逗号分隔
+	Priority  int                   `gorm:"column:priority;default:0;NOT NULL"`                   // 优先级, 数值大的优先使用
+	StartTime time.Time             `gorm:"column:start_time;NOT NULL"`                           // 活动开始时间
+	EndTime   time.Time             `gorm:"column:end_time;NOT NULL"`                             // 活动结束时间
+	Status    int                   `gorm:"column:status;default:1;NOT NULL"`                     // 活动状态 1-生效 2-下线
+	IsDel     soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 0-未删除 1-已删除
+	CreatedAt time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (DiscountCampaign) TableName() string {
+	return "discount_campaigns"
+}
@@ -10,7 +10,9 @@ type OrderItem struct {
 	ID                    int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 订单关联购物项主键id
 	OrderId               int64     `gorm:"column:order_id;NOT NULL"`                             // 订单主键id
 	CommodityId           int64     `gorm:"column:commodity_id;NOT NULL"`                         // 关联的商品id
+	SkuId                 int64     `gorm:"column:sku_id;default:0;NOT NULL"`                     // 关联的SKU id
 	CommodityName         string    `gorm:"column:commodity_name;NOT NULL"`                       // 下单时商品的名称(订单快照)
+	SkuSpecDesc           string    `gorm:"column:sku_spec_desc;NOT NULL"`                        // 下单时SKU的规格描述(订单快照)
 	CommodityImg          string    `gorm:"column:commodity_img;NOT NULL"`                        // 下单时商品的主图(订单快照)
 	CommoditySellingPrice int       `gorm:"column:commodity_selling_price;default:0;NOT NULL"`    // 下单时商品的价格(订单快照)
 	CommodityNum          int       `gorm:"column:commodity_num;default:1;NOT NULL"`              // 数量(订单快照)
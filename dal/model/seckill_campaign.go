@@ -0,0 +1,26 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete"
+	"time"
+)
+
+type SeckillCampaign struct {
+	ID           int64                 `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 秒杀活动ID
+	Name         string                `gorm:"column:name;NOT NULL"`                                 // 活动名称
+	CommodityId  int64                 `gorm:"column:commodity_id;NOT NULL"`                         // 秒杀商品ID
+	SkuId        int64                 `gorm:"column:sku_id;NOT NULL"`                               // 秒杀商品的SKU ID
+	SeckillPrice int                   `gorm:"column:seckill_price;default:0;NOT NULL"`              // 秒杀价（分）
+	Quota        int                   `gorm:"column:quota;default:0;NOT NULL"`                      // 活动专属名额, 从商品库存中划出
+	UserLimit    int                   `gorm:"column:user_limit;default:1;NOT NULL"`                 // 每个用户限购数量
+	StartTime    time.Time             `gorm:"column:start_time;NOT NULL"`                           // 活动开始时间
+	EndTime      time.Time             `gorm:"column:end_time;NOT NULL"`                             // 活动结束时间
+	Status       int                   `gorm:"column:status;default:1;NOT NULL"`                     // 活动状态 1-生效 2-下线
+	IsDel        soft_delete.DeletedAt `gorm:"softDelete:flag"`                                      // 0-未删除 1-已删除
+	CreatedAt    time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt    time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (SeckillCampaign) TableName() string {
+	return "seckill_campaigns"
+}
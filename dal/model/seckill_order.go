@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// SeckillOrder 秒杀请求创建的订单, 订单取消或超时关闭时据此退回预扣的秒杀名额
+type SeckillOrder struct {
+	ID         int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                    // 记录ID
+	CampaignId int64     `gorm:"column:campaign_id;NOT NULL"`                             // 秒杀活动ID
+	RequestId  string    `gorm:"column:request_id;type:varchar(64);uniqueIndex;NOT NULL"` // 秒杀请求ID, 同一请求只创建一个订单
+	OrderId    int64     `gorm:"column:order_id;uniqueIndex;NOT NULL"`                    // 订单ID
+	OrderNo    string    `gorm:"column:order_no;NOT NULL"`                                // 订单号
+	UserId     int64     `gorm:"column:user_id;NOT NULL"`                                 // 用户ID
+	Num        int       `gorm:"column:num;NOT NULL"`                                     // 抢购数量
+	Reverted   int       `gorm:"column:reverted;default:0;NOT NULL"`                      // 名额是否已退回 0-否 1-是
+	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`    // 创建时间
+	UpdatedAt  time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`    // 更新时间
+}
+
+func (SeckillOrder) TableName() string {
+	return "seckill_orders"
+}
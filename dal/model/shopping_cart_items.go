@@ -9,7 +9,10 @@ type ShoppingCartItem struct {
 	CartItemId   int64                 `gorm:"column:cart_item_id;primary_key;AUTO_INCREMENT"`
 	UserId       int64                 `gorm:"column:user_id;NOT NULL"`
 	CommodityId  int64                 `gorm:"column:commodity_id;NOT NULL"`
+	SkuId        int64                 `gorm:"column:sku_id;default:0;NOT NULL"`
 	CommodityNum int                   `gorm:"column:commodity_num;default:1;NOT NULL"`
+	AddedPrice   int                   `gorm:"column:added_price;default:0;NOT NULL"` // 加购时的售价
+	Selected     bool                  `gorm:"column:selected;default:1;NOT NULL"`    // 是否勾选结算
 	IsDel        soft_delete.DeletedAt `gorm:"softDelete:flag"`
 	CreatedAt    time.Time             `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`
 	UpdatedAt    time.Time             `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// 用户收藏的商品, 取消收藏时直接删除记录, 以便再次收藏时不与唯一键冲突
+
+type UserFavorite struct {
+	ID             int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                         // 收藏ID
+	UserId         int64     `gorm:"column:user_id;uniqueIndex:uniq_user_commodity;NOT NULL"`      // 用户ID
+	CommodityId    int64     `gorm:"column:commodity_id;uniqueIndex:uniq_user_commodity;NOT NULL"` // 商品ID
+	FavoritedPrice int       `gorm:"column:favorited_price;default:0;NOT NULL"`                    // 降价提醒的参考价(分), 收藏时的售价, 提醒降价后更新为降价后的售价
+	CreatedAt      time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`         // 收藏时间
+	UpdatedAt      time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`         // 更新时间
+}
+
+func (UserFavorite) TableName() string {
+	return "user_favorites"
+}
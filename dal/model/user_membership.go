@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// UserMembership 用户的会员信息, 用户第一次获得成长值或购买会员时创建
+type UserMembership struct {
+	ID            int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                        // 会员ID
+	UserId        int64     `gorm:"column:user_id;uniqueIndex;NOT NULL"`                         // 用户ID
+	Level         int       `gorm:"column:level;default:0;NOT NULL"`                             // 会员等级, 0-普通用户
+	Growth        int       `gorm:"column:growth;default:0;NOT NULL"`                            // 累计获得的成长值
+	LevelExpireAt time.Time `gorm:"column:level_expire_at;default:1970-01-01 00:00:00;NOT NULL"` // 会员等级的到期时间
+	CreatedAt     time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`        // 创建时间
+	UpdatedAt     time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"`        // 更新时间
+}
+
+func (UserMembership) TableName() string {
+	return "user_memberships"
+}
+
+// UserGrowthLog 用户获得成长值的记录, 每个订单只记录一次
+type UserGrowthLog struct {
+	ID        int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                                        // 记录ID
+	UserId    int64     `gorm:"column:user_id;index:idx_user_created;NOT NULL"`                              // 用户ID
+	OrderId   int64     `gorm:"column:order_id;uniqueIndex;NOT NULL"`                                        // 获得成长值的订单ID
+	Growth    int       `gorm:"column:growth;default:0;NOT NULL"`                                            // 获得的成长值
+	CreatedAt time.Time `gorm:"column:created_at;index:idx_user_created;default:CURRENT_TIMESTAMP;NOT NULL"` // 获得时间
+}
+
+func (UserGrowthLog) TableName() string {
+	return "user_growth_logs"
+}
+
+// UserMembershipPurchase 订单购买会员的记录, 每个订单只记录一次, 订单退款时据此扣回购买的天数
+type UserMembershipPurchase struct {
+	ID        int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 记录ID
+	UserId    int64     `gorm:"column:user_id;NOT NULL"`                              // 用户ID
+	OrderId   int64     `gorm:"column:order_id;uniqueIndex;NOT NULL"`                 // 购买会员的订单ID
+	Level     int       `gorm:"column:level;NOT NULL"`                                // 购买的会员等级
+	Days      int       `gorm:"column:days;NOT NULL"`                                 // 购买的天数
+	Revoked   int       `gorm:"column:revoked;default:0;NOT NULL"`                    // 是否已因退款扣回 0-否 1-是
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 购买时间
+	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (UserMembershipPurchase) TableName() string {
+	return "user_membership_purchases"
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// UserPointsAccount 用户的积分余额, 用户第一次获得积分时创建
+type UserPointsAccount struct {
+	ID        int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                 // 账户ID
+	UserId    int64     `gorm:"column:user_id;uniqueIndex;NOT NULL"`                  // 用户ID
+	Balance   int       `gorm:"column:balance;default:0;NOT NULL"`                    // 可用的积分
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 创建时间
+	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;NOT NULL"` // 更新时间
+}
+
+func (UserPointsAccount) TableName() string {
+	return "user_points_accounts"
+}
+
+// UserPointsEntry 积分流水, 只追加不修改积分数量.
+// 增加积分的流水用 Remaining 记录还没有使用和过期的部分, 使用积分时按过期时间从早到晚扣减
+type UserPointsEntry struct {
+	ID        int64     `gorm:"column:id;primary_key;AUTO_INCREMENT"`                         // 流水ID
+	UserId    int64     `gorm:"column:user_id;index:idx_user_remaining;NOT NULL"`             // 用户ID
+	Type      int       `gorm:"column:type;NOT NULL"`                                         // 流水类型, 取值见 enum.PointsEntry*
+	Points    int       `gorm:"column:points;NOT NULL"`                                       // 积分变动, 增加为正数, 减少为负数
+	Remaining int       `gorm:"column:remaining;index:idx_user_remaining;default:0;NOT NULL"` // 增加的积分中还没有使用和过期的部分
+	ExpireAt  time.Time `gorm:"column:expire_at;index;default:1970-01-01 00:00:00;NOT NULL"`  // 增加的积分的过期时间
+	OrderId   int64     `gorm:"column:order_id;index;default:0;NOT NULL"`                     // 关联的订单ID
+	BizKey    string    `gorm:"column:biz_key;type:varchar(64);uniqueIndex;NOT NULL"`         // 业务唯一键, 保证同一业务只记录一次
+	Remark    string    `gorm:"column:remark;type:varchar(128);default:'';NOT NULL"`          // 向用户展示的说明
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;NOT NULL"`         // 记录时间
+}
+
+func (UserPointsEntry) TableName() string {
+	return "user_points_entries"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/Ian-zy0329/go-mall/api/reply"
 	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
 	"github.com/Ian-zy0329/go-mall/common/util"
 	"github.com/Ian-zy0329/go-mall/logic/do"
@@ -24,23 +25,67 @@ func NewCartAppSvc(ctx context.Context) *CartAppSvc {
 }
 
 func (cas *CartAppSvc) AddCartItem(request *request.AddCartItem, userId int64) error {
-	commodityDomainSvc := domainservice.NewCommodityDomainSvc(cas.ctx)
-	commodityInfo := commodityDomainSvc.GetCommodityInfo(request.CommodityId)
-	if commodityInfo == nil || commodityInfo.ID == 0 { // 商品不存在
-		return errcode.ErrCommodityNotExists
+	cartItem, _, err := newSellableCartItem(cas.ctx, request.SkuId, request.CommodityNum, userId)
+	if err != nil {
+		return err
 	}
-	if commodityInfo.StockNum < request.CommodityNum {
-		// 先初步判断库存是否充足, 下单时需要重新用当前读判断库存
-		return errcode.ErrCommodityStockOut
+	return cas.cartDomainSvc.CartAddItem(cartItem)
+}
+
+// GuestCartAddItem 未登录用户加购, cartToken 为空时生成新的购物车标识, 返回使用的购物车标识
+func (cas *CartAppSvc) GuestCartAddItem(cartToken string, request *request.GuestCartItem) (*reply.GuestCartToken, error) {
+	if _, _, err := newSellableCartItem(cas.ctx, request.SkuId, request.CommodityNum, 0); err != nil {
+		return nil, err
 	}
+	if cartToken == "" {
+		cartToken = domainservice.NewGuestCartToken()
+	}
+	if err := cas.cartDomainSvc.GuestCartAddItem(cartToken, request.SkuId, request.CommodityNum); err != nil {
+		return nil, err
+	}
+	return &reply.GuestCartToken{CartToken: cartToken}, nil
+}
+
+func (cas *CartAppSvc) GuestCartUpdateItem(cartToken string, request *request.GuestCartItem) error {
+	return cas.cartDomainSvc.GuestCartUpdateItem(cartToken, request.SkuId, request.CommodityNum)
+}
 
-	cartItem := new(do.ShoppingCartItem)
-	err := util.CopyProperties(&cartItem, request)
+func (cas *CartAppSvc) GuestCartDeleteItem(cartToken string, skuId int64) error {
+	return cas.cartDomainSvc.GuestCartDeleteItem(cartToken, skuId)
+}
+
+func (cas *CartAppSvc) GetGuestCartItems(cartToken string) (*reply.UserCart, error) {
+	cartItemDomains, err := cas.cartDomainSvc.GetGuestCartItems(cartToken)
 	if err != nil {
-		return errcode.ErrCoverData.WithCause(err)
+		return nil, err
 	}
-	cartItem.UserId = userId
-	return cas.cartDomainSvc.CartAddItem(cartItem)
+	return newUserCartReply(cartItemDomains)
+}
+
+// MigrateCartStorage 迁移购物车存储, 见 domainservice.MigrateCartStorage
+func (cas *CartAppSvc) MigrateCartStorage(toRedis bool) (int, error) {
+	return domainservice.MigrateCartStorage(cas.ctx, toRedis)
+}
+
+// newSellableCartItem 校验SKU可售且库存初步充足后生成要加入购物车的购物项, 同时返回SKU的售卖信息
+func newSellableCartItem(ctx context.Context, skuId int64, commodityNum int, userId int64) (*do.ShoppingCartItem, *do.SkuSellInfo, error) {
+	sellInfos, err := domainservice.NewCommodityDomainSvc(ctx).CheckSkusSellable([]int64{skuId})
+	if err != nil {
+		return nil, nil, err
+	}
+	sellInfo := sellInfos[skuId]
+	if sellInfo.Sku.StockNum < commodityNum {
+		// 先初步判断库存是否充足, 下单时需要重新用当前读判断库存
+		return nil, nil, errcode.ErrCommodityStockOut
+	}
+	cartItem := &do.ShoppingCartItem{
+		UserId:       userId,
+		CommodityId:  sellInfo.Sku.CommodityId,
+		SkuId:        skuId,
+		CommodityNum: commodityNum,
+		AddedPrice:   sellInfo.Sku.SellingPrice,
+	}
+	return cartItem, sellInfo, nil
 }
 
 func (cas *CartAppSvc) CheckCartItemBill(itemIds []int64, userId int64) (*reply.CheckedCartItemBill, error) {
@@ -64,30 +109,66 @@ func (cas *CartAppSvc) UpdateCartItem(request *request.CartItemUpdate, userId in
 	return cas.cartDomainSvc.UpdateCartItem(request, userId)
 }
 
-func (cas *CartAppSvc) GetUserCartItems(userId int64) ([]*reply.CartItem, error) {
+func (cas *CartAppSvc) GetUserCartItems(userId int64) (*reply.UserCart, error) {
 	cartItemDomains, err := cas.cartDomainSvc.GetUserCartItems(userId)
 	if err != nil {
 		return nil, err
 	}
-	cartItems := make([]*reply.CartItem, 0, len(cartItemDomains))
-	err = util.CopyProperties(&cartItems, cartItemDomains)
-	if err != nil {
+	return newUserCartReply(cartItemDomains)
+}
+
+// newUserCartReply 把购物项按是否可以结算分为两组
+func newUserCartReply(cartItemDomains []*do.ShoppingCartItem) (*reply.UserCart, error) {
+	validItems, invalidItems := lo.FilterReject(cartItemDomains, func(item *do.ShoppingCartItem, _ int) bool {
+		return item.IsValid()
+	})
+	userCart := &reply.UserCart{
+		Items:        make([]*reply.CartItem, 0, len(validItems)),
+		InvalidItems: make([]*reply.CartItem, 0, len(invalidItems)),
+	}
+	if err := util.CopyProperties(&userCart.Items, &validItems); err != nil {
 		return nil, errcode.ErrCoverData.WithCause(err)
 	}
-	return cartItems, nil
+	if err := util.CopyProperties(&userCart.InvalidItems, &invalidItems); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return userCart, nil
 }
 
 func (cas *CartAppSvc) DeleteCartItem(userId, cartItemId int64) error {
 	return cas.cartDomainSvc.DeleteCartItem(userId, cartItemId)
 }
 
-func (cas *CartAppSvc) CheckCartItemBillV2(cartItemIds []int64, userId int64) (*reply.CheckedCartItemBillV2, error) {
+func (cas *CartAppSvc) SelectCartItems(request *request.CartItemSelect, userId int64) error {
+	return cas.cartDomainSvc.SelectCartItems(userId, request.CartItemIds, *request.Selected)
+}
+
+func (cas *CartAppSvc) SelectAllCartItems(request *request.CartSelectAll, userId int64) error {
+	return cas.cartDomainSvc.SelectAllCartItems(userId, *request.Selected)
+}
+
+func (cas *CartAppSvc) DeleteCartItems(request *request.CartItemBatchDelete, userId int64) error {
+	return cas.cartDomainSvc.DeleteCartItems(userId, request.CartItemIds)
+}
+
+func (cas *CartAppSvc) ClearInvalidCartItems(userId int64) (*reply.CartInvalidCleared, error) {
+	cleared, err := cas.cartDomainSvc.ClearInvalidCartItems(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.CartInvalidCleared{ClearedNum: cleared}, nil
+}
+
+func (cas *CartAppSvc) CheckCartItemBillV2(cartItemIds []int64, userCouponId int64, usePoints int, userId int64) (*reply.CheckedCartItemBillV2, error) {
 	checkedCartItems, err := cas.cartDomainSvc.GetCheckedCartItems(cartItemIds, userId)
 	if err != nil {
 		return nil, err
 	}
-	billChecker := domainservice.NewCartBillChecker(checkedCartItems, userId)
+	billChecker := domainservice.NewCartBillChecker(cas.ctx, checkedCartItems, userId).UseCoupon(userCouponId).UsePoints(usePoints)
 	billInfo, err := billChecker.GetBill()
+	if err != nil {
+		return nil, err
+	}
 	replyBillInfo := new(reply.CheckedCartItemBillV2)
 	if err = util.CopyProperties(&replyBillInfo.Items, checkedCartItems); err != nil {
 		return nil, errcode.ErrCoverData.WithCause(err)
@@ -95,5 +176,23 @@ func (cas *CartAppSvc) CheckCartItemBillV2(cartItemIds []int64, userId int64) (*
 	if err = util.CopyProperties(&replyBillInfo.BillDetail, &billInfo); err != nil {
 		return nil, errcode.ErrCoverData.WithCause(err)
 	}
+	replyBillInfo.BillDetail.UsableCoupons = lo.Map(billInfo.UsableCoupons, func(coupon *do.CouponDiscount, _ int) *reply.UsableCoupon {
+		return &reply.UsableCoupon{
+			UserCouponId:  coupon.UserCoupon.ID,
+			CouponName:    coupon.UserCoupon.Template.Name,
+			DiscountMoney: coupon.DiscountMoney,
+			ValidEndAt:    coupon.UserCoupon.ValidEndAt.Format(enum.TimeFormatHyphenedYMDHIS),
+		}
+	})
+	replyBillInfo.BillDetail.DiscountCampaigns = lo.Map(billInfo.DiscountCampaigns, func(evaluation *do.DiscountEvaluation, _ int) *reply.DiscountCampaignResult {
+		return &reply.DiscountCampaignResult{
+			CampaignId:    evaluation.Campaign.ID,
+			CampaignName:  evaluation.Campaign.Name,
+			Result:        evaluation.Result,
+			ScopeAmount:   evaluation.ScopeAmount,
+			ScopeNum:      evaluation.ScopeNum,
+			DiscountMoney: evaluation.DiscountMoney,
+		}
+	})
 	return replyBillInfo, nil
 }
@@ -3,11 +3,17 @@ package appservice
 import (
 	"context"
 	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
 	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
 	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"github.com/samber/lo"
+	"strings"
+	"time"
 )
 
 type CommodityAppSvc struct {
@@ -47,36 +53,129 @@ func (cas *CommodityAppSvc) GetSubCategories(parentId int64) []*reply.CommodityC
 	return replyData
 }
 
-func (cas *CommodityAppSvc) GetCategoryCommodityList(categoryId int64, pagination *app.Pagination) ([]*reply.CommodityListElem, error) {
-	categoryInfo := cas.commodityDomainSvc.GetCategoryInfo(categoryId)
+func (cas *CommodityAppSvc) GetCategoryCommodityList(request *request.CommodityInCategory, pagination *app.Pagination) (*reply.CommodityList, error) {
+	categoryInfo := cas.commodityDomainSvc.GetCategoryInfo(request.CategoryId)
 	if categoryInfo == nil || categoryInfo.ID == 0 {
 		return nil, errcode.ErrParams
 	}
-	commodityList, err := cas.commodityDomainSvc.GetCommodityListInCategory(categoryInfo, pagination)
+	commodityList, facets, err := cas.commodityDomainSvc.GetCommodityListInCategory(categoryInfo, newCommodityListQuery(&request.CommodityListFilter), pagination)
 	if err != nil {
 		return nil, err
 	}
-	replyCommodityList := make([]*reply.CommodityListElem, 0, len(commodityList))
-	err = util.CopyProperties(&replyCommodityList, &commodityList)
+	return newCommodityListReply(commodityList, facets)
+}
+
+func (cas *CommodityAppSvc) SearchCommodity(request *request.CommoditySearch, pagination *app.Pagination) (*reply.CommodityList, error) {
+	var categoryInfo *do.CommodityCategory
+	if request.CategoryId > 0 {
+		categoryInfo = cas.commodityDomainSvc.GetCategoryInfo(request.CategoryId)
+		if categoryInfo == nil || categoryInfo.ID == 0 {
+			return nil, errcode.ErrParams
+		}
+	}
+	commodityList, facets, err := cas.commodityDomainSvc.SearchCommodity(request.Keyword, categoryInfo, newCommodityListQuery(&request.CommodityListFilter), pagination)
+	if err != nil {
+		return nil, err
+	}
+	replyData, err := newCommodityListReply(commodityList, facets)
 	if err != nil {
+		return nil, err
+	}
+	terms := util.SearchTerms(request.Keyword)
+	for _, commodity := range replyData.List {
+		commodity.Highlight = &reply.CommodityHighlight{
+			Name:  util.HighlightTerms(commodity.Name, terms),
+			Intro: util.HighlightTerms(commodity.Intro, terms),
+			Tag:   util.HighlightTerms(commodity.Tag, terms),
+		}
+	}
+
+	return replyData, nil
+}
+
+// SearchSuggest 搜索框输入时的补全
+func (cas *CommodityAppSvc) SearchSuggest(keyword string) ([]*reply.SearchSuggestion, error) {
+	suggestions, err := domainservice.NewSearchDomainSvc(cas.ctx).Suggest(keyword, enum.SearchSuggestLimit)
+	if err != nil {
+		return nil, err
+	}
+	replySuggestions := make([]*reply.SearchSuggestion, 0, len(suggestions))
+	if err = util.CopyProperties(&replySuggestions, &suggestions); err != nil {
 		return nil, errcode.ErrCoverData.WithCause(err)
 	}
-	return replyCommodityList, nil
+	return replySuggestions, nil
 }
 
-func (cas *CommodityAppSvc) SearchCommodity(keyword string, pagination *app.Pagination) ([]*reply.CommodityListElem, error) {
-	commodityList, err := cas.commodityDomainSvc.SearchCommodity(keyword, pagination)
+// HotSearchQueries 某一天的热搜榜, date 为空时为当天
+func (cas *CommodityAppSvc) HotSearchQueries(request *request.HotSearch) ([]*reply.SearchQueryStat, error) {
+	day := time.Now()
+	if request.Date != "" {
+		var err error
+		day, err = time.ParseInLocation(enum.TimeFormatHyphenedYMD, request.Date, time.Local)
+		if err != nil {
+			return nil, errcode.ErrParams.WithCause(err)
+		}
+	}
+	size := request.Size
+	if size <= 0 {
+		size = enum.DefaultHotKeywordsSize
+	}
+	hotQueries, err := domainservice.NewSearchDomainSvc(cas.ctx).GetHotQueries(day, size)
 	if err != nil {
 		return nil, err
 	}
-	replyCommodityList := make([]*reply.CommodityListElem, 0, len(commodityList))
-	err = util.CopyProperties(&replyCommodityList, &commodityList)
+	replyQueries := make([]*reply.SearchQueryStat, 0, len(hotQueries))
+	if err = util.CopyProperties(&replyQueries, &hotQueries); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyQueries, nil
+}
+
+// ZeroResultSearchQueries 搜索次数最多的无结果搜索词, 供运营补充商品或调整商品描述
+func (cas *CommodityAppSvc) ZeroResultSearchQueries(limit int) ([]*reply.SearchQueryStat, error) {
+	queries, err := domainservice.NewSearchDomainSvc(cas.ctx).GetZeroResultQueries(limit)
 	if err != nil {
+		return nil, err
+	}
+	replyQueries := make([]*reply.SearchQueryStat, 0, len(queries))
+	if err = util.CopyProperties(&replyQueries, &queries); err != nil {
 		return nil, errcode.ErrCoverData.WithCause(err)
 	}
+	return replyQueries, nil
+}
+
+// TrimSearchQueries 清理热度最低的搜索词, 返回清理的数量
+func (cas *CommodityAppSvc) TrimSearchQueries() (int, error) {
+	return domainservice.NewSearchDomainSvc(cas.ctx).TrimQueries()
+}
+
+func newCommodityListQuery(filter *request.CommodityListFilter) *do.CommodityListQuery {
+	tags := lo.Compact(lo.Map(strings.Split(filter.Tags, ","), func(tag string, _ int) string {
+		return strings.TrimSpace(tag)
+	}))
+	return &do.CommodityListQuery{
+		MinPrice:    filter.MinPrice,
+		MaxPrice:    filter.MaxPrice,
+		Tags:        tags,
+		InStockOnly: filter.InStock,
+		Sort:        filter.Sort,
+	}
+}
 
-	return replyCommodityList, nil
+func newCommodityListReply(commodityList []*do.Commodity, facets *do.CommodityFacets) (*reply.CommodityList, error) {
+	replyData := &reply.CommodityList{
+		List:   make([]*reply.CommodityListElem, 0, len(commodityList)),
+		Facets: new(reply.CommodityFacets),
+	}
+	if err := util.CopyProperties(&replyData.List, &commodityList); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	if err := util.CopyProperties(replyData.Facets, facets); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyData, nil
 }
+
 func (cas *CommodityAppSvc) CommodityInfo(commodityId int64) *reply.Commodity {
 	commodityDO := cas.commodityDomainSvc.GetCommodityInfo(commodityId)
 	if commodityDO == nil || commodityDO.ID == 0 {
@@ -91,3 +190,115 @@ func (cas *CommodityAppSvc) CommodityInfo(commodityId int64) *reply.Commodity {
 func (cas *CommodityAppSvc) InitRedisStock() error {
 	return cas.commodityDomainSvc.InitRedisStock()
 }
+
+// GetCommodityStock 获取商品每个SKU的库存
+func (cas *CommodityAppSvc) GetCommodityStock(commodityId int64) ([]*reply.CommodityStock, error) {
+	_, skus, err := cas.commodityDomainSvc.GetCommoditySkus(commodityId)
+	if err != nil {
+		return nil, err
+	}
+	if len(skus) == 0 {
+		return nil, errcode.ErrCommodityNotExists
+	}
+	stockDomainSvc := domainservice.NewStockDomainSvc(cas.ctx)
+	stocks := make([]*reply.CommodityStock, 0, len(skus))
+	for _, sku := range skus {
+		stockItem, err := stockDomainSvc.GetSkuStock(sku.ID)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, &reply.CommodityStock{
+			CommodityId: commodityId,
+			SkuId:       stockItem.ItemID,
+			Available:   stockItem.Stock,
+			Reserved:    stockItem.Reserved,
+			Sold:        stockItem.Sold,
+		})
+	}
+	return stocks, nil
+}
+
+// GetCommoditySkus 获取商品的规格矩阵: 规格项及可选值, 每个SKU对应的规格值、价格和可售库存
+func (cas *CommodityAppSvc) GetCommoditySkus(commodityId int64) (*reply.CommoditySkuMatrix, error) {
+	specs, skus, err := cas.commodityDomainSvc.GetCommoditySkus(commodityId)
+	if err != nil {
+		return nil, err
+	}
+	if len(skus) == 0 {
+		return nil, errcode.ErrCommodityNotExists
+	}
+	matrix := &reply.CommoditySkuMatrix{
+		Specs: make([]*reply.CommoditySpec, 0, len(specs)),
+		Skus:  make([]*reply.CommoditySku, 0, len(skus)),
+	}
+	if err = util.CopyProperties(&matrix.Specs, &specs); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	stockDomainSvc := domainservice.NewStockDomainSvc(cas.ctx)
+	for _, sku := range skus {
+		replySku := new(reply.CommoditySku)
+		if err = util.CopyProperties(replySku, sku); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		replySku.SpecValueIds = sku.SpecValueIdList()
+		stockItem, err := stockDomainSvc.GetSkuStock(sku.ID)
+		if err != nil {
+			return nil, err
+		}
+		replySku.Available = stockItem.Stock
+		if sku.SellStatus != enum.CommoditySellStatusOn {
+			// 下架的规格不可购买, 前端按无货展示
+			replySku.Available = 0
+		}
+		matrix.Skus = append(matrix.Skus, replySku)
+	}
+	return matrix, nil
+}
+
+// MigrateSearchSchema 创建商品搜索使用的全文索引
+func (cas *CommodityAppSvc) MigrateSearchSchema() error {
+	return cas.commodityDomainSvc.MigrateSearchSchema()
+}
+
+// MigrateToSku 把旧商品数据迁移到 SPU/SKU 模型, 返回迁移的商品数量
+func (cas *CommodityAppSvc) MigrateToSku() (int, error) {
+	return cas.commodityDomainSvc.MigrateToSku()
+}
+
+// CheckStockConsistency 对账Redis与MySQL的库存, repair 指定修复的一方, 为空时只报告差异
+func (cas *CommodityAppSvc) CheckStockConsistency(repair string) ([]*reply.StockDiff, error) {
+	diffs, err := domainservice.NewStockDomainSvc(cas.ctx).CheckStockConsistency(repair)
+	if err != nil {
+		return nil, err
+	}
+	replyDiffs := make([]*reply.StockDiff, 0, len(diffs))
+	if err = util.CopyProperties(&replyDiffs, &diffs); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyDiffs, nil
+}
+
+// RelatedCommodities 经常与商品一起购买的商品, 不足时用同分类的热销商品补充
+func (cas *CommodityAppSvc) RelatedCommodities(commodityId int64, size int) ([]*reply.CommodityListElem, error) {
+	commodity := cas.commodityDomainSvc.GetCommodityInfo(commodityId)
+	if commodity == nil {
+		return nil, errcode.ErrCommodityNotExists
+	}
+	if size <= 0 {
+		size = enum.DefaultRelatedSize
+	}
+	relatedList, err := domainservice.NewRecommendDomainSvc(cas.ctx).GetRelatedCommodities(commodity, size)
+	if err != nil {
+		return nil, err
+	}
+	replyList := make([]*reply.CommodityListElem, 0, len(relatedList))
+	if err = util.CopyProperties(&replyList, &relatedList); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyList, nil
+}
+
+// RefreshRelatedCommodities 根据订单重新计算每个商品的相关商品, 返回有相关商品的商品数量
+func (cas *CommodityAppSvc) RefreshRelatedCommodities() (int, error) {
+	return domainservice.NewRecommendDomainSvc(cas.ctx).RefreshRelatedCommodities()
+}
@@ -0,0 +1,133 @@
+package appservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+)
+
+func (cas *CommodityAppSvc) AdminCreateCommodity(request *request.AdminCommodityCreate) (*reply.AdminCommodityCreated, error) {
+	creation := &do.CommodityCreation{
+		Commodity:     new(do.Commodity),
+		Specs:         make([]*do.CommoditySpec, 0, len(request.Specs)),
+		Skus:          make([]*do.CommoditySku, 0, len(request.Skus)),
+		SkuSpecValues: make([][]string, 0, len(request.Skus)),
+	}
+	if err := util.CopyProperties(creation.Commodity, request); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	if creation.Commodity.SellStatus == 0 {
+		creation.Commodity.SellStatus = enum.CommoditySellStatusOn
+	}
+	for _, spec := range request.Specs {
+		creation.Specs = append(creation.Specs, &do.CommoditySpec{
+			Name: spec.Name,
+			Values: lo.Map(spec.Values, func(value string, _ int) *do.CommoditySpecValue {
+				return &do.CommoditySpecValue{Value: value}
+			}),
+		})
+	}
+	for _, skuRequest := range request.Skus {
+		sku := new(do.CommoditySku)
+		if err := util.CopyProperties(sku, skuRequest); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		if sku.SellStatus == 0 {
+			sku.SellStatus = enum.CommoditySellStatusOn
+		}
+		if sku.CoverImg == "" {
+			sku.CoverImg = request.CoverImg
+		}
+		creation.Skus = append(creation.Skus, sku)
+		creation.SkuSpecValues = append(creation.SkuSpecValues, skuRequest.SpecValues)
+	}
+
+	commodityId, err := cas.commodityDomainSvc.CreateCommodity(creation)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.AdminCommodityCreated{
+		CommodityId: commodityId,
+		Skus: lo.Map(creation.Skus, func(sku *do.CommoditySku, _ int) *reply.AdminCreatedSku {
+			return &reply.AdminCreatedSku{SkuId: sku.ID, SpecDesc: sku.SpecDesc}
+		}),
+	}, nil
+}
+
+func (cas *CommodityAppSvc) AdminUpdateCommodity(commodityId int64, request *request.AdminCommodityUpdate) error {
+	commodity := new(do.Commodity)
+	if err := util.CopyProperties(commodity, request); err != nil {
+		return errcode.ErrCoverData.WithCause(err)
+	}
+	commodity.ID = commodityId
+	skuUpdates := make([]*do.CommoditySkuUpdate, 0, len(request.Skus))
+	if err := util.CopyProperties(&skuUpdates, &request.Skus); err != nil {
+		return errcode.ErrCoverData.WithCause(err)
+	}
+	return cas.commodityDomainSvc.UpdateCommodity(commodity, skuUpdates)
+}
+
+func (cas *CommodityAppSvc) AdminSetCommoditySellStatus(commodityId int64, sellStatus int) error {
+	return cas.commodityDomainSvc.SetCommoditySellStatus(commodityId, sellStatus)
+}
+
+func (cas *CommodityAppSvc) AdminDeleteCommodity(commodityId int64) error {
+	return cas.commodityDomainSvc.DeleteCommodity(commodityId)
+}
+
+func (cas *CommodityAppSvc) AdminRestoreCommodity(commodityId int64) error {
+	return cas.commodityDomainSvc.RestoreCommodity(commodityId)
+}
+
+func (cas *CommodityAppSvc) AdminListCommodities(request *request.AdminCommodityList, pagination *app.Pagination) ([]*reply.AdminCommodity, error) {
+	query := new(do.AdminCommodityQuery)
+	if err := util.CopyProperties(query, request); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	commodityList, err := cas.commodityDomainSvc.AdminListCommodities(query, pagination)
+	if err != nil {
+		return nil, err
+	}
+	replyCommodityList := make([]*reply.AdminCommodity, 0, len(commodityList))
+	if err = util.CopyProperties(&replyCommodityList, &commodityList); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyCommodityList, nil
+}
+
+func (cas *CommodityAppSvc) AdminCreateCategory(request *request.AdminCategory) (*reply.AdminCategoryCreated, error) {
+	category := new(do.CommodityCategory)
+	if err := util.CopyProperties(category, request); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	categoryId, err := cas.commodityDomainSvc.CreateCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.AdminCategoryCreated{CategoryId: categoryId}, nil
+}
+
+func (cas *CommodityAppSvc) AdminUpdateCategory(categoryId int64, request *request.AdminCategory) error {
+	category := new(do.CommodityCategory)
+	if err := util.CopyProperties(category, request); err != nil {
+		return errcode.ErrCoverData.WithCause(err)
+	}
+	category.ID = categoryId
+	return cas.commodityDomainSvc.UpdateCategory(category)
+}
+
+func (cas *CommodityAppSvc) AdminRerankCategories(rerankRequest *request.AdminCategoryRerank) error {
+	ranks := lo.SliceToMap(rerankRequest.Ranks, func(rank *request.AdminCategoryRank) (int64, int) {
+		return rank.CategoryId, rank.Rank
+	})
+	return cas.commodityDomainSvc.RerankCategories(ranks)
+}
+
+func (cas *CommodityAppSvc) AdminDeleteCategory(categoryId int64) error {
+	return cas.commodityDomainSvc.DeleteCategory(categoryId)
+}
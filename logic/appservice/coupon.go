@@ -0,0 +1,96 @@
+package appservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"time"
+)
+
+type CouponAppSvc struct {
+	ctx             context.Context
+	couponDomainSvc *domainservice.CouponDomainSvc
+}
+
+func NewCouponAppSvc(ctx context.Context) *CouponAppSvc {
+	return &CouponAppSvc{
+		ctx:             ctx,
+		couponDomainSvc: domainservice.NewCouponDomainSvc(ctx),
+	}
+}
+
+func (cas *CouponAppSvc) AdminCreateTemplate(request *request.CouponTemplateCreate) (*reply.CouponTemplate, error) {
+	template := new(do.CouponTemplate)
+	if err := util.CopyProperties(template, request); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	// 请求中的时间按服务器所在时区解析
+	template.ValidStartAt, _ = time.ParseInLocation(enum.TimeFormatHyphenedYMDHIS, request.ValidStartAt, time.Local)
+	template.ValidEndAt, _ = time.ParseInLocation(enum.TimeFormatHyphenedYMDHIS, request.ValidEndAt, time.Local)
+	if err := cas.couponDomainSvc.CreateTemplate(template); err != nil {
+		return nil, err
+	}
+	return newCouponTemplateReply(template)
+}
+
+func (cas *CouponAppSvc) AdminSetTemplateStatus(templateId int64, request *request.CouponTemplateStatus) error {
+	return cas.couponDomainSvc.SetTemplateStatus(templateId, request.Status)
+}
+
+func (cas *CouponAppSvc) AdminIssueCoupons(templateId int64, request *request.CouponIssue) (*reply.CouponIssued, error) {
+	issued, err := cas.couponDomainSvc.IssueCoupons(templateId, request.UserIds)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.CouponIssued{IssuedNum: issued}, nil
+}
+
+func (cas *CouponAppSvc) GetClaimableTemplates() ([]*reply.CouponTemplate, error) {
+	templates, err := cas.couponDomainSvc.GetClaimableTemplates()
+	if err != nil {
+		return nil, err
+	}
+	replyTemplates := make([]*reply.CouponTemplate, 0, len(templates))
+	if err = util.CopyProperties(&replyTemplates, &templates); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyTemplates, nil
+}
+
+func (cas *CouponAppSvc) ClaimCoupon(userId, templateId int64) (*reply.UserCoupon, error) {
+	coupon, err := cas.couponDomainSvc.ClaimCoupon(userId, templateId)
+	if err != nil {
+		return nil, err
+	}
+	replyCoupon := new(reply.UserCoupon)
+	if err = util.CopyProperties(replyCoupon, coupon); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyCoupon, nil
+}
+
+func (cas *CouponAppSvc) GetUserCoupons(userId int64, request *request.UserCouponList, pagination *app.Pagination) ([]*reply.UserCoupon, error) {
+	coupons, err := cas.couponDomainSvc.GetUserCoupons(userId, request.Status, pagination)
+	if err != nil {
+		return nil, err
+	}
+	replyCoupons := make([]*reply.UserCoupon, 0, len(coupons))
+	if err = util.CopyProperties(&replyCoupons, &coupons); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyCoupons, nil
+}
+
+func newCouponTemplateReply(template *do.CouponTemplate) (*reply.CouponTemplate, error) {
+	replyTemplate := new(reply.CouponTemplate)
+	if err := util.CopyProperties(replyTemplate, template); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyTemplate, nil
+}
@@ -0,0 +1,60 @@
+package appservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"time"
+)
+
+type DiscountAppSvc struct {
+	ctx               context.Context
+	discountDomainSvc *domainservice.DiscountDomainSvc
+}
+
+func NewDiscountAppSvc(ctx context.Context) *DiscountAppSvc {
+	return &DiscountAppSvc{
+		ctx:               ctx,
+		discountDomainSvc: domainservice.NewDiscountDomainSvc(ctx),
+	}
+}
+
+func (das *DiscountAppSvc) AdminCreateCampaign(request *request.DiscountCampaignCreate) (*reply.DiscountCampaign, error) {
+	campaign := new(do.DiscountCampaign)
+	if err := util.CopyProperties(campaign, request); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	// 请求中的时间按服务器所在时区解析
+	campaign.StartTime, _ = time.ParseInLocation(enum.TimeFormatHyphenedYMDHIS, request.StartTime, time.Local)
+	campaign.EndTime, _ = time.ParseInLocation(enum.TimeFormatHyphenedYMDHIS, request.EndTime, time.Local)
+	if err := das.discountDomainSvc.CreateCampaign(campaign); err != nil {
+		return nil, err
+	}
+	replyCampaign := new(reply.DiscountCampaign)
+	if err := util.CopyProperties(replyCampaign, campaign); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyCampaign, nil
+}
+
+func (das *DiscountAppSvc) AdminSetCampaignStatus(campaignId int64, request *request.DiscountCampaignStatus) error {
+	return das.discountDomainSvc.SetCampaignStatus(campaignId, request.Status)
+}
+
+func (das *DiscountAppSvc) AdminGetCampaigns(pagination *app.Pagination) ([]*reply.DiscountCampaign, error) {
+	campaigns, err := das.discountDomainSvc.GetCampaigns(pagination)
+	if err != nil {
+		return nil, err
+	}
+	replyCampaigns := make([]*reply.DiscountCampaign, 0, len(campaigns))
+	if err = util.CopyProperties(&replyCampaigns, &campaigns); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyCampaigns, nil
+}
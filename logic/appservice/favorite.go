@@ -0,0 +1,83 @@
+package appservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+)
+
+type FavoriteAppSvc struct {
+	ctx               context.Context
+	favoriteDomainSvc *domainservice.FavoriteDomainSvc
+}
+
+func NewFavoriteAppSvc(ctx context.Context) *FavoriteAppSvc {
+	return &FavoriteAppSvc{
+		ctx:               ctx,
+		favoriteDomainSvc: domainservice.NewFavoriteDomainSvc(ctx),
+	}
+}
+
+func (fas *FavoriteAppSvc) AddFavorite(userId, commodityId int64) error {
+	return fas.favoriteDomainSvc.AddFavorite(userId, commodityId)
+}
+
+func (fas *FavoriteAppSvc) RemoveFavorite(userId, commodityId int64) error {
+	return fas.favoriteDomainSvc.RemoveFavorite(userId, commodityId)
+}
+
+func (fas *FavoriteAppSvc) GetUserFavorites(userId int64, pagination *app.Pagination) ([]*reply.FavoriteItem, error) {
+	favorites, err := fas.favoriteDomainSvc.GetUserFavorites(userId, pagination)
+	if err != nil {
+		return nil, err
+	}
+	replyFavorites := make([]*reply.FavoriteItem, 0, len(favorites))
+	for _, favorite := range favorites {
+		commodity := favorite.Commodity
+		status := enum.CartItemStatusValid
+		if commodity.IsDel != 0 {
+			status = enum.CartItemStatusDeleted
+		} else if commodity.SellStatus != enum.CommoditySellStatusOn {
+			status = enum.CartItemStatusOffShelf
+		}
+		replyFavorites = append(replyFavorites, &reply.FavoriteItem{
+			CommodityId:    favorite.CommodityId,
+			CommodityName:  commodity.Name,
+			CoverImg:       commodity.CoverImg,
+			SellingPrice:   commodity.SellingPrice,
+			FavoritedPrice: favorite.FavoritedPrice,
+			PriceDropped:   commodity.SellingPrice < favorite.FavoritedPrice,
+			StockNum:       commodity.StockNum,
+			Status:         status,
+			FavoritedAt:    favorite.CreatedAt.Format(enum.TimeFormatHyphenedYMDHIS),
+		})
+	}
+	return replyFavorites, nil
+}
+
+// MoveToCart 把收藏商品的一个SKU加入购物车, 加入成功后取消收藏
+func (fas *FavoriteAppSvc) MoveToCart(userId, commodityId int64, moveRequest *request.FavoriteMoveToCart) error {
+	if err := fas.favoriteDomainSvc.CheckFavorite(userId, commodityId); err != nil {
+		return err
+	}
+	cartItem, sellInfo, err := newSellableCartItem(fas.ctx, moveRequest.SkuId, moveRequest.CommodityNum, userId)
+	if err != nil {
+		return err
+	}
+	if sellInfo.Sku.CommodityId != commodityId {
+		return errcode.ErrCommoditySkuInvalid
+	}
+	if err = domainservice.NewCartDomainSvc(fas.ctx).CartAddItem(cartItem); err != nil {
+		return err
+	}
+	if err = fas.favoriteDomainSvc.RemoveFavorite(userId, commodityId); err != nil {
+		// 商品已经加入购物车, 收藏没能取消时只记录日志
+		logger.New(fas.ctx).Error("MoveToCartRemoveFavoriteError", "userId", userId, "commodityId", commodityId, "err", err)
+	}
+	return nil
+}
@@ -7,8 +7,13 @@ import (
 	"github.com/Ian-zy0329/go-mall/common/app"
 	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/library"
+	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"github.com/samber/lo"
 )
 
 type OrderAppSvc struct {
@@ -34,10 +39,15 @@ func (oas *OrderAppSvc) CreateOrder(request *request.OrderCreate, userId int64)
 	if err != nil {
 		return nil, err
 	}
-	order, err := oas.orderDomainSvc.CreateOrder(cartItems, userAddressInfo)
+	order, err := oas.orderDomainSvc.CreateOrder(cartItems, userAddressInfo, request.UserCouponId, request.UsePoints)
 	if err != nil {
 		return nil, err
 	}
+	// 订单已经创建, 删除购物项失败只记录日志, 不影响下单结果
+	orderedItemIds := lo.Map(cartItems, func(item *do.ShoppingCartItem, _ int) int64 { return item.CartItemId })
+	if err = cartDomainSvc.RemoveOrderedCartItems(userId, orderedItemIds); err != nil {
+		logger.New(oas.ctx).Error("CreateOrderRemoveCartItemsError", "orderNo", order.OrderNo, "err", err)
+	}
 	orderReply := new(reply.OrderCreateReply)
 	orderReply.OrderNo = order.OrderNo
 	return orderReply, nil
@@ -83,6 +93,15 @@ func (oas *OrderAppSvc) CancelOrder(orderNo string, userId int64) error {
 	return oas.orderDomainSvc.CancelUserOrder(orderNo, userId)
 }
 
+func (oas *OrderAppSvc) ConfirmReceipt(orderNo string, userId int64) error {
+	return oas.orderDomainSvc.ConfirmReceipt(orderNo, userId)
+}
+
+// MerchantCloseOrder 后台关闭订单
+func (oas *OrderAppSvc) MerchantCloseOrder(orderNo string) error {
+	return oas.orderDomainSvc.MerchantCloseOrder(orderNo)
+}
+
 func (oas *OrderAppSvc) OrderCreatePay(payRequest *request.OrderPayCreate, userId int64) (replyData interface{}, err error) {
 	switch payRequest.PayType {
 	case enum.PayTypeWxPay:
@@ -93,3 +112,31 @@ func (oas *OrderAppSvc) OrderCreatePay(payRequest *request.OrderPayCreate, userI
 	}
 	return
 }
+
+// HandleWxPayNotify 处理微信支付的支付结果通知, 支付成功时结算订单
+func (oas *OrderAppSvc) HandleWxPayNotify(timestamp, nonce, signature, rawPost string) error {
+	wpl := library.NewWxPayLib(oas.ctx, library.WxPayConfig{
+		AppId:           config.App.WechatPay.AppId,
+		MchId:           config.App.WechatPay.MchId,
+		NotifyUrl:       config.App.WechatPay.NotifyUrl,
+		PrivateSerialNo: config.App.WechatPay.PrivateSerialNo,
+		AesKey:          config.App.WechatPay.AesKey,
+	})
+	verified, err := wpl.ValidateNotifySingature(timestamp, nonce, signature, rawPost)
+	if err != nil || !verified {
+		return errcode.ErrParams.WithCause(err)
+	}
+	notifyData, err := wpl.DecryptNotifyResourceData(rawPost)
+	if err != nil {
+		return errcode.Wrap("HandleWxPayNotifyError", err)
+	}
+	if notifyData.TradeState != "SUCCESS" {
+		return nil
+	}
+	return oas.orderDomainSvc.SettleOrderPaid(notifyData.OutTradeNo, notifyData.TransactionID, notifyData.SuccessTime)
+}
+
+// ReleaseExpiredStockReservations 释放超时未支付订单的库存预占, 由定时任务调用
+func (oas *OrderAppSvc) ReleaseExpiredStockReservations() error {
+	return oas.orderDomainSvc.ReleaseExpiredStockReservations()
+}
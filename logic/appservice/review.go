@@ -0,0 +1,81 @@
+package appservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+)
+
+type ReviewAppSvc struct {
+	ctx             context.Context
+	reviewDomainSvc *domainservice.ReviewDomainSvc
+}
+
+func NewReviewAppSvc(ctx context.Context) *ReviewAppSvc {
+	return &ReviewAppSvc{
+		ctx:             ctx,
+		reviewDomainSvc: domainservice.NewReviewDomainSvc(ctx),
+	}
+}
+
+func (ras *ReviewAppSvc) CreateReview(reviewRequest *request.ReviewCreate, userId int64) (*reply.ReviewCreated, error) {
+	review := &do.CommodityReview{
+		UserId:  userId,
+		SkuId:   reviewRequest.SkuId,
+		Rating:  reviewRequest.Rating,
+		Content: reviewRequest.Content,
+		Images:  reviewRequest.Images,
+	}
+	reviewId, err := ras.reviewDomainSvc.CreateReview(reviewRequest.OrderNo, review)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.ReviewCreated{ReviewId: reviewId}, nil
+}
+
+// CommodityReviews 商品的评价列表和星级汇总, 汇总不受星级筛选影响
+func (ras *ReviewAppSvc) CommodityReviews(commodityId int64, listRequest *request.CommodityReviewList, pagination *app.Pagination) (*reply.CommodityReviewList, error) {
+	reviews, err := ras.reviewDomainSvc.GetCommodityReviews(commodityId, listRequest.Rating, pagination)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := ras.reviewDomainSvc.GetCommodityRatingSummary(commodityId)
+	if err != nil {
+		return nil, err
+	}
+
+	replyData := &reply.CommodityReviewList{
+		Summary: &reply.CommodityRatingSummary{
+			ReviewCount:   summary.ReviewCount,
+			AverageRating: summary.AverageRating,
+			GoodRate:      summary.GoodRate,
+			RatingCounts:  make([]*reply.CommodityRatingCount, 0, enum.ReviewRatingMax),
+		},
+		List: make([]*reply.CommodityReview, 0, len(reviews)),
+	}
+	for rating := enum.ReviewRatingMax; rating >= enum.ReviewRatingMin; rating-- {
+		replyData.Summary.RatingCounts = append(replyData.Summary.RatingCounts, &reply.CommodityRatingCount{
+			Rating: rating,
+			Count:  summary.RatingCounts[rating],
+		})
+	}
+	if err = util.CopyProperties(&replyData.List, &reviews); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	for _, replyReview := range replyData.List {
+		if replyReview.MerchantReply == "" {
+			replyReview.RepliedAt = ""
+		}
+	}
+	return replyData, nil
+}
+
+func (ras *ReviewAppSvc) AdminReplyReview(reviewId int64, content string) error {
+	return ras.reviewDomainSvc.ReplyReview(reviewId, content)
+}
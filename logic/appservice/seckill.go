@@ -0,0 +1,95 @@
+package appservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/api/reply"
+	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"time"
+)
+
+type SeckillAppSvc struct {
+	ctx              context.Context
+	seckillDomainSvc *domainservice.SeckillDomainSvc
+}
+
+func NewSeckillAppSvc(ctx context.Context) *SeckillAppSvc {
+	return &SeckillAppSvc{
+		ctx:              ctx,
+		seckillDomainSvc: domainservice.NewSeckillDomainSvc(ctx),
+	}
+}
+
+// GetActiveCampaigns 进行中和一天内即将开始的秒杀活动
+func (sas *SeckillAppSvc) GetActiveCampaigns() ([]*reply.SeckillCampaign, error) {
+	campaigns, err := sas.seckillDomainSvc.GetActiveCampaigns(24 * time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	replyCampaigns := make([]*reply.SeckillCampaign, 0, len(campaigns))
+	if err = util.CopyProperties(&replyCampaigns, &campaigns); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	for i, campaign := range campaigns {
+		quota, err := sas.seckillDomainSvc.GetCampaignQuota(campaign)
+		if err != nil {
+			return nil, err
+		}
+		replyCampaigns[i].Remaining = quota.Remaining
+	}
+	return replyCampaigns, nil
+}
+
+func (sas *SeckillAppSvc) GetCampaignInfo(campaignId int64) (*reply.SeckillCampaign, error) {
+	campaign, err := sas.seckillDomainSvc.GetCampaign(campaignId)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := sas.seckillDomainSvc.GetCampaignQuota(campaign)
+	if err != nil {
+		return nil, err
+	}
+	replyCampaign := new(reply.SeckillCampaign)
+	if err = util.CopyProperties(replyCampaign, campaign); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	replyCampaign.Remaining = quota.Remaining
+	return replyCampaign, nil
+}
+
+func (sas *SeckillAppSvc) SubmitOrder(campaignId int64, request *request.SeckillOrderSubmit, userId int64) (*reply.SeckillSubmitReply, error) {
+	requestId, err := sas.seckillDomainSvc.SubmitRequest(campaignId, userId, request.UserAddressId, request.Num)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.SeckillSubmitReply{RequestId: requestId}, nil
+}
+
+func (sas *SeckillAppSvc) GetResult(requestId string, userId int64) (*reply.SeckillResult, error) {
+	result, err := sas.seckillDomainSvc.GetUserResult(requestId, userId)
+	if err != nil {
+		return nil, err
+	}
+	replyResult := new(reply.SeckillResult)
+	if err = util.CopyProperties(replyResult, result); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return replyResult, nil
+}
+
+func (sas *SeckillAppSvc) PreloadCampaigns() error {
+	return sas.seckillDomainSvc.PreloadCampaigns()
+}
+
+// ConsumeRequest 处理一个排队中的秒杀请求
+func (sas *SeckillAppSvc) ConsumeRequest() error {
+	return sas.seckillDomainSvc.ConsumeRequest(enum.SeckillQueuePopTimeout)
+}
+
+// RecoverRequests 把处理中断的秒杀请求放回队列
+func (sas *SeckillAppSvc) RecoverRequests() error {
+	return sas.seckillDomainSvc.RecoverRequests()
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"github.com/Ian-zy0329/go-mall/api/reply"
 	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
 	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/common/util"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	"github.com/samber/lo"
 )
 
 type UserAppSvc struct {
@@ -60,7 +63,8 @@ func (us *UserAppSvc) UserRegister(userRegisterReq *request.UserRegister) error
 }
 
 func (us *UserAppSvc) UserLogin(userLoginReq *request.UserLogin) (*reply.TokenReply, error) {
-	token, err := us.userDomainSvc.LoginUser(userLoginReq.Body.LoginName, userLoginReq.Body.Password, userLoginReq.Header.Platform)
+	token, err := us.userDomainSvc.LoginUser(userLoginReq.Body.LoginName, userLoginReq.Body.Password,
+		userLoginReq.Header.Platform, userLoginReq.Header.CartToken)
 	if err != nil {
 		return nil, err
 	}
@@ -172,3 +176,64 @@ func (us *UserAppSvc) ModifyUserAddress(request *request.UserAddress, userId int
 func (us *UserAppSvc) DeleteUserAddress(userId, addressId int64) error {
 	return us.userDomainSvc.DeleteUserAddress(userId, addressId)
 }
+
+func (us *UserAppSvc) GetUserVip(userId int64) (*reply.UserVip, error) {
+	membership, err := domainservice.NewVipDomainSvc(us.ctx).GetUserMembership(userId)
+	if err != nil {
+		return nil, err
+	}
+	vipReply := &reply.UserVip{
+		Level:        membership.Level,
+		Growth:       membership.Growth,
+		WindowGrowth: membership.WindowGrowth,
+	}
+	if membership.LevelInfo != nil {
+		vipReply.LevelName = membership.LevelInfo.Name
+		vipReply.DiscountRate = membership.LevelInfo.DiscountRate
+		vipReply.LevelExpireAt = membership.LevelExpireAt.Format(enum.TimeFormatHyphenedYMDHIS)
+	}
+	if membership.NextLevel != nil {
+		vipReply.NextLevel = membership.NextLevel.Level
+		vipReply.NextLevelName = membership.NextLevel.Name
+		vipReply.NextLevelGrowth = membership.NextLevel.MinGrowth
+	}
+	return vipReply, nil
+}
+
+func (us *UserAppSvc) GetUserPoints(userId int64, pagination *app.Pagination) (*reply.UserPoints, error) {
+	pointsDomainSvc := domainservice.NewPointsDomainSvc(us.ctx)
+	balance, err := pointsDomainSvc.GetUserBalance(userId)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pointsDomainSvc.GetUserEntries(userId, pagination)
+	if err != nil {
+		return nil, err
+	}
+	pointsReply := &reply.UserPoints{Balance: balance}
+	pointsReply.Entries = lo.Map(entries, func(entry *do.PointsEntry, _ int) *reply.PointsEntry {
+		replyEntry := &reply.PointsEntry{
+			ID:        entry.ID,
+			Type:      entry.Type,
+			Points:    entry.Points,
+			OrderId:   entry.OrderId,
+			Remark:    entry.Remark,
+			CreatedAt: entry.CreatedAt.Format(enum.TimeFormatHyphenedYMDHIS),
+		}
+		if entry.Points > 0 {
+			replyEntry.ExpireAt = entry.ExpireAt.Format(enum.TimeFormatHyphenedYMDHIS)
+		}
+		return replyEntry
+	})
+	return pointsReply, nil
+}
+
+// ExpirePoints 处理已过期的积分, 由定时任务执行
+func (us *UserAppSvc) ExpirePoints() (int, error) {
+	return domainservice.NewPointsDomainSvc(us.ctx).ExpirePoints()
+}
+
+// RefreshVipLevels 按成长值重新计算会员等级, 由定时任务执行
+func (us *UserAppSvc) RefreshVipLevels() (int, error) {
+	return domainservice.NewVipDomainSvc(us.ctx).RefreshLevels()
+}
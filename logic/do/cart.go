@@ -1,19 +1,34 @@
 package do
 
-import "time"
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"time"
+)
 
 type ShoppingCartItem struct {
 	CartItemId            int64
 	UserId                int64
 	CommodityId           int64
+	CategoryId            int64 // 商品所在的三级分类
+	SkuId                 int64
 	CommodityName         string
+	SkuSpecDesc           string
 	CommodityImg          string
 	CommoditySellingPrice int
+	AddedPrice            int // 加购时的售价, 0 表示加购时未记录
+	AvailableStock        int // 当前可售库存
 	CommodityNum          int
+	Selected              bool   // 是否勾选结算
+	Status                string // 购物项状态, 取值见 enum.CartItemStatus*
 	CreatedAt             time.Time
 	UpdatedAt             time.Time
 }
 
+// IsValid 购物项是否可以结算
+func (item *ShoppingCartItem) IsValid() bool {
+	return item.Status == enum.CartItemStatusValid || item.Status == enum.CartItemStatusPriceChanged
+}
+
 type CartBillInfo struct {
 	Coupon struct {
 		CouponId      int64
@@ -27,7 +42,25 @@ type CartBillInfo struct {
 		DiscountMoney int
 		Threshold     int
 	}
+	Points struct {
+		Points      int // 使用的积分, 下单时扣减
+		DeductMoney int
+	}
+	UsableCoupons      []*CouponDiscount     // 可以用于结算的优惠券, 按优惠金额从高到低排序
+	DiscountCampaigns  []*DiscountEvaluation // 当前生效的每个满减活动的计算结果
 	VipDiscountMoney   int
 	OriginalTotalPrice int
+	Reductions         []*BillReduction // 按计算顺序排列的每项优惠, 说明支付金额是怎样计算出来的
 	TotalPrice         int
 }
+
+// BillReduction 结算明细中的一项优惠
+type BillReduction struct {
+	Checker     string // 计算优惠的插件, 取值见 enum.BillChecker*
+	Name        string // 向用户展示的优惠说明
+	RefId       int64  // 使用的用户优惠券ID或满减活动ID, 会员折扣为会员等级, 积分抵扣为使用的积分
+	Amount      int    // 插件计算出的优惠金额
+	DeductMoney int    // 按互斥规则和最低支付金额实际抵扣的金额
+	Result      string // 取值见 enum.BillReduction*
+	ExcludedBy  string // 因互斥没有使用时, 同组中被使用的优惠插件
+}
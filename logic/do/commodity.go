@@ -1,6 +1,10 @@
 package do
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 type CommodityCategory struct {
 	ID        int64     `json:"id"`
@@ -38,14 +42,103 @@ type Commodity struct {
 	StockNum      int       `json:"stock_num"`
 	Tag           string    `json:"tag"`
 	SellStatus    int       `json:"sell_status"`
+	SalesNum      int       `json:"sales_num"`
+	PurchaseLimit int       `json:"purchase_limit"` // 每个用户的限购数量, 计入历史订单, 0-不单独限购
+	IsDel         uint      `json:"is_del"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// CommodityListQuery 商品列表的查询条件, 分类列表和搜索共用
+type CommodityListQuery struct {
+	CategoryIds       []int64  // 列表所在分类下的三级分类, 为空时不限分类
+	FilterCategoryIds []int64  // 用户筛选的三级分类, 统计分类分面时不计入
+	Keyword           string   // 搜索关键词, 为空时不限关键词
+	FullTextSearch    bool     // 关键词使用全文索引检索, 为 false 时按商品名模糊匹配
+	MinPrice          int      // 最低售价(分), 0表示不限
+	MaxPrice          int      // 最高售价(分), 0表示不限
+	Tags              []string // 商品标签需要包含的所有词
+	InStockOnly       bool     // 只看有货的商品
+	Sort              string   // 排序方式, 取值见 enum.CommoditySort*
+}
+
+// CommodityFacets 商品列表的分面统计, 每个分面统计时不计入该分面自身的筛选条件
+type CommodityFacets struct {
+	Categories  []*CommodityCategoryFacet `json:"categories"`
+	PriceRanges []*CommodityPriceFacet    `json:"price_ranges"`
+}
+
+type CommodityCategoryFacet struct {
+	CategoryId   int64  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Count        int64  `json:"count"`
+}
+
+type CommodityPriceFacet struct {
+	MinPrice int   `json:"min_price"`
+	MaxPrice int   `json:"max_price"` // 0表示没有上限
+	Count    int64 `json:"count"`
+}
+
+// CommoditySpec 商品的规格项及其可选值
+type CommoditySpec struct {
+	ID          int64                 `json:"id"`
+	CommodityId int64                 `json:"commodity_id"`
+	Name        string                `json:"name"`
+	Rank        int                   `json:"rank"`
+	Values      []*CommoditySpecValue `json:"values"`
+}
+
+type CommoditySpecValue struct {
+	ID     int64  `json:"id"`
+	SpecId int64  `json:"spec_id"`
+	Value  string `json:"value"`
+	Rank   int    `json:"rank"`
+}
+
+// CommoditySku 商品的可售单元
+type CommoditySku struct {
+	ID            int64     `json:"id"`
+	CommodityId   int64     `json:"commodity_id"`
+	SpecValueIds  string    `json:"spec_value_ids"` // 按规格项排序的规格值ID, 逗号分隔
+	SpecDesc      string    `json:"spec_desc"`
+	CoverImg      string    `json:"cover_img"`
+	Images        string    `json:"images"`
+	OriginalPrice int       `json:"original_price"`
+	SellingPrice  int       `json:"selling_price"`
+	StockNum      int       `json:"stock_num"`
+	SellStatus    int       `json:"sell_status"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SpecValueIdList 把逗号分隔的规格值ID转换为列表
+func (sku *CommoditySku) SpecValueIdList() []int64 {
+	ids := make([]int64, 0)
+	for _, idStr := range strings.Split(sku.SpecValueIds, ",") {
+		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CommoditySkuMigration 把同名的多个旧商品合并为一个SPU的迁移方案
+// 旧商品ID直接作为SKU ID, 购物车、订单和Redis库存中已有的ID不需要改写
+type CommoditySkuMigration struct {
+	CommodityId   int64                   // 合并后保留的商品ID, 取同组中最小的旧商品ID
+	FoldedIds     []int64                 // 被合并为SKU后需要删除的其他旧商品ID
+	Specs         []*CommoditySpec        // 从商品简介中识别出的规格项
+	Skus          []*CommoditySku         // SKU ID 为对应的旧商品ID
+	SkuSpecValues [][]*CommoditySpecValue // 每个SKU的规格值, 与 Skus 按下标对应, 指向 Specs 中的值
+}
+
 // StockItem 库存数据结构
 type StockItem struct {
-	ItemID    int64     `json:"item_id"`   // 当前库存
+	ItemID    int64     `json:"item_id"`   // SKU ID, Redis库存以SKU为单位
 	Stock     int       `json:"stock"`     // 当前库存
+	Reserved  int       `json:"reserved"`  // 待支付订单预占的库存
+	Sold      int       `json:"sold"`      // 支付后确认售出的库存
 	Version   int64     `json:"version"`   // 版本号（乐观锁）
 	Modified  time.Time `json:"modified"`  // 最后修改时间
 	InitStock int       `json:"initStock"` // 初始库存
@@ -53,6 +146,7 @@ type StockItem struct {
 
 // DeductionLog 扣减日志
 type DeductionLog struct {
+	Type       string    `json:"type"` // reserve 预占 | confirm 确认售出 | release 释放预占 | repair 对账修复 | adjust 后台调整
 	OrderID    string    `json:"order_id"`
 	UserID     int64     `json:"user_id"`
 	ItemID     int64     `json:"item_id"`
@@ -62,3 +156,50 @@ type DeductionLog struct {
 	Timestamp  time.Time `json:"timestamp"`
 	IsRollback bool      `json:"is_rollback"` // 是否回滚操作
 }
+
+// StockDiff Redis与MySQL的库存对账结果
+// MySQL 的 stock_num 在支付确认时才扣减, 所以两边一致时: Redis可售 + Redis预占 = MySQL库存 - 待落库的确认数量
+type StockDiff struct {
+	ItemID           int64  `json:"item_id"`
+	RedisMissing     bool   `json:"redis_missing"` // Redis库存key已过期或被删除
+	RedisStock       int    `json:"redis_stock"`
+	RedisReserved    int    `json:"redis_reserved"`
+	PendingConfirmed int    `json:"pending_confirmed"` // 已在Redis确认售出, 订单尚未在MySQL标记为已支付的数量
+	MysqlStock       int    `json:"mysql_stock"`
+	Diff             int    `json:"diff"`     // Redis 比 MySQL 多出的库存
+	Repaired         string `json:"repaired"` // 已修复的一方 redis | mysql, 未修复时为空
+}
+
+// SkuSellInfo SKU及其所属商品的售卖信息, 已删除的商品和SKU也会查出, SKU不存在时 Sku 为 nil
+type SkuSellInfo struct {
+	Sku       *CommoditySku
+	Commodity *Commodity
+	SellState int // 取值见 enum.SkuSellState*
+}
+
+// CommodityCreation 后台新建商品的数据, 没有规格项时只能有一个默认SKU
+type CommodityCreation struct {
+	Commodity     *Commodity
+	Specs         []*CommoditySpec
+	Skus          []*CommoditySku
+	SkuSpecValues [][]string // 每个SKU按规格项顺序的规格值, 与 Skus 一一对应
+}
+
+// CommoditySkuUpdate 后台修改SKU的数据, 库存以增减量调整, 避免覆盖并发的下单扣减
+type CommoditySkuUpdate struct {
+	SkuId         int64
+	CoverImg      string
+	Images        string
+	OriginalPrice int
+	SellingPrice  int
+	StockDelta    int
+	SellStatus    int
+}
+
+// AdminCommodityQuery 后台商品列表的查询条件
+type AdminCommodityQuery struct {
+	Keyword    string // 按商品名模糊匹配
+	CategoryId int64  // 三级分类ID, 0表示不限
+	SellStatus int    // 上架状态, 0表示不限
+	Deleted    bool   // 只查已删除的商品
+}
@@ -0,0 +1,42 @@
+package do
+
+import "time"
+
+type CouponTemplate struct {
+	ID            int64
+	Name          string
+	DiscountType  int // 优惠方式, 取值见 enum.CouponDiscountType*
+	DiscountValue int
+	MaxDiscount   int
+	Threshold     int
+	ScopeType     int     // 使用范围, 取值见 enum.PromotionScope*
+	ScopeIds      []int64 // 使用范围内的三级分类或商品ID
+	TotalNum      int
+	IssuedNum     int
+	PerUserLimit  int
+	ValidStartAt  time.Time
+	ValidEndAt    time.Time
+	ValidDays     int
+	Status        int
+	CreatedAt     time.Time
+}
+
+type UserCoupon struct {
+	ID           int64
+	UserId       int64
+	TemplateId   int64
+	Status       int // 取值见 enum.UserCouponStatus*
+	OrderId      int64
+	ValidStartAt time.Time
+	ValidEndAt   time.Time
+	UsedAt       time.Time
+	CreatedAt    time.Time
+	Template     *CouponTemplate
+}
+
+// CouponDiscount 优惠券用于一组购物项时的优惠
+type CouponDiscount struct {
+	UserCoupon    *UserCoupon
+	ScopeAmount   int // 使用范围内商品的金额
+	DiscountMoney int // 优惠金额, 0 表示不满足使用门槛
+}
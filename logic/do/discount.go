@@ -0,0 +1,36 @@
+package do
+
+import "time"
+
+type DiscountCampaign struct {
+	ID        int64
+	Name      string
+	Type      int // 活动类型, 取值见 enum.DiscountType*
+	Tiers     []*DiscountTier
+	ScopeType int     // 适用范围, 取值见 enum.PromotionScope*
+	ScopeIds  []int64 // 适用范围内的三级分类或商品ID
+	Priority  int
+	StartTime time.Time
+	EndTime   time.Time
+	Status    int
+	CreatedAt time.Time
+}
+
+// DiscountTier 满减活动的一档门槛和优惠:
+//   - 阶梯满减: 门槛为商品金额(分), 优惠为减免金额(分)
+//   - 满件折扣: 门槛为商品件数, 优惠为减免的百分比
+//   - 组合一口价: 门槛为组合的件数, 优惠为组合的价格(分)
+type DiscountTier struct {
+	Threshold int `json:"threshold"`
+	Value     int `json:"value"`
+}
+
+// DiscountEvaluation 满减活动用于一组购物项的计算结果
+type DiscountEvaluation struct {
+	Campaign      *DiscountCampaign
+	ScopeAmount   int           // 适用范围内商品的金额
+	ScopeNum      int           // 适用范围内商品的件数
+	Tier          *DiscountTier // 满足的最高一档, 未满足门槛时为 nil
+	DiscountMoney int
+	Result        string // 取值见 enum.DiscountResult*
+}
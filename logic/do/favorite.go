@@ -0,0 +1,22 @@
+package do
+
+import "time"
+
+type UserFavorite struct {
+	ID             int64
+	UserId         int64
+	CommodityId    int64
+	FavoritedPrice int
+	Commodity      *Commodity // 商品的当前信息, 商品已删除时 IsDel 为1
+	CreatedAt      time.Time
+}
+
+// FavoritePriceDrop 收藏商品的降价事件
+type FavoritePriceDrop struct {
+	UserId        int64     `json:"user_id"`
+	CommodityId   int64     `json:"commodity_id"`
+	CommodityName string    `json:"commodity_name"`
+	OldPrice      int       `json:"old_price"`
+	NewPrice      int       `json:"new_price"`
+	DroppedAt     time.Time `json:"dropped_at"`
+}
@@ -36,7 +36,9 @@ type OrderAddress struct {
 type OrderItem struct {
 	OrderId               int64
 	CommodityId           int64
+	SkuId                 int64
 	CommodityName         string
+	SkuSpecDesc           string
 	CommodityImg          string
 	CommoditySellingPrice int
 	CommodityNum          int
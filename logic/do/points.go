@@ -0,0 +1,21 @@
+package do
+
+import "time"
+
+type PointsEntry struct {
+	ID        int64
+	UserId    int64
+	Type      int // 取值见 enum.PointsEntry*
+	Points    int // 增加为正数, 减少为负数
+	Remaining int // 增加的积分中还没有使用和过期的部分
+	ExpireAt  time.Time
+	OrderId   int64
+	Remark    string
+	CreatedAt time.Time
+}
+
+// PointsConsumption 从一条积分流水的剩余积分中扣减的积分
+type PointsConsumption struct {
+	EntryId int64
+	Points  int
+}
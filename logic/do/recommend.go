@@ -0,0 +1,7 @@
+package do
+
+// RelatedCommodity 与某个商品一起购买过的商品
+type RelatedCommodity struct {
+	CommodityId int64
+	OrderCount  int // 一起购买的订单数
+}
@@ -0,0 +1,29 @@
+package do
+
+import "time"
+
+type CommodityReview struct {
+	ID            int64
+	UserId        int64
+	OrderId       int64
+	OrderItemId   int64
+	CommodityId   int64
+	SkuId         int64
+	SkuSpecDesc   string
+	Rating        int
+	Content       string
+	Images        []string
+	MerchantReply string
+	RepliedAt     time.Time
+	UserNickname  string // 评价用户的昵称, 展示评价列表时填充
+	UserAvatar    string // 评价用户的头像, 展示评价列表时填充
+	CreatedAt     time.Time
+}
+
+// CommodityRatingSummary 商品评价的星级汇总
+type CommodityRatingSummary struct {
+	ReviewCount   int64
+	AverageRating float64       // 平均星级, 保留一位小数
+	GoodRate      float64       // 好评率, 0~1
+	RatingCounts  map[int]int64 // 各星级的评价数量
+}
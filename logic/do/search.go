@@ -0,0 +1,13 @@
+package do
+
+// SearchSuggestion 搜索补全的候选词
+type SearchSuggestion struct {
+	Text   string `json:"text"`
+	Source string `json:"source"` // 来源, 取值见 enum.SearchSuggestSource*
+}
+
+// SearchQueryStat 搜索词及其搜索次数
+type SearchQueryStat struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,52 @@
+package do
+
+import "time"
+
+type SeckillCampaign struct {
+	ID           int64
+	Name         string
+	CommodityId  int64
+	SkuId        int64
+	SeckillPrice int
+	Quota        int
+	UserLimit    int
+	StartTime    time.Time
+	EndTime      time.Time
+	Status       int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// SeckillQuota 活动在Redis中的名额数据
+type SeckillQuota struct {
+	CampaignId int64
+	Remaining  int // 剩余名额
+	Sold       int // 已被抢到的名额
+}
+
+// SeckillRequest 抢到名额后进入队列, 等待异步创建订单的秒杀请求
+type SeckillRequest struct {
+	RequestId     string    `json:"request_id"`
+	CampaignId    int64     `json:"campaign_id"`
+	UserId        int64     `json:"user_id"`
+	Num           int       `json:"num"`
+	UserAddressId int64     `json:"user_address_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ProcessingSeckillRequest 已从下单队列取出、还没写入处理结果的秒杀请求
+type ProcessingSeckillRequest struct {
+	Payload  string // 请求在处理中列表里的原始数据
+	Request  *SeckillRequest
+	PoppedAt time.Time // 从下单队列取出的时间
+}
+
+// SeckillResult 秒杀请求的处理结果
+type SeckillResult struct {
+	RequestId  string `json:"request_id"`
+	CampaignId int64  `json:"campaign_id"`
+	UserId     int64  `json:"user_id"`
+	Status     int    `json:"status"`
+	OrderNo    string `json:"order_no"`
+	Reason     string `json:"reason"`
+}
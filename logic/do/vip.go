@@ -0,0 +1,22 @@
+package do
+
+import "time"
+
+type VipLevel struct {
+	Level        int
+	Name         string
+	DiscountRate int // 会员折扣减免的百分比
+	MinGrowth    int // 达到该等级需要的成长值, 0-只能购买
+	SkuId        int64
+	Days         int
+}
+
+type UserMembership struct {
+	UserId        int64
+	Level         int // 当前有效的会员等级, 等级已到期时为 0
+	Growth        int // 累计获得的成长值
+	WindowGrowth  int // 计算会员等级的统计周期内获得的成长值
+	LevelExpireAt time.Time
+	LevelInfo     *VipLevel // 当前等级, 普通用户为 nil
+	NextLevel     *VipLevel // 按成长值可以升到的下一个等级, 已是最高等级时为 nil
+}
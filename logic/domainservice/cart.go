@@ -2,106 +2,191 @@ package domainservice
 
 import (
 	"context"
+	"fmt"
 	"github.com/Ian-zy0329/go-mall/api/request"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
 	"github.com/Ian-zy0329/go-mall/common/logger"
-	"github.com/Ian-zy0329/go-mall/common/util"
-	"github.com/Ian-zy0329/go-mall/dal/dao"
-	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/samber/lo"
 )
 
 type CartDomainSvc struct {
-	ctx     context.Context
-	cartDao *dao.CartDao
+	ctx      context.Context
+	cartRepo CartRepository
 }
 
 func NewCartDomainSvc(ctx context.Context) *CartDomainSvc {
 	return &CartDomainSvc{
-		ctx:     ctx,
-		cartDao: dao.NewCartDao(ctx),
+		ctx:      ctx,
+		cartRepo: NewCartRepository(ctx),
 	}
 }
 
+// CartAddItem 加购, 购物车中已有该SKU时累加数量, 加购后需要满足购物车容量和商品的限购数量
 func (cds *CartDomainSvc) CartAddItem(cartItem *do.ShoppingCartItem) error {
-	cartItemModel, err := cds.cartDao.GetUserCartItemWithCommodityId(cartItem.UserId, cartItem.CommodityId)
+	userCartItems, err := cds.cartRepo.GetUserCartItems(cartItem.UserId)
 	if err != nil {
 		return errcode.Wrap("CartAddItemError", err)
 	}
-	if cartItemModel != nil && cartItemModel.CartItemId != 0 {
-		cartItemModel.CommodityNum += cartItem.CommodityNum
-		return cds.cartDao.UpdateCartItem(cartItemModel)
+	existedItem, _ := lo.Find(userCartItems, func(item *do.ShoppingCartItem) bool {
+		return item.SkuId == cartItem.SkuId
+	})
+	if existedItem == nil && len(userCartItems) >= cartMaxItems() {
+		return errcode.ErrCartFull
+	}
+	commodityNum := cartItem.CommodityNum + lo.SumBy(userCartItems, func(item *do.ShoppingCartItem) int {
+		return lo.Ternary(item.CommodityId == cartItem.CommodityId, item.CommodityNum, 0)
+	})
+	if err = checkPurchaseLimits(cds.ctx, cartItem.UserId, map[int64]int{cartItem.CommodityId: commodityNum}); err != nil {
+		return err
+	}
+	if existedItem != nil {
+		existedItem.CommodityNum += cartItem.CommodityNum
+		err = cds.cartRepo.UpdateCartItem(existedItem)
+	} else {
+		cartItem.Selected = true
+		err = cds.cartRepo.AddCartItem(cartItem)
 	}
-	err = cds.cartDao.AddCartItem(cartItem)
 	if err != nil {
 		err = errcode.Wrap("CartAddItemError", err)
 	}
 	return err
 }
 
+// GetCheckedCartItems 获取要结算的购物项, 没有指定购物项时结算购物车中当前勾选的购物项
 func (cds *CartDomainSvc) GetCheckedCartItems(cartItemIds []int64, userId int64) ([]*do.ShoppingCartItem, error) {
-	cartItemModels, err := cds.cartDao.FindCartItems(cartItemIds)
+	var userCartItems []*do.ShoppingCartItem
+	var err error
+	if len(cartItemIds) == 0 {
+		userCartItems, err = cds.getSelectedCartItems(userId)
+	} else {
+		userCartItems, err = cds.findUserCartItems(userId, cartItemIds)
+	}
 	if err != nil {
-		err = errcode.Wrap("GetCheckedCartItemsError", err)
 		return nil, err
 	}
-	userCartItemModels := lo.Filter(cartItemModels, func(item *model.ShoppingCartItem, index int) bool {
-		return item.UserId == userId
-	})
-	if len(userCartItemModels) != len(cartItemIds) {
-		return nil, errcode.ErrCartWrongUser
+	if len(userCartItems) == 0 {
+		return nil, errcode.ErrCartItemParam
 	}
-	userCartItems := make([]*do.ShoppingCartItem, 0, len(userCartItemModels))
-	err = util.CopyProperties(&userCartItems, &cartItemModels)
+	if err = cds.fillInCommodityInfo(userCartItems); err != nil {
+		return nil, err
+	}
+	// 结算的购物项都必须可以售卖
+	for _, cartItem := range userCartItems {
+		switch cartItem.Status {
+		case enum.CartItemStatusDeleted:
+			return nil, errcode.ErrCommodityNotExists.WithCause(fmt.Errorf("购物项 %d 的商品已删除", cartItem.CartItemId))
+		case enum.CartItemStatusOffShelf:
+			return nil, errcode.ErrCommodityOffShelf.WithCause(fmt.Errorf("购物项 %d 的商品已下架", cartItem.CartItemId))
+		case enum.CartItemStatusInsufficientStock:
+			return nil, errcode.ErrCommodityStockOut.WithCause(fmt.Errorf("购物项 %d 的商品库存不足", cartItem.CartItemId))
+		}
+	}
+	return userCartItems, nil
+}
+
+// findUserCartItems 获取用户购物车中指定ID的购物项, 有购物项不属于该用户时返回 ErrCartWrongUser
+func (cds *CartDomainSvc) findUserCartItems(userId int64, cartItemIds []int64) ([]*do.ShoppingCartItem, error) {
+	cartItemIds = lo.Uniq(cartItemIds)
+	userCartItems, err := cds.cartRepo.FindUserCartItems(userId, cartItemIds)
 	if err != nil {
-		return nil, errcode.ErrCoverData.WithCause(err)
+		return nil, errcode.Wrap("FindUserCartItemsError", err)
+	}
+	if len(userCartItems) != len(cartItemIds) {
+		return nil, errcode.ErrCartWrongUser
 	}
-	cds.fillInCommodityInfo(userCartItems)
 	return userCartItems, nil
 }
 
+// getSelectedCartItems 获取用户购物车中勾选的购物项
+func (cds *CartDomainSvc) getSelectedCartItems(userId int64) ([]*do.ShoppingCartItem, error) {
+	cartItems, err := cds.cartRepo.GetUserCartItems(userId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSelectedCartItemsError", err)
+	}
+	return lo.Filter(cartItems, func(item *do.ShoppingCartItem, _ int) bool {
+		return item.Selected
+	}), nil
+}
+
+// fillInCommodityInfo 填充购物项的SKU和商品信息并标记状态, 价格和图片以SKU为准.
+// 已删除、已下架、库存不足、价格变动依次判断, 只标记状态, 不影响其他购物项
 func (cds *CartDomainSvc) fillInCommodityInfo(cartItems []*do.ShoppingCartItem) error {
-	// 获取购物项中的商品信息
-	commodityDao := dao.NewCommodityDao(cds.ctx)
-	commodityIdList := lo.Map(cartItems, func(item *do.ShoppingCartItem, index int) int64 {
-		return item.CommodityId
+	skuIdList := lo.Map(cartItems, func(item *do.ShoppingCartItem, index int) int64 {
+		return item.SkuId
 	})
-	commodities, err := commodityDao.FindCommodities(commodityIdList)
+	sellInfos, err := NewCommodityDomainSvc(cds.ctx).GetSkuSellInfos(skuIdList)
 	if err != nil {
 		return errcode.Wrap("CartItemFillInCommodityInfoError", err)
 	}
-	if len(commodities) != len(cartItems) {
-		logger.New(cds.ctx).Error("fillInCommodityError", "err", "商品信息不匹配", "commodityIdList", commodityIdList,
-			"fetchedCommodities", commodities)
-		return errcode.ErrCartItemParam
+	// 可售库存以Redis为准, Redis读取失败或还未初始化的SKU使用MySQL中的库存
+	stockNums, err := cache.GetStockNums(cds.ctx, skuIdList)
+	if err != nil {
+		logger.New(cds.ctx).Warn("CartItemGetStockNumsError", "skuIds", skuIdList, "err", err)
+		stockNums = map[int64]int{}
 	}
-	// 转换成以ID为Key的商品Map
-	commodityMap := lo.SliceToMap(commodities, func(item *model.Commodity) (int64, *model.Commodity) {
-		return item.ID, item
-	})
 	for _, cartItem := range cartItems {
-		cartItem.CommodityName = commodityMap[cartItem.CommodityId].Name
-		cartItem.CommodityImg = commodityMap[cartItem.CommodityId].CoverImg
-		cartItem.CommoditySellingPrice = commodityMap[cartItem.CommodityId].SellingPrice
+		sellInfo := sellInfos[cartItem.SkuId]
+		switch sellInfo.SellState {
+		case enum.SkuSellStateOnSale:
+			cartItem.Status = enum.CartItemStatusValid
+		case enum.SkuSellStateOffShelf:
+			cartItem.Status = enum.CartItemStatusOffShelf
+		default:
+			cartItem.Status = enum.CartItemStatusDeleted
+		}
+		sku, commodity := sellInfo.Sku, sellInfo.Commodity
+		if sku == nil || commodity == nil {
+			logger.New(cds.ctx).Warn("fillInCommodityWarn", "msg", "购物项的SKU或商品不存在", "cartItemId", cartItem.CartItemId, "skuId", cartItem.SkuId)
+			continue
+		}
+		cartItem.CommodityId = commodity.ID
+		cartItem.CategoryId = commodity.CategoryId
+		cartItem.CommodityName = commodity.Name
+		cartItem.CommodityImg = lo.Ternary(sku.CoverImg != "", sku.CoverImg, commodity.CoverImg)
+		cartItem.CommoditySellingPrice = sku.SellingPrice
+		cartItem.SkuSpecDesc = sku.SpecDesc
+		stockNum, ok := stockNums[cartItem.SkuId]
+		cartItem.AvailableStock = lo.Ternary(ok, stockNum, sku.StockNum)
+		if cartItem.Status != enum.CartItemStatusValid {
+			continue
+		}
+		if cartItem.AvailableStock < cartItem.CommodityNum {
+			cartItem.Status = enum.CartItemStatusInsufficientStock
+		} else if cartItem.AddedPrice > 0 && cartItem.AddedPrice != sku.SellingPrice {
+			cartItem.Status = enum.CartItemStatusPriceChanged
+		}
 	}
 
 	return nil
 }
 
 func (cds *CartDomainSvc) UpdateCartItem(request *request.CartItemUpdate, userId int64) error {
-	cartItemModel, err := cds.cartDao.GetCartItemById(request.CartItemId)
+	userCartItems, err := cds.cartRepo.GetUserCartItems(userId)
 	if err != nil {
 		err = errcode.Wrap("CartUpdateItemError", err)
 		return err
 	}
-	if cartItemModel == nil || cartItemModel.UserId != userId {
-		logger.New(cds.ctx).Error("DataMatchError", "cartItem", cartItemModel, "request", request, "requestUserId", userId)
+	cartItem, _ := lo.Find(userCartItems, func(item *do.ShoppingCartItem) bool {
+		return item.CartItemId == request.CartItemId
+	})
+	if cartItem == nil {
+		logger.New(cds.ctx).Error("DataMatchError", "request", request, "requestUserId", userId)
 		return errcode.ErrParams
 	}
+	commodityNum := request.CommodityNum + lo.SumBy(userCartItems, func(item *do.ShoppingCartItem) int {
+		return lo.Ternary(item.CommodityId == cartItem.CommodityId && item != cartItem, item.CommodityNum, 0)
+	})
+	if request.CommodityNum > cartItem.CommodityNum {
+		if err = checkPurchaseLimits(cds.ctx, userId, map[int64]int{cartItem.CommodityId: commodityNum}); err != nil {
+			return err
+		}
+	}
 
-	cartItemModel.CommodityNum = request.CommodityNum
-	err = cds.cartDao.UpdateCartItem(cartItemModel)
+	cartItem.CommodityNum = request.CommodityNum
+	err = cds.cartRepo.UpdateCartItem(cartItem)
 	if err != nil {
 		err = errcode.Wrap("CartUpdateItemError", err)
 	}
@@ -110,15 +195,13 @@ func (cds *CartDomainSvc) UpdateCartItem(request *request.CartItemUpdate, userId
 }
 
 func (cds *CartDomainSvc) GetUserCartItems(userId int64) (cartItems []*do.ShoppingCartItem, err error) {
-	cartItemModels, err := cds.cartDao.GetUserCartItems(userId)
+	cartItems, err = cds.cartRepo.GetUserCartItems(userId)
 	if err != nil {
 		err = errcode.Wrap("CartGetUserCartItemsError", err)
 		return
 	}
-	cartItems = make([]*do.ShoppingCartItem, len(cartItemModels))
-	util.CopyProperties(&cartItems, cartItemModels)
-	if err != nil {
-		return nil, errcode.ErrCoverData.WithCause(err)
+	if len(cartItems) == 0 {
+		return cartItems, nil
 	}
 	err = cds.fillInCommodityInfo(cartItems)
 	if err != nil {
@@ -128,14 +211,85 @@ func (cds *CartDomainSvc) GetUserCartItems(userId int64) (cartItems []*do.Shoppi
 }
 
 func (cds *CartDomainSvc) DeleteCartItem(userId, itemId int64) error {
-	cartItemModel, _ := cds.cartDao.GetCartItemById(itemId)
-	if cartItemModel == nil || cartItemModel.UserId != userId {
-		logger.New(cds.ctx).Error("DataMatchError", "cartItem", cartItemModel, "cartItemId", itemId, "userId", userId)
+	cartItem, err := cds.cartRepo.GetUserCartItem(userId, itemId)
+	if err != nil {
+		return errcode.Wrap("DeleteCartItemError", err)
+	}
+	if cartItem == nil {
+		logger.New(cds.ctx).Error("DataMatchError", "cartItemId", itemId, "userId", userId)
 		return errcode.ErrParams
 	}
-	err := cds.cartDao.DeleteCartItem(cartItemModel)
+	err = cds.cartRepo.DeleteUserCartItems(userId, []int64{itemId})
 	if err != nil {
 		err = errcode.Wrap("DeleteCartItemError", err)
 	}
 	return err
 }
+
+// RemoveOrderedCartItems 下单成功后从购物车中删除已下单的购物项
+func (cds *CartDomainSvc) RemoveOrderedCartItems(userId int64, cartItemIds []int64) error {
+	if err := cds.cartRepo.DeleteUserCartItems(userId, cartItemIds); err != nil {
+		return errcode.Wrap("RemoveOrderedCartItemsError", err)
+	}
+	return nil
+}
+
+// SelectCartItems 勾选或取消勾选用户购物车中的购物项
+func (cds *CartDomainSvc) SelectCartItems(userId int64, cartItemIds []int64, selected bool) error {
+	userCartItems, err := cds.findUserCartItems(userId, cartItemIds)
+	if err != nil {
+		return err
+	}
+	itemIds := lo.Map(userCartItems, func(item *do.ShoppingCartItem, _ int) int64 { return item.CartItemId })
+	if err = cds.cartRepo.SetUserCartItemsSelected(userId, itemIds, selected); err != nil {
+		return errcode.Wrap("SelectCartItemsError", err)
+	}
+	return nil
+}
+
+// SelectAllCartItems 勾选或取消勾选用户购物车中的所有购物项
+func (cds *CartDomainSvc) SelectAllCartItems(userId int64, selected bool) error {
+	cartItems, err := cds.cartRepo.GetUserCartItems(userId)
+	if err != nil {
+		return errcode.Wrap("SelectAllCartItemsError", err)
+	}
+	if len(cartItems) == 0 {
+		return nil
+	}
+	itemIds := lo.Map(cartItems, func(item *do.ShoppingCartItem, _ int) int64 { return item.CartItemId })
+	if err = cds.cartRepo.SetUserCartItemsSelected(userId, itemIds, selected); err != nil {
+		return errcode.Wrap("SelectAllCartItemsError", err)
+	}
+	return nil
+}
+
+// DeleteCartItems 批量删除用户购物车中的购物项
+func (cds *CartDomainSvc) DeleteCartItems(userId int64, cartItemIds []int64) error {
+	userCartItems, err := cds.findUserCartItems(userId, cartItemIds)
+	if err != nil {
+		return err
+	}
+	itemIds := lo.Map(userCartItems, func(item *do.ShoppingCartItem, _ int) int64 { return item.CartItemId })
+	if err = cds.cartRepo.DeleteUserCartItems(userId, itemIds); err != nil {
+		return errcode.Wrap("DeleteCartItemsError", err)
+	}
+	return nil
+}
+
+// ClearInvalidCartItems 删除用户购物车中库存不足、已下架、已删除的购物项, 返回删除的数量
+func (cds *CartDomainSvc) ClearInvalidCartItems(userId int64) (int, error) {
+	cartItems, err := cds.GetUserCartItems(userId)
+	if err != nil {
+		return 0, err
+	}
+	invalidItemIds := lo.FilterMap(cartItems, func(item *do.ShoppingCartItem, _ int) (int64, bool) {
+		return item.CartItemId, !item.IsValid()
+	})
+	if len(invalidItemIds) == 0 {
+		return 0, nil
+	}
+	if err = cds.cartRepo.DeleteUserCartItems(userId, invalidItemIds); err != nil {
+		return 0, errcode.Wrap("ClearInvalidCartItemsError", err)
+	}
+	return len(invalidItemIds), nil
+}
@@ -1,16 +1,38 @@
 package domainservice
 
 import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/config"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/samber/lo"
 	"math"
 )
 
+// 结算时按配置 bill_check.chain 的顺序执行优惠计算插件, 每个插件把计算出的优惠加入结算明细,
+// 所有插件执行完后按 bill_check.exclusive 的互斥规则和 bill_check.min_pay_money 的最低支付金额
+// 计算每项优惠实际抵扣的金额. 没有抵扣的优惠券和满减活动不会在下单时锁定和记录
+
+// billCheckerPlugins 可以在 bill_check.chain 中配置的优惠计算插件
+var billCheckerPlugins = map[string]func() cartBillCheckHandler{
+	enum.BillCheckerCoupon:   func() cartBillCheckHandler { return &couponChecker{} },
+	enum.BillCheckerDiscount: func() cartBillCheckHandler { return &discountChecker{} },
+	enum.BillCheckerVip:      func() cartBillCheckHandler { return &vipChecker{} },
+	enum.BillCheckerPoints:   func() cartBillCheckHandler { return &pointsChecker{} },
+}
+
 type CartBillChecker struct {
-	UserId        int64
-	checkingItems []*do.ShoppingCartItem
-	Coupon        struct {
+	ctx                context.Context
+	UserId             int64
+	UserCouponId       int64 // 用户选择的优惠券, 为 0 时使用优惠金额最高的优惠券
+	RedeemPoints       int   // 用户要使用的积分, 为 0 时不使用积分抵扣
+	checkingItems      []*do.ShoppingCartItem
+	OriginalTotalPrice int
+	UsableCoupons      []*do.CouponDiscount
+	DiscountCampaigns  []*do.DiscountEvaluation // 当前生效的每个满减活动的计算结果, 包括未使用的原因
+	Coupon             struct {
 		CouponId      int64
 		CouponName    string
 		DiscountMoney int
@@ -23,20 +45,57 @@ type CartBillChecker struct {
 		Threshold     int
 	}
 	VipOffRate int
+	Points     struct {
+		Points      int
+		DeductMoney int
+	}
+	reductions []*do.BillReduction
 	handler    cartBillCheckHandler
 }
 
-func NewCartBillChecker(items []*do.ShoppingCartItem, userId int64) *CartBillChecker {
+func NewCartBillChecker(ctx context.Context, items []*do.ShoppingCartItem, userId int64) *CartBillChecker {
 	checker := new(CartBillChecker)
+	checker.ctx = ctx
 	checker.UserId = userId
 	checker.checkingItems = items
 	checker.handler = &checkerStarter{}
-	checker.handler.SetNext(&couponChecker{}).
-		SetNext(&discountChecker{}).
-		SetNext(&vipChecker{})
+	chain := config.App.BillCheck.Chain
+	if len(chain) == 0 {
+		chain = enum.DefaultBillCheckChain
+	}
+	var handler cartBillCheckHandler = checker.handler
+	// 插件名在加载配置时已经校验过
+	for _, name := range lo.Uniq(chain) {
+		handler = handler.SetNext(billCheckerPlugins[name]())
+	}
 	return checker
 }
 
+// UseCoupon 使用用户选择的优惠券结算, 优惠券不能用于这些购物项时结算返回 ErrCouponUnavailable
+func (cbc *CartBillChecker) UseCoupon(userCouponId int64) *CartBillChecker {
+	cbc.UserCouponId = userCouponId
+	return cbc
+}
+
+// UsePoints 使用积分抵扣, 积分余额不足时结算返回 ErrPointsInsufficient
+func (cbc *CartBillChecker) UsePoints(points int) *CartBillChecker {
+	cbc.RedeemPoints = points
+	return cbc
+}
+
+// addReduction 插件把计算出的优惠加入结算明细, 没有优惠时不加入
+func (cbc *CartBillChecker) addReduction(checker, name string, refId int64, amount int) {
+	if amount <= 0 {
+		return
+	}
+	cbc.reductions = append(cbc.reductions, &do.BillReduction{
+		Checker: checker,
+		Name:    name,
+		RefId:   refId,
+		Amount:  amount,
+	})
+}
+
 type cartBillCheckHandler interface {
 	RunChecker(*CartBillChecker) error
 	SetNext(cartBillCheckHandler) cartBillCheckHandler
@@ -67,17 +126,33 @@ type couponChecker struct {
 	cartCommonChecker
 }
 
+// Check 从用户可以用于结算购物项的优惠券中选出用户指定的或者优惠金额最高的一张
 func (cc *couponChecker) Check(cbc *CartBillChecker) error {
-	cbc.Coupon = struct {
-		CouponId      int64
-		CouponName    string
-		DiscountMoney int
-		Threshold     int
-	}{
-		CouponId:      1,
-		DiscountMoney: 100,
-		Threshold:     100,
+	usableCoupons, err := NewCouponDomainSvc(cbc.ctx).GetUsableCoupons(cbc.UserId, cbc.checkingItems)
+	if err != nil {
+		return err
+	}
+	cbc.UsableCoupons = usableCoupons
+	var selected *do.CouponDiscount
+	if cbc.UserCouponId > 0 {
+		found := false
+		selected, found = lo.Find(usableCoupons, func(coupon *do.CouponDiscount) bool {
+			return coupon.UserCoupon.ID == cbc.UserCouponId
+		})
+		if !found {
+			return errcode.ErrCouponUnavailable
+		}
+	} else if len(usableCoupons) > 0 {
+		selected = usableCoupons[0]
+	}
+	if selected == nil {
+		return nil
 	}
+	cbc.Coupon.CouponId = selected.UserCoupon.ID
+	cbc.Coupon.CouponName = selected.UserCoupon.Template.Name
+	cbc.Coupon.DiscountMoney = selected.DiscountMoney
+	cbc.Coupon.Threshold = selected.UserCoupon.Template.Threshold
+	cbc.addReduction(enum.BillCheckerCoupon, "优惠券: "+cbc.Coupon.CouponName, cbc.Coupon.CouponId, cbc.Coupon.DiscountMoney)
 	return nil
 }
 
@@ -85,17 +160,21 @@ type discountChecker struct {
 	cartCommonChecker
 }
 
+// Check 计算当前生效的满减活动, 使用同时满足门槛的活动中优先级最高的一个
 func (dc *discountChecker) Check(cbc *CartBillChecker) error {
-	cbc.Discount = struct {
-		DiscountId    int64
-		DiscountName  string
-		DiscountMoney int
-		Threshold     int
-	}{
-		DiscountId:    1,
-		DiscountMoney: 100,
-		Threshold:     1000,
+	evaluations, applied, err := NewDiscountDomainSvc(cbc.ctx).EvaluateCampaigns(cbc.checkingItems)
+	if err != nil {
+		return err
+	}
+	cbc.DiscountCampaigns = evaluations
+	if applied == nil {
+		return nil
 	}
+	cbc.Discount.DiscountId = applied.Campaign.ID
+	cbc.Discount.DiscountName = applied.Campaign.Name
+	cbc.Discount.DiscountMoney = applied.DiscountMoney
+	cbc.Discount.Threshold = applied.Tier.Threshold
+	cbc.addReduction(enum.BillCheckerDiscount, "满减活动: "+cbc.Discount.DiscountName, cbc.Discount.DiscountId, cbc.Discount.DiscountMoney)
 	return nil
 }
 
@@ -103,8 +182,36 @@ type vipChecker struct {
 	cartCommonChecker
 }
 
+// Check 按用户当前有效的会员等级计算会员折扣
 func (vc *vipChecker) Check(cbc *CartBillChecker) error {
-	cbc.VipOffRate = 0
+	level, err := NewVipDomainSvc(cbc.ctx).GetUserActiveLevel(cbc.UserId)
+	if err != nil || level == nil {
+		return err
+	}
+	cbc.VipOffRate = level.DiscountRate
+	vipDiscountMoney := int(math.Round(float64(cbc.OriginalTotalPrice) * float64(cbc.VipOffRate) / 100))
+	cbc.addReduction(enum.BillCheckerVip, fmt.Sprintf("会员折扣: %s 减免%d%%", level.Name, level.DiscountRate), int64(level.Level), vipDiscountMoney)
+	return nil
+}
+
+type pointsChecker struct {
+	cartCommonChecker
+}
+
+// Check 按用户要使用的积分计算积分抵扣, 抵扣金额不超过商品金额的 points.max_redeem_rate%
+func (pc *pointsChecker) Check(cbc *CartBillChecker) error {
+	if cbc.RedeemPoints <= 0 {
+		return nil
+	}
+	balance, err := NewPointsDomainSvc(cbc.ctx).GetUserBalance(cbc.UserId)
+	if err != nil {
+		return err
+	}
+	if balance < cbc.RedeemPoints {
+		return errcode.ErrPointsInsufficient
+	}
+	cbc.Points.Points, cbc.Points.DeductMoney = pointsRedemption(cbc.RedeemPoints, balance, cbc.OriginalTotalPrice)
+	cbc.addReduction(enum.BillCheckerPoints, fmt.Sprintf("积分抵扣: 使用%d积分", cbc.Points.Points), int64(cbc.Points.Points), cbc.Points.DeductMoney)
 	return nil
 }
 
@@ -117,26 +224,94 @@ func (cs *checkerStarter) Check(cbc *CartBillChecker) (err error) {
 }
 
 func (cbc *CartBillChecker) GetBill() (*do.CartBillInfo, error) {
+	cbc.OriginalTotalPrice = itemsAmount(cbc.checkingItems)
 	err := cbc.handler.RunChecker(cbc)
 	if err != nil {
 		return nil, errcode.Wrap("CartBillCheckerError", err)
 	}
-	originalTotalPrice := lo.Reduce(cbc.checkingItems, func(agg int, item *do.ShoppingCartItem, index int) int {
-		return agg + item.CommoditySellingPrice
-	}, 0)
-	vipDiscountMoney := int(math.Round(float64(originalTotalPrice) * float64(cbc.VipOffRate) / 100))
-	totalPrice := originalTotalPrice - vipDiscountMoney
-	if cbc.Coupon.Threshold != 0 && originalTotalPrice > cbc.Coupon.Threshold {
-		totalPrice -= cbc.Coupon.DiscountMoney
+	minPayMoney := config.App.BillCheck.MinPayMoney
+	if minPayMoney <= 0 {
+		minPayMoney = enum.DefaultMinPayMoney
+	}
+	totalPrice := SettleBillReductions(cbc.reductions, cbc.OriginalTotalPrice, minPayMoney, config.App.BillCheck.Exclusive)
+	vipDiscountMoney := 0
+	for _, reduction := range cbc.reductions {
+		switch reduction.Checker {
+		case enum.BillCheckerCoupon:
+			cbc.Coupon.DiscountMoney = reduction.DeductMoney
+		case enum.BillCheckerDiscount:
+			cbc.Discount.DiscountMoney = reduction.DeductMoney
+		case enum.BillCheckerVip:
+			vipDiscountMoney = reduction.DeductMoney
+		case enum.BillCheckerPoints:
+			// 抵扣金额受限时只使用能整积分抵扣的部分, 不足1积分的金额退回支付金额
+			points := reduction.DeductMoney / pointsRedeemValue()
+			totalPrice += reduction.DeductMoney - points*pointsRedeemValue()
+			reduction.DeductMoney = points * pointsRedeemValue()
+			// 被互斥规则排除的积分保留排除原因
+			if points == 0 && reduction.Result != enum.BillReductionExcluded {
+				reduction.Result = enum.BillReductionNoRoom
+			}
+			reduction.Name = fmt.Sprintf("积分抵扣: 使用%d积分", points)
+			reduction.RefId = int64(points)
+			cbc.Points.Points, cbc.Points.DeductMoney = points, reduction.DeductMoney
+		}
+	}
+	// 没有抵扣的优惠券和满减活动不计入账单, 下单时不会被锁定
+	if cbc.Coupon.DiscountMoney == 0 {
+		cbc.Coupon.CouponId, cbc.Coupon.CouponName, cbc.Coupon.Threshold = 0, "", 0
 	}
-	if cbc.Discount.Threshold != 0 && originalTotalPrice > cbc.Discount.Threshold {
-		totalPrice -= cbc.Discount.DiscountMoney
+	if cbc.Discount.DiscountMoney == 0 {
+		cbc.Discount.DiscountId, cbc.Discount.DiscountName, cbc.Discount.Threshold = 0, "", 0
 	}
 	billInfo := new(do.CartBillInfo)
 	billInfo.Coupon = cbc.Coupon
 	billInfo.Discount = cbc.Discount
-	billInfo.OriginalTotalPrice = originalTotalPrice
+	billInfo.Points = cbc.Points
+	billInfo.UsableCoupons = cbc.UsableCoupons
+	billInfo.DiscountCampaigns = cbc.DiscountCampaigns
+	billInfo.OriginalTotalPrice = cbc.OriginalTotalPrice
+	billInfo.Reductions = cbc.reductions
 	billInfo.TotalPrice = totalPrice
 	billInfo.VipDiscountMoney = vipDiscountMoney
-	return billInfo, err
+	return billInfo, nil
+}
+
+// SettleBillReductions 计算每项优惠实际抵扣的金额, 返回支付金额:
+//   - 互斥组中有多项优惠时只使用优惠金额最高的一项, 金额相同时使用计算顺序靠前的
+//   - 按计算顺序依次抵扣, 支付金额不低于最低支付金额, 商品金额低于最低支付金额时不抵扣
+func SettleBillReductions(reductions []*do.BillReduction, originalTotalPrice, minPayMoney int, exclusive [][]string) int {
+	for _, group := range exclusive {
+		inGroup := lo.Filter(reductions, func(reduction *do.BillReduction, _ int) bool {
+			return reduction.Result != enum.BillReductionExcluded && lo.Contains(group, reduction.Checker)
+		})
+		if len(inGroup) < 2 {
+			continue
+		}
+		kept := lo.MaxBy(inGroup, func(a, b *do.BillReduction) bool { return a.Amount > b.Amount })
+		for _, reduction := range inGroup {
+			if reduction != kept {
+				reduction.Result = enum.BillReductionExcluded
+				reduction.ExcludedBy = kept.Checker
+			}
+		}
+	}
+	payMoney := originalTotalPrice
+	minPayMoney = min(minPayMoney, originalTotalPrice)
+	for _, reduction := range reductions {
+		if reduction.Result == enum.BillReductionExcluded {
+			continue
+		}
+		reduction.DeductMoney = min(reduction.Amount, payMoney-minPayMoney)
+		payMoney -= reduction.DeductMoney
+		switch {
+		case reduction.DeductMoney == reduction.Amount:
+			reduction.Result = enum.BillReductionApplied
+		case reduction.DeductMoney > 0:
+			reduction.Result = enum.BillReductionCapped
+		default:
+			reduction.Result = enum.BillReductionNoRoom
+		}
+	}
+	return payMoney
 }
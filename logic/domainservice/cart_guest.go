@@ -0,0 +1,151 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"sort"
+	"time"
+)
+
+// 未登录用户的购物车保存在Redis中, 以客户端持有的购物车标识区分, 最后一次修改后保留一段时间;
+// 用户登录时合并到用户的购物车中
+
+// NewGuestCartToken 生成未登录用户的购物车标识
+func NewGuestCartToken() string {
+	return util.RandomString(enum.GuestCartTokenLength)
+}
+
+// guestCartExpire 未登录用户购物车的保留时长, 未配置时使用默认值
+func guestCartExpire() time.Duration {
+	if config.App.Cart.GuestExpire > 0 {
+		return config.App.Cart.GuestExpire
+	}
+	return enum.DefaultGuestCartExpire
+}
+
+// GuestCartAddItem 向未登录用户的购物车添加SKU, 已有的SKU累加数量, 数量不超过单个购物项的上限
+func (cds *CartDomainSvc) GuestCartAddItem(cartToken string, skuId int64, num int) error {
+	items, err := cache.GetGuestCartItems(cds.ctx, cartToken)
+	if err != nil {
+		return errcode.Wrap("GuestCartAddItemError", err)
+	}
+	if _, ok := items[skuId]; !ok && len(items) >= enum.GuestCartMaxItems {
+		return errcode.ErrCartFull
+	}
+	if err = cache.SetGuestCartItem(cds.ctx, cartToken, skuId, min(items[skuId]+num, commodityMaxNum()), guestCartExpire()); err != nil {
+		return errcode.Wrap("GuestCartAddItemError", err)
+	}
+	return nil
+}
+
+// GuestCartUpdateItem 修改未登录用户购物车中SKU的数量
+func (cds *CartDomainSvc) GuestCartUpdateItem(cartToken string, skuId int64, num int) error {
+	items, err := cache.GetGuestCartItems(cds.ctx, cartToken)
+	if err != nil {
+		return errcode.Wrap("GuestCartUpdateItemError", err)
+	}
+	if _, ok := items[skuId]; !ok {
+		return errcode.ErrCartItemParam
+	}
+	if err = cache.SetGuestCartItem(cds.ctx, cartToken, skuId, min(num, commodityMaxNum()), guestCartExpire()); err != nil {
+		return errcode.Wrap("GuestCartUpdateItemError", err)
+	}
+	return nil
+}
+
+// GuestCartDeleteItem 从未登录用户的购物车中删除SKU
+func (cds *CartDomainSvc) GuestCartDeleteItem(cartToken string, skuId int64) error {
+	deleted, err := cache.DelGuestCartItem(cds.ctx, cartToken, skuId)
+	if err != nil {
+		return errcode.Wrap("GuestCartDeleteItemError", err)
+	}
+	if !deleted {
+		return errcode.ErrCartItemParam
+	}
+	return nil
+}
+
+// GetGuestCartItems 获取未登录用户购物车中的购物项, 按SKU ID排序, 填充商品信息和状态
+func (cds *CartDomainSvc) GetGuestCartItems(cartToken string) ([]*do.ShoppingCartItem, error) {
+	items, err := cache.GetGuestCartItems(cds.ctx, cartToken)
+	if err != nil {
+		return nil, errcode.Wrap("GetGuestCartItemsError", err)
+	}
+	skuIds := lo.Keys(items)
+	sort.Slice(skuIds, func(i, j int) bool { return skuIds[i] < skuIds[j] })
+	cartItems := lo.Map(skuIds, func(skuId int64, _ int) *do.ShoppingCartItem {
+		return &do.ShoppingCartItem{SkuId: skuId, CommodityNum: items[skuId]}
+	})
+	if len(cartItems) == 0 {
+		return cartItems, nil
+	}
+	if err = cds.fillInCommodityInfo(cartItems); err != nil {
+		return nil, err
+	}
+	return cartItems, nil
+}
+
+// MergeGuestCart 把未登录用户的购物车合并到用户的购物车: 同一SKU数量相加, 不超过单个购物项的上限,
+// 已删除的SKU不合并. 合并完成后删除未登录用户的购物车, 返回合并的购物项数量
+func (cds *CartDomainSvc) MergeGuestCart(cartToken string, userId int64) (int, error) {
+	items, err := cache.GetGuestCartItems(cds.ctx, cartToken)
+	if err != nil {
+		return 0, errcode.Wrap("MergeGuestCartError", err)
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	skuIds := lo.Keys(items)
+	sort.Slice(skuIds, func(i, j int) bool { return skuIds[i] < skuIds[j] })
+	sellInfos, err := NewCommodityDomainSvc(cds.ctx).GetSkuSellInfos(skuIds)
+	if err != nil {
+		return 0, errcode.Wrap("MergeGuestCartError", err)
+	}
+	userCartItems, err := cds.cartRepo.GetUserCartItems(userId)
+	if err != nil {
+		return 0, errcode.Wrap("MergeGuestCartError", err)
+	}
+	// 合并时只限制购物车容量和每个购物项的数量, 商品的限购数量在下单时校验
+	merged := 0
+	for _, skuId := range skuIds {
+		sellInfo := sellInfos[skuId]
+		if sellInfo.SellState == enum.SkuSellStateDeleted {
+			logger.New(cds.ctx).Info("MergeGuestCartSkipDeletedSku", "userId", userId, "skuId", skuId)
+			continue
+		}
+		cartItem, _ := lo.Find(userCartItems, func(item *do.ShoppingCartItem) bool { return item.SkuId == skuId })
+		if cartItem == nil && len(userCartItems) >= cartMaxItems() {
+			logger.New(cds.ctx).Info("MergeGuestCartSkipCartFull", "userId", userId, "skuId", skuId)
+			continue
+		}
+		if cartItem != nil {
+			cartItem.CommodityNum = min(cartItem.CommodityNum+items[skuId], commodityMaxNum())
+			err = cds.cartRepo.UpdateCartItem(cartItem)
+		} else {
+			cartItem = &do.ShoppingCartItem{
+				UserId:       userId,
+				CommodityId:  sellInfo.Sku.CommodityId,
+				SkuId:        skuId,
+				CommodityNum: min(items[skuId], commodityMaxNum()),
+				AddedPrice:   sellInfo.Sku.SellingPrice,
+				Selected:     true,
+			}
+			err = cds.cartRepo.AddCartItem(cartItem)
+			userCartItems = append(userCartItems, cartItem)
+		}
+		if err != nil {
+			return merged, errcode.Wrap("MergeGuestCartError", err)
+		}
+		merged++
+	}
+	if err = cache.DelGuestCart(cds.ctx, cartToken); err != nil {
+		return merged, errcode.Wrap("MergeGuestCartError", err)
+	}
+	return merged, nil
+}
@@ -0,0 +1,234 @@
+package domainservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"time"
+)
+
+// CartRepository 用户购物车的存储, 购物项只包含存储的字段, 商品信息由 CartDomainSvc 填充.
+// 每个部署通过配置 cart.storage 选择使用MySQL还是Redis存储
+type CartRepository interface {
+	// GetUserCartItems 获取用户购物车中的所有购物项, 按购物项ID排序
+	GetUserCartItems(userId int64) ([]*do.ShoppingCartItem, error)
+	// GetUserCartItem 获取用户购物车中指定ID的购物项, 不存在或不属于该用户时返回 nil
+	GetUserCartItem(userId, cartItemId int64) (*do.ShoppingCartItem, error)
+	// GetUserCartItemWithSkuId 获取用户购物车中指定SKU的购物项, 不存在时返回 nil
+	GetUserCartItemWithSkuId(userId, skuId int64) (*do.ShoppingCartItem, error)
+	// FindUserCartItems 获取用户购物车中指定ID的购物项, 不属于该用户的购物项不会返回
+	FindUserCartItems(userId int64, cartItemIds []int64) ([]*do.ShoppingCartItem, error)
+	// AddCartItem 添加购物项, 添加后回填购物项ID
+	AddCartItem(cartItem *do.ShoppingCartItem) error
+	// UpdateCartItem 保存购物项的数量和勾选状态
+	UpdateCartItem(cartItem *do.ShoppingCartItem) error
+	// SetUserCartItemsSelected 设置用户购物车中指定ID的购物项是否勾选
+	SetUserCartItemsSelected(userId int64, cartItemIds []int64, selected bool) error
+	// DeleteUserCartItems 删除用户购物车中指定ID的购物项
+	DeleteUserCartItems(userId int64, cartItemIds []int64) error
+}
+
+// NewCartRepository 按配置创建用户购物车的存储, 未配置时使用MySQL
+func NewCartRepository(ctx context.Context) CartRepository {
+	if config.App.Cart.Storage == enum.CartStorageRedis {
+		return &cartRedisRepository{ctx: ctx}
+	}
+	return &cartMysqlRepository{ctx: ctx, cartDao: dao.NewCartDao(ctx)}
+}
+
+// cartMysqlRepository 购物车存储在 shopping_cart_items 表
+type cartMysqlRepository struct {
+	ctx     context.Context
+	cartDao *dao.CartDao
+}
+
+func (r *cartMysqlRepository) GetUserCartItems(userId int64) ([]*do.ShoppingCartItem, error) {
+	cartItemModels, err := r.cartDao.GetUserCartItems(userId)
+	if err != nil {
+		return nil, err
+	}
+	return cartItemsFromModels(cartItemModels)
+}
+
+func (r *cartMysqlRepository) GetUserCartItem(userId, cartItemId int64) (*do.ShoppingCartItem, error) {
+	cartItemModel, err := r.cartDao.GetCartItemById(cartItemId)
+	if err != nil {
+		return nil, err
+	}
+	if cartItemModel.CartItemId == 0 || cartItemModel.UserId != userId {
+		return nil, nil
+	}
+	return cartItemFromModel(cartItemModel)
+}
+
+func (r *cartMysqlRepository) GetUserCartItemWithSkuId(userId, skuId int64) (*do.ShoppingCartItem, error) {
+	cartItemModel, err := r.cartDao.GetUserCartItemWithSkuId(userId, skuId)
+	if err != nil {
+		return nil, err
+	}
+	if cartItemModel.CartItemId == 0 {
+		return nil, nil
+	}
+	return cartItemFromModel(cartItemModel)
+}
+
+func (r *cartMysqlRepository) FindUserCartItems(userId int64, cartItemIds []int64) ([]*do.ShoppingCartItem, error) {
+	cartItemModels, err := r.cartDao.FindUserCartItems(userId, cartItemIds)
+	if err != nil {
+		return nil, err
+	}
+	return cartItemsFromModels(cartItemModels)
+}
+
+func (r *cartMysqlRepository) AddCartItem(cartItem *do.ShoppingCartItem) error {
+	return r.cartDao.AddCartItem(cartItem)
+}
+
+func (r *cartMysqlRepository) UpdateCartItem(cartItem *do.ShoppingCartItem) error {
+	return r.cartDao.UpdateCartItem(&model.ShoppingCartItem{
+		CartItemId:   cartItem.CartItemId,
+		CommodityNum: cartItem.CommodityNum,
+		Selected:     cartItem.Selected,
+	})
+}
+
+func (r *cartMysqlRepository) SetUserCartItemsSelected(userId int64, cartItemIds []int64, selected bool) error {
+	return r.cartDao.UpdateUserCartItemsSelected(userId, cartItemIds, selected)
+}
+
+func (r *cartMysqlRepository) DeleteUserCartItems(userId int64, cartItemIds []int64) error {
+	return r.cartDao.DeleteUserCartItems(userId, cartItemIds)
+}
+
+func cartItemFromModel(cartItemModel *model.ShoppingCartItem) (*do.ShoppingCartItem, error) {
+	cartItem := new(do.ShoppingCartItem)
+	if err := util.CopyProperties(cartItem, cartItemModel); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return cartItem, nil
+}
+
+func cartItemsFromModels(cartItemModels []*model.ShoppingCartItem) ([]*do.ShoppingCartItem, error) {
+	cartItems := make([]*do.ShoppingCartItem, 0, len(cartItemModels))
+	if err := util.CopyProperties(&cartItems, &cartItemModels); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return cartItems, nil
+}
+
+// cartRedisRepository 购物车存储在每个用户一个的Redis哈希中, field 为SKU ID, 购物项ID由计数器生成
+type cartRedisRepository struct {
+	ctx context.Context
+}
+
+func (r *cartRedisRepository) GetUserCartItems(userId int64) ([]*do.ShoppingCartItem, error) {
+	return cache.GetUserCartItems(r.ctx, userId)
+}
+
+func (r *cartRedisRepository) GetUserCartItem(userId, cartItemId int64) (*do.ShoppingCartItem, error) {
+	cartItems, err := cache.GetUserCartItems(r.ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	cartItem, _ := lo.Find(cartItems, func(item *do.ShoppingCartItem) bool {
+		return item.CartItemId == cartItemId
+	})
+	return cartItem, nil
+}
+
+func (r *cartRedisRepository) GetUserCartItemWithSkuId(userId, skuId int64) (*do.ShoppingCartItem, error) {
+	return cache.GetUserCartItem(r.ctx, userId, skuId)
+}
+
+func (r *cartRedisRepository) FindUserCartItems(userId int64, cartItemIds []int64) ([]*do.ShoppingCartItem, error) {
+	cartItems, err := cache.GetUserCartItems(r.ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Filter(cartItems, func(item *do.ShoppingCartItem, _ int) bool {
+		return lo.Contains(cartItemIds, item.CartItemId)
+	}), nil
+}
+
+func (r *cartRedisRepository) AddCartItem(cartItem *do.ShoppingCartItem) error {
+	cartItemId, err := cache.NewUserCartItemId(r.ctx)
+	if err != nil {
+		return err
+	}
+	cartItem.CartItemId = cartItemId
+	cartItem.CreatedAt = time.Now()
+	cartItem.UpdatedAt = cartItem.CreatedAt
+	return cache.SetUserCartItem(r.ctx, cartItem)
+}
+
+func (r *cartRedisRepository) UpdateCartItem(cartItem *do.ShoppingCartItem) error {
+	cartItem.UpdatedAt = time.Now()
+	return cache.SetUserCartItem(r.ctx, cartItem)
+}
+
+func (r *cartRedisRepository) SetUserCartItemsSelected(userId int64, cartItemIds []int64, selected bool) error {
+	cartItems, err := r.FindUserCartItems(userId, cartItemIds)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, cartItem := range cartItems {
+		cartItem.Selected = selected
+		cartItem.UpdatedAt = now
+	}
+	return cache.SetUserCartItems(r.ctx, userId, cartItems)
+}
+
+func (r *cartRedisRepository) DeleteUserCartItems(userId int64, cartItemIds []int64) error {
+	cartItems, err := r.FindUserCartItems(userId, cartItemIds)
+	if err != nil {
+		return err
+	}
+	skuIds := lo.Map(cartItems, func(item *do.ShoppingCartItem, _ int) int64 { return item.SkuId })
+	return cache.DelUserCartItems(r.ctx, userId, skuIds)
+}
+
+// MigrateCartStorage 为购物车表增加新字段; toRedis 为 true 时把MySQL中的购物项复制到Redis,
+// 购物项ID保持不变, 切换到Redis存储前执行. 可以重复执行, 返回复制的购物项数量
+func MigrateCartStorage(ctx context.Context, toRedis bool) (int, error) {
+	cartDao := dao.NewCartDao(ctx)
+	if err := cartDao.MigrateCartSchema(); err != nil {
+		return 0, errcode.Wrap("MigrateCartSchemaError", err)
+	}
+	if !toRedis {
+		return 0, nil
+	}
+	copied := 0
+	var afterId int64
+	for {
+		cartItemModels, err := cartDao.GetCartItemsAfter(afterId, enum.CartMigrateBatchSize)
+		if err != nil {
+			return copied, errcode.Wrap("MigrateCartStorageError", err)
+		}
+		if len(cartItemModels) == 0 {
+			break
+		}
+		cartItems, err := cartItemsFromModels(cartItemModels)
+		if err != nil {
+			return copied, err
+		}
+		for _, cartItem := range cartItems {
+			if err = cache.SetUserCartItem(ctx, cartItem); err != nil {
+				return copied, errcode.Wrap("MigrateCartStorageError", err)
+			}
+			copied++
+		}
+		afterId = cartItemModels[len(cartItemModels)-1].CartItemId
+	}
+	if err := cache.EnsureUserCartItemIdAtLeast(ctx, afterId); err != nil {
+		return copied, errcode.Wrap("MigrateCartStorageError", err)
+	}
+	return copied, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/Ian-zy0329/go-mall/common/app"
 	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
@@ -12,10 +11,13 @@ import (
 	"github.com/Ian-zy0329/go-mall/common/util"
 	"github.com/Ian-zy0329/go-mall/dal/cache"
 	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/Ian-zy0329/go-mall/resources"
+	"github.com/samber/lo"
 	"sort"
 	"time"
+	"unicode/utf8"
 )
 
 type CommodityDomainSvc struct {
@@ -46,42 +48,44 @@ func (cds *CommodityDomainSvc) InitCategoryData() error {
 	if err != nil {
 		return errcode.Wrap("初始化商品分类错误", err)
 	}
+	cds.invalidateCategoryCache()
 	return nil
 }
 
 func (cds *CommodityDomainSvc) InitRedisStock() error {
-	commodityModels, _ := cds.commodityDao.GetAllCommodity()
-	pipeline := cache.Redis().Pipeline()
+	skuModels, _ := cds.commodityDao.GetAllSkus()
 	stockItems := make([]*do.StockItem, 0)
-	for _, commodity := range commodityModels {
+	for _, sku := range skuModels {
 		stockItems = append(stockItems, &do.StockItem{
-			InitStock: commodity.StockNum,
-			Stock:     commodity.StockNum,
+			InitStock: sku.StockNum,
+			Stock:     sku.StockNum,
 			Modified:  time.Now(),
 			Version:   1,
-			ItemID:    commodity.ID,
+			ItemID:    sku.ID,
 		})
 	}
-	for _, stockItem := range stockItems {
-		stockKey := fmt.Sprintf("%s%d", enum.STOCK_KEY_PREFIX, stockItem.ItemID)
-		pipeline.HSet(cds.ctx, stockKey, map[string]interface{}{
-			"id":        stockItem.ItemID,
-			"stock":     stockItem.Stock,
-			"version":   stockItem.Version,
-			"modified":  stockItem.Modified.Format(time.RFC3339),
-			"initStock": stockItem.InitStock,
-		})
-		pipeline.SAdd(cds.ctx, enum.STOCK_INIT_SETKEY, stockItem.ItemID)
-		pipeline.Expire(cds.ctx, stockKey, 30*24*time.Hour) // 30天过期
-	}
-	if _, err := pipeline.Exec(cds.ctx); err != nil {
+	if err := cache.InitStockItems(cds.ctx, stockItems); err != nil {
 		return errcode.Wrap("初始化商品库存错误", err)
 	}
 	return nil
 }
 
+// GetHierarchicCommodityCategories 获取层级分类树, 分类树构建后缓存在Redis中, 后台修改分类时清除
 func (cds *CommodityDomainSvc) GetHierarchicCommodityCategories() []*do.HierarchicCommodityCategory {
-	categoryModels, _ := cds.commodityDao.GetAllCategories()
+	hierarchicCategories, err := cache.GetCategoryTree(cds.ctx, cds.buildHierarchicCategories)
+	if err != nil {
+		logger.New(cds.ctx).Error("GetHierarchicCommodityCategoriesError", "err", err)
+		return nil
+	}
+	return hierarchicCategories
+}
+
+// buildHierarchicCategories 从MySQL读取全部分类并构建层级分类树
+func (cds *CommodityDomainSvc) buildHierarchicCategories() ([]*do.HierarchicCommodityCategory, error) {
+	categoryModels, err := cds.commodityDao.GetAllCategories()
+	if err != nil {
+		return nil, errcode.Wrap("GetAllCategoriesError", err)
+	}
 	FlatCategories := make([]*do.HierarchicCommodityCategory, 0, len(categoryModels))
 	util.CopyProperties(&FlatCategories, &categoryModels)
 	sort.SliceStable(FlatCategories, func(i, j int) bool {
@@ -116,7 +120,7 @@ func (cds *CommodityDomainSvc) GetHierarchicCommodityCategories() []*do.Hierarch
 		}
 		hierarchicCategories = append(hierarchicCategories, category)
 	}
-	return hierarchicCategories
+	return hierarchicCategories, nil
 }
 
 func (cds *CommodityDomainSvc) GetSubCategories(parentId int64) ([]*do.CommodityCategory, error) {
@@ -149,27 +153,21 @@ func (cds *CommodityDomainSvc) InitCommodityData() error {
 	if err != nil {
 		return errcode.Wrap("初始化商品错误", err)
 	}
-	return nil
+	// 初始化数据中每个颜色、容量都是一个商品, 合并为 SPU/SKU
+	if _, err = cds.MigrateToSku(); err != nil {
+		return err
+	}
+	return cds.MigrateSearchSchema()
 }
 
-func (cds *CommodityDomainSvc) GetCommodityListInCategory(cagegoryInfo *do.CommodityCategory, pagination *app.Pagination) ([]*do.Commodity, error) {
-	offset := pagination.Offset()
-	size := pagination.GetPageSize()
+// GetCommodityListInCategory 获取分类下满足筛选条件的一页商品及其分面统计
+func (cds *CommodityDomainSvc) GetCommodityListInCategory(cagegoryInfo *do.CommodityCategory, query *do.CommodityListQuery, pagination *app.Pagination) ([]*do.Commodity, *do.CommodityFacets, error) {
 	thirdLevelCategoryIds, err := cds.commodityDao.GetThirdLevelCategories(cagegoryInfo)
 	if err != nil {
-		return nil, errcode.Wrap("获取三级分类错误", err)
-	}
-	commodityModelList, totalRows, err := cds.commodityDao.GetCommoditiesListInCategory(thirdLevelCategoryIds, offset, size)
-	if err != nil {
-		return nil, errcode.Wrap("获取商品列表错误", err)
-	}
-	pagination.SetTotalRows(int(totalRows))
-	commodityList := make([]*do.Commodity, 0, len(commodityModelList))
-	err = util.CopyProperties(&commodityList, &commodityModelList)
-	if err != nil {
-		return nil, errcode.ErrCoverData.WithCause(err)
+		return nil, nil, errcode.Wrap("获取三级分类错误", err)
 	}
-	return commodityList, nil
+	query.CategoryIds = thirdLevelCategoryIds
+	return cds.listCommodities(query, pagination)
 }
 
 func (cds *CommodityDomainSvc) GetCategoryInfo(categoryId int64) *do.CommodityCategory {
@@ -184,38 +182,132 @@ func (cds *CommodityDomainSvc) GetCategoryInfo(categoryId int64) *do.CommodityCa
 	return categoryInfo
 }
 
-func (cds *CommodityDomainSvc) SearchCommodity(keyword string, pagination *app.Pagination) ([]*do.Commodity, error) {
-	offset := pagination.Offset()
-	size := pagination.GetPageSize()
+// SearchCommodity 按关键词在商品名称、标签、简介中全文检索满足筛选条件的商品, 未指定排序时按相关度排序, 并记录搜索词;
+// ngram 全文索引无法匹配单个字, 关键词拆分后没有两个字及以上的词时退化为名称模糊匹配
+func (cds *CommodityDomainSvc) SearchCommodity(keyword string, categoryInfo *do.CommodityCategory, query *do.CommodityListQuery, pagination *app.Pagination) ([]*do.Commodity, *do.CommodityFacets, error) {
+	if categoryInfo != nil {
+		thirdLevelCategoryIds, err := cds.commodityDao.GetThirdLevelCategories(categoryInfo)
+		if err != nil {
+			return nil, nil, errcode.Wrap("获取三级分类错误", err)
+		}
+		query.FilterCategoryIds = thirdLevelCategoryIds
+	}
+	query.Keyword = keyword
+	query.FullTextSearch = lo.SomeBy(util.SearchTerms(keyword), func(term string) bool { return utf8.RuneCountInString(term) >= 2 })
+	commodityList, facets, err := cds.listCommodities(query, pagination)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 翻页不算新的搜索, 只在请求第一页时记录搜索词
+	if pagination.GetPage() == 1 {
+		NewSearchDomainSvc(cds.ctx).RecordQuery(keyword, int64(pagination.TotalRows))
+	}
+	return commodityList, facets, nil
+}
 
-	commodityModelList, totalRows, err := cds.commodityDao.FindCommodityWithNameKeyword(keyword, offset, size)
+// listCommodities 按查询条件获取一页商品, 并统计分类和价格区间的分面
+func (cds *CommodityDomainSvc) listCommodities(query *do.CommodityListQuery, pagination *app.Pagination) ([]*do.Commodity, *do.CommodityFacets, error) {
+	commodityModelList, totalRows, err := cds.commodityDao.ListCommodities(query, pagination.Offset(), pagination.GetPageSize())
 	if err != nil {
-		return nil, errcode.Wrap("SearchCommodityError", err)
+		return nil, nil, errcode.Wrap("获取商品列表错误", err)
 	}
 	pagination.SetTotalRows(int(totalRows))
-
 	commodityList := make([]*do.Commodity, 0, len(commodityModelList))
 	err = util.CopyProperties(&commodityList, &commodityModelList)
 	if err != nil {
-		return nil, errcode.ErrCoverData.WithCause(err)
+		return nil, nil, errcode.ErrCoverData.WithCause(err)
+	}
+	facets, err := cds.getCommodityFacets(query)
+	if err != nil {
+		return nil, nil, err
 	}
+	return commodityList, facets, nil
+}
 
-	return commodityList, nil
+func (cds *CommodityDomainSvc) getCommodityFacets(query *do.CommodityListQuery) (*do.CommodityFacets, error) {
+	categoryFacets, err := cds.commodityDao.CountCategoryFacets(query)
+	if err != nil {
+		return nil, errcode.Wrap("统计商品分类分面错误", err)
+	}
+	categories, err := cds.commodityDao.FindCategories(lo.Map(categoryFacets, func(facet *do.CommodityCategoryFacet, _ int) int64 {
+		return facet.CategoryId
+	}))
+	if err != nil {
+		return nil, errcode.Wrap("统计商品分类分面错误", err)
+	}
+	categoryNames := lo.SliceToMap(categories, func(category *model.CommodityCategory) (int64, string) {
+		return category.ID, category.Name
+	})
+	for _, facet := range categoryFacets {
+		facet.CategoryName = categoryNames[facet.CategoryId]
+	}
+
+	bounds := enum.CommodityPriceFacetBounds
+	priceCounts, err := cds.commodityDao.CountPriceFacets(query, bounds)
+	if err != nil {
+		return nil, errcode.Wrap("统计商品价格分面错误", err)
+	}
+	priceFacets := make([]*do.CommodityPriceFacet, 0, len(bounds))
+	for i, bound := range bounds {
+		if priceCounts[i] == 0 {
+			continue
+		}
+		priceFacet := &do.CommodityPriceFacet{MinPrice: bound, Count: priceCounts[i]}
+		if i+1 < len(bounds) {
+			priceFacet.MaxPrice = bounds[i+1]
+		}
+		priceFacets = append(priceFacets, priceFacet)
+	}
+	return &do.CommodityFacets{Categories: categoryFacets, PriceRanges: priceFacets}, nil
+}
+
+// MigrateSearchSchema 创建商品搜索使用的全文索引
+func (cds *CommodityDomainSvc) MigrateSearchSchema() error {
+	if err := cds.commodityDao.MigrateSearchSchema(); err != nil {
+		return errcode.Wrap("MigrateSearchSchemaError", err)
+	}
+	return nil
 }
 
+// GetCommodityInfo 获取商品详情, 详情缓存在Redis中, 不存在的商品ID也会短暂缓存;
+// 缓存中的销量和总库存会有延迟, 实时库存以SKU库存接口为准
 func (cds *CommodityDomainSvc) GetCommodityInfo(commodityId int64) *do.Commodity {
-	commodityModel, err := cds.commodityDao.FindCommodityById(commodityId)
-	log := logger.New(cds.ctx)
+	commodity, err := cache.GetCommodityDetail(cds.ctx, commodityId, func() (*do.Commodity, error) {
+		return cds.loadCommodityInfo(commodityId)
+	})
 	if err != nil {
-		log.Error("GetCommodityInfoError", "err", err)
+		logger.New(cds.ctx).Error("GetCommodityInfoError", "err", err)
 		return nil
 	}
+	return commodity
+}
 
-	commodity := new(do.Commodity)
-	err = util.CopyProperties(commodity, commodityModel)
+// loadCommodityInfo 从MySQL读取商品详情, 商品不存在时返回 nil
+func (cds *CommodityDomainSvc) loadCommodityInfo(commodityId int64) (*do.Commodity, error) {
+	commodityModel, err := cds.commodityDao.FindCommodityById(commodityId)
 	if err != nil {
-		log.Error(errcode.ErrCoverData.Error(), "err", err)
-		return nil
+		return nil, errcode.Wrap("FindCommodityError", err)
+	}
+	if commodityModel.ID == 0 {
+		return nil, nil
+	}
+	commodity := new(do.Commodity)
+	if err = util.CopyProperties(commodity, commodityModel); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return commodity, nil
+}
+
+// invalidateCommodityCache 商品修改后清除详情缓存, 清除失败时缓存会在过期后自然更新
+func (cds *CommodityDomainSvc) invalidateCommodityCache(commodityId int64) {
+	if err := cache.DelCommodityDetail(cds.ctx, commodityId); err != nil {
+		logger.New(cds.ctx).Error("InvalidateCommodityCacheError", "commodityId", commodityId, "err", err)
+	}
+}
+
+// invalidateCategoryCache 分类修改后清除分类树缓存
+func (cds *CommodityDomainSvc) invalidateCategoryCache() {
+	if err := cache.DelCategoryTree(cds.ctx); err != nil {
+		logger.New(cds.ctx).Error("InvalidateCategoryCacheError", "err", err)
 	}
-	return commodity
 }
@@ -0,0 +1,374 @@
+package domainservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"strings"
+)
+
+// CreateCommodity 后台新建商品及其规格和SKU, 并初始化SKU的Redis库存, 返回新商品的ID
+func (cds *CommodityDomainSvc) CreateCommodity(creation *do.CommodityCreation) (int64, error) {
+	if err := cds.checkCommodityCategory(creation.Commodity.CategoryId); err != nil {
+		return 0, err
+	}
+	for _, sku := range creation.Skus {
+		if err := checkCommodityPrice(sku.OriginalPrice, sku.SellingPrice); err != nil {
+			return 0, err
+		}
+	}
+	skuSpecValues, err := matchSkuSpecValues(creation)
+	if err != nil {
+		return 0, err
+	}
+
+	commodityModel := new(model.Commodity)
+	if err = util.CopyProperties(commodityModel, creation.Commodity); err != nil {
+		return 0, errcode.ErrCoverData.WithCause(err)
+	}
+	commodityModel.ID = 0
+	if err = cds.commodityDao.CreateCommodityWithSkus(commodityModel, creation, skuSpecValues); err != nil {
+		return 0, errcode.Wrap("CreateCommodityError", err)
+	}
+	if err = NewStockDomainSvc(cds.ctx).InitSkuStock(creation.Skus); err != nil {
+		// 商品已经创建成功, Redis库存在首次下单时会从MySQL预热
+		logger.New(cds.ctx).Error("CreateCommodityInitStockError", "commodityId", commodityModel.ID, "err", err)
+	}
+	// 新ID之前可能被当作不存在的商品缓存过
+	cds.invalidateCommodityCache(commodityModel.ID)
+	return commodityModel.ID, nil
+}
+
+// UpdateCommodity 后台修改商品的基本信息和SKU, SKU的库存按增减量同时调整Redis和MySQL
+func (cds *CommodityDomainSvc) UpdateCommodity(commodity *do.Commodity, skuUpdates []*do.CommoditySkuUpdate) error {
+	commodityModel, err := cds.commodityDao.FindCommodityById(commodity.ID)
+	if err != nil {
+		return errcode.Wrap("UpdateCommodityError", err)
+	}
+	if commodityModel.ID == 0 {
+		return errcode.ErrCommodityNotExists
+	}
+	if err = cds.checkCommodityCategory(commodity.CategoryId); err != nil {
+		return err
+	}
+	skuModels, err := cds.commodityDao.GetCommoditySkus(commodity.ID)
+	if err != nil {
+		return errcode.Wrap("UpdateCommodityError", err)
+	}
+	skuIds := lo.Map(skuModels, func(sku *model.CommoditySku, _ int) int64 { return sku.ID })
+	for _, skuUpdate := range skuUpdates {
+		if !lo.Contains(skuIds, skuUpdate.SkuId) {
+			return errcode.ErrCommoditySkuInvalid.WithCause(fmt.Errorf("SKU %d 不属于商品 %d", skuUpdate.SkuId, commodity.ID))
+		}
+		if err = checkCommodityPrice(skuUpdate.OriginalPrice, skuUpdate.SellingPrice); err != nil {
+			return err
+		}
+	}
+
+	commodityFields := map[string]interface{}{
+		"name":           commodity.Name,
+		"intro":          commodity.Intro,
+		"category_id":    commodity.CategoryId,
+		"cover_img":      commodity.CoverImg,
+		"images":         commodity.Images,
+		"detail_content": commodity.DetailContent,
+		"tag":            commodity.Tag,
+		"purchase_limit": commodity.PurchaseLimit,
+	}
+	// 先调整Redis的可售库存, Redis中的库存可能已被下单预占, 以Redis的校验为准; 写MySQL失败时撤销调整
+	stockDeltas := make(map[int64]int, len(skuUpdates))
+	for _, skuUpdate := range skuUpdates {
+		stockDeltas[skuUpdate.SkuId] = skuUpdate.StockDelta
+	}
+	err = NewStockDomainSvc(cds.ctx).AdjustSkuStock(stockDeltas, func() error {
+		stockEnough, err := cds.commodityDao.UpdateCommodityWithSkus(commodity.ID, commodityFields, skuUpdates)
+		if err != nil {
+			return errcode.Wrap("UpdateCommodityError", err)
+		}
+		if !stockEnough {
+			return errcode.ErrCommodityStockOut
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cds.invalidateCommodityCache(commodity.ID)
+	cds.notifyFavoritePriceDrop(commodityModel.ID, commodityModel.SellingPrice)
+	return nil
+}
+
+// notifyFavoritePriceDrop 商品的最低售价比修改前低时提醒收藏了商品的用户, 提醒失败不影响商品修改
+func (cds *CommodityDomainSvc) notifyFavoritePriceDrop(commodityId int64, oldPrice int) {
+	log := logger.New(cds.ctx)
+	commodity, err := cds.loadCommodityInfo(commodityId)
+	if err != nil || commodity == nil {
+		log.Error("NotifyFavoritePriceDropError", "commodityId", commodityId, "err", err)
+		return
+	}
+	if commodity.SellingPrice >= oldPrice {
+		return
+	}
+	if err = NewFavoriteDomainSvc(cds.ctx).NotifyPriceDrop(commodity); err != nil {
+		log.Error("NotifyFavoritePriceDropError", "commodityId", commodityId, "err", err)
+	}
+}
+
+// SetCommoditySellStatus 商品上架或下架
+func (cds *CommodityDomainSvc) SetCommoditySellStatus(commodityId int64, sellStatus int) error {
+	commodityModel, err := cds.commodityDao.FindCommodityById(commodityId)
+	if err != nil {
+		return errcode.Wrap("SetCommoditySellStatusError", err)
+	}
+	if commodityModel.ID == 0 {
+		return errcode.ErrCommodityNotExists
+	}
+	if err = cds.commodityDao.UpdateCommoditySellStatus(commodityId, sellStatus); err != nil {
+		return errcode.Wrap("SetCommoditySellStatusError", err)
+	}
+	cds.invalidateCommodityCache(commodityId)
+	return nil
+}
+
+// DeleteCommodity 软删除商品, 商品的SKU和Redis库存保留, 恢复商品后可以继续售卖
+func (cds *CommodityDomainSvc) DeleteCommodity(commodityId int64) error {
+	commodityModel, err := cds.commodityDao.FindCommodityById(commodityId)
+	if err != nil {
+		return errcode.Wrap("DeleteCommodityError", err)
+	}
+	if commodityModel.ID == 0 {
+		return errcode.ErrCommodityNotExists
+	}
+	if err = cds.commodityDao.DeleteCommodity(commodityId); err != nil {
+		return errcode.Wrap("DeleteCommodityError", err)
+	}
+	cds.invalidateCommodityCache(commodityId)
+	return nil
+}
+
+// RestoreCommodity 恢复被软删除的商品
+func (cds *CommodityDomainSvc) RestoreCommodity(commodityId int64) error {
+	commodityModel, err := cds.commodityDao.FindCommodityByIdUnscoped(commodityId)
+	if err != nil {
+		return errcode.Wrap("RestoreCommodityError", err)
+	}
+	if commodityModel.ID == 0 {
+		return errcode.ErrCommodityNotExists
+	}
+	if err = cds.commodityDao.RestoreCommodity(commodityId); err != nil {
+		return errcode.Wrap("RestoreCommodityError", err)
+	}
+	cds.invalidateCommodityCache(commodityId)
+	return nil
+}
+
+// AdminListCommodities 后台商品列表
+func (cds *CommodityDomainSvc) AdminListCommodities(query *do.AdminCommodityQuery, pagination *app.Pagination) ([]*do.Commodity, error) {
+	commodityModels, totalRows, err := cds.commodityDao.AdminListCommodities(query, pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("AdminListCommoditiesError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+	commodityList := make([]*do.Commodity, 0, len(commodityModels))
+	if err = util.CopyProperties(&commodityList, &commodityModels); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return commodityList, nil
+}
+
+// checkCommodityCategory 商品只能挂在已存在的三级分类下
+func (cds *CommodityDomainSvc) checkCommodityCategory(categoryId int64) error {
+	category, err := cds.commodityDao.GetCategoryById(categoryId)
+	if err != nil {
+		return errcode.Wrap("CheckCommodityCategoryError", err)
+	}
+	if category.ID == 0 || category.Level != 3 {
+		return errcode.ErrCommodityCategoryInvalid
+	}
+	return nil
+}
+
+// checkCommodityPrice 售价必须大于0且不能高于原价
+func checkCommodityPrice(originalPrice, sellingPrice int) error {
+	if sellingPrice <= 0 || originalPrice < sellingPrice {
+		return errcode.ErrCommodityPriceInvalid.WithCause(fmt.Errorf("原价 %d, 售价 %d", originalPrice, sellingPrice))
+	}
+	return nil
+}
+
+// matchSkuSpecValues 为新建商品的规格值排序, 并把每个SKU的规格值名称对应到规格值上,
+// 每个SKU要在每个规格项下各选一个值, 且不能有规格值组合相同的SKU
+func matchSkuSpecValues(creation *do.CommodityCreation) ([][]*do.CommoditySpecValue, error) {
+	if len(creation.Specs) == 0 && len(creation.Skus) != 1 {
+		return nil, errcode.ErrCommoditySkuInvalid.WithCause(errors.New("没有规格项的商品只能有一个SKU"))
+	}
+	for i, spec := range creation.Specs {
+		spec.Rank = i
+		for j, value := range spec.Values {
+			value.Rank = j
+		}
+		if len(lo.UniqBy(spec.Values, func(value *do.CommoditySpecValue) string { return value.Value })) != len(spec.Values) {
+			return nil, errcode.ErrCommoditySkuInvalid.WithCause(fmt.Errorf("规格项 %s 有重复的规格值", spec.Name))
+		}
+	}
+	skuSpecValues := make([][]*do.CommoditySpecValue, 0, len(creation.Skus))
+	combinations := make(map[string]struct{}, len(creation.Skus))
+	for i, sku := range creation.Skus {
+		var valueNames []string
+		if i < len(creation.SkuSpecValues) {
+			valueNames = creation.SkuSpecValues[i]
+		}
+		if len(valueNames) != len(creation.Specs) {
+			return nil, errcode.ErrCommoditySkuInvalid.WithCause(fmt.Errorf("第%d个SKU的规格值数量与规格项数量不一致", i+1))
+		}
+		values := make([]*do.CommoditySpecValue, 0, len(valueNames))
+		for j, valueName := range valueNames {
+			value, found := lo.Find(creation.Specs[j].Values, func(value *do.CommoditySpecValue) bool { return value.Value == valueName })
+			if !found {
+				return nil, errcode.ErrCommoditySkuInvalid.WithCause(fmt.Errorf("规格项 %s 没有规格值 %s", creation.Specs[j].Name, valueName))
+			}
+			values = append(values, value)
+		}
+		sku.SpecDesc = strings.Join(valueNames, skuSpecValueSep)
+		combination := strings.Join(valueNames, "\x00")
+		if _, exists := combinations[combination]; exists {
+			return nil, errcode.ErrCommoditySkuInvalid.WithCause(fmt.Errorf("规格值组合 %s 重复", sku.SpecDesc))
+		}
+		combinations[combination] = struct{}{}
+		skuSpecValues = append(skuSpecValues, values)
+	}
+	return skuSpecValues, nil
+}
+
+// CreateCategory 新建分类, 分类的层级由父分类决定, 返回新分类的ID
+func (cds *CommodityDomainSvc) CreateCategory(category *do.CommodityCategory) (int64, error) {
+	level, err := cds.categoryLevelUnder(category.ParentId)
+	if err != nil {
+		return 0, err
+	}
+	categoryModel := &model.CommodityCategory{
+		Level:    level,
+		ParentId: category.ParentId,
+		Name:     category.Name,
+		IconImg:  category.IconImg,
+		Rank:     category.Rank,
+	}
+	if err = cds.commodityDao.CreateCategory(categoryModel); err != nil {
+		return 0, errcode.Wrap("CreateCategoryError", err)
+	}
+	cds.invalidateCategoryCache()
+	return categoryModel.ID, nil
+}
+
+// UpdateCategory 修改分类, 修改父分类后层级随之改变:
+// 有子分类的分类层级不能改变, 有商品的分类只能是三级分类
+func (cds *CommodityDomainSvc) UpdateCategory(category *do.CommodityCategory) error {
+	categoryModel, err := cds.commodityDao.GetCategoryById(category.ID)
+	if err != nil {
+		return errcode.Wrap("UpdateCategoryError", err)
+	}
+	if categoryModel.ID == 0 {
+		return errcode.ErrCategoryNotExists
+	}
+	if category.ParentId == category.ID {
+		return errcode.ErrCategoryLevelInvalid
+	}
+	level, err := cds.categoryLevelUnder(category.ParentId)
+	if err != nil {
+		return err
+	}
+	if level != categoryModel.Level {
+		subCount, err := cds.commodityDao.CountSubCategories(category.ID)
+		if err != nil {
+			return errcode.Wrap("UpdateCategoryError", err)
+		}
+		commodityCount, err := cds.commodityDao.CountCommoditiesInCategory(category.ID)
+		if err != nil {
+			return errcode.Wrap("UpdateCategoryError", err)
+		}
+		if subCount > 0 || commodityCount > 0 {
+			return errcode.ErrCategoryLevelInvalid.WithCause(fmt.Errorf("分类 %d 下有 %d 个子分类、%d 个商品, 不能从%d级改为%d级",
+				category.ID, subCount, commodityCount, categoryModel.Level, level))
+		}
+	}
+	err = cds.commodityDao.UpdateCategory(category.ID, map[string]interface{}{
+		"level":     level,
+		"parent_id": category.ParentId,
+		"name":      category.Name,
+		"icon_img":  category.IconImg,
+		"rank":      category.Rank,
+	})
+	if err != nil {
+		return errcode.Wrap("UpdateCategoryError", err)
+	}
+	cds.invalidateCategoryCache()
+	return nil
+}
+
+// RerankCategories 批量修改分类的排序值, 用于后台拖动调整分类顺序
+func (cds *CommodityDomainSvc) RerankCategories(ranks map[int64]int) error {
+	categoryIds := lo.Keys(ranks)
+	categories, err := cds.commodityDao.FindCategories(categoryIds)
+	if err != nil {
+		return errcode.Wrap("RerankCategoriesError", err)
+	}
+	if len(categories) != len(categoryIds) {
+		return errcode.ErrCategoryNotExists
+	}
+	if err = cds.commodityDao.UpdateCategoryRanks(ranks); err != nil {
+		return errcode.Wrap("RerankCategoriesError", err)
+	}
+	cds.invalidateCategoryCache()
+	return nil
+}
+
+// DeleteCategory 删除分类, 分类下还有子分类或商品时不能删除
+func (cds *CommodityDomainSvc) DeleteCategory(categoryId int64) error {
+	categoryModel, err := cds.commodityDao.GetCategoryById(categoryId)
+	if err != nil {
+		return errcode.Wrap("DeleteCategoryError", err)
+	}
+	if categoryModel.ID == 0 {
+		return errcode.ErrCategoryNotExists
+	}
+	subCount, err := cds.commodityDao.CountSubCategories(categoryId)
+	if err != nil {
+		return errcode.Wrap("DeleteCategoryError", err)
+	}
+	commodityCount, err := cds.commodityDao.CountCommoditiesInCategory(categoryId)
+	if err != nil {
+		return errcode.Wrap("DeleteCategoryError", err)
+	}
+	if subCount > 0 || commodityCount > 0 {
+		return errcode.ErrCategoryNotEmpty
+	}
+	if err = cds.commodityDao.DeleteCategory(categoryId); err != nil {
+		return errcode.Wrap("DeleteCategoryError", err)
+	}
+	cds.invalidateCategoryCache()
+	return nil
+}
+
+// categoryLevelUnder 返回挂在 parentId 下的分类的层级, parentId 为0时是一级分类, 父分类必须存在且不是三级分类
+func (cds *CommodityDomainSvc) categoryLevelUnder(parentId int64) (int, error) {
+	if parentId == 0 {
+		return 1, nil
+	}
+	parent, err := cds.commodityDao.GetCategoryById(parentId)
+	if err != nil {
+		return 0, errcode.Wrap("GetParentCategoryError", err)
+	}
+	if parent.ID == 0 {
+		return 0, errcode.ErrCategoryNotExists.WithCause(fmt.Errorf("父分类 %d 不存在", parentId))
+	}
+	if parent.Level >= 3 {
+		return 0, errcode.ErrCategoryLevelInvalid
+	}
+	return parent.Level + 1, nil
+}
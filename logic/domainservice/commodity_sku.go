@@ -0,0 +1,310 @@
+package domainservice
+
+import (
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 迁移时从旧商品简介中识别规格的规则: 简介中各商品不同的词, 容量归入"存储", 其余的词合并为一个规格项,
+// 全部是颜色时命名为"颜色", 否则命名为"版本"
+var (
+	skuStorageRegexp     = regexp.MustCompile(`^(\d+)(G|GB|T|TB)$`)
+	skuStorageJoinRegexp = regexp.MustCompile(`^(\D+?)(\d+(?:G|GB|T|TB))$`) // 例如 全网通64G
+	skuColorRegexp       = regexp.MustCompile(`(色|金|银|灰|黑|白|红|蓝|绿|紫|粉|橙|黄)$`)
+)
+
+const (
+	skuSpecVersion  = "版本"
+	skuSpecColor    = "颜色"
+	skuSpecStorage  = "存储"
+	skuSpecStyle    = "款式"
+	skuSpecMissing  = "标准"
+	skuSpecValueSep = " "
+)
+
+// GetSkuInfo 获取SKU信息, SKU不存在或已删除时返回 ErrCommodityNotExists
+func (cds *CommodityDomainSvc) GetSkuInfo(skuId int64) (*do.CommoditySku, error) {
+	skuModel, err := cds.commodityDao.FindSkuById(skuId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSkuInfoError", err)
+	}
+	if skuModel.ID == 0 {
+		return nil, errcode.ErrCommodityNotExists
+	}
+	sku := new(do.CommoditySku)
+	if err = util.CopyProperties(sku, skuModel); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return sku, nil
+}
+
+// GetSkuSellInfos 获取SKU及其所属商品, 并按统一的可售规则判断售卖状态, 已删除的也会返回
+func (cds *CommodityDomainSvc) GetSkuSellInfos(skuIds []int64) (map[int64]*do.SkuSellInfo, error) {
+	skuModels, err := cds.commodityDao.FindSkusUnscoped(lo.Uniq(skuIds))
+	if err != nil {
+		return nil, errcode.Wrap("GetSkuSellInfosError", err)
+	}
+	commodityIds := lo.Uniq(lo.Map(skuModels, func(sku *model.CommoditySku, _ int) int64 { return sku.CommodityId }))
+	commodityModels, err := cds.commodityDao.FindCommoditiesUnscoped(commodityIds)
+	if err != nil {
+		return nil, errcode.Wrap("GetSkuSellInfosError", err)
+	}
+	skuMap := lo.SliceToMap(skuModels, func(sku *model.CommoditySku) (int64, *model.CommoditySku) { return sku.ID, sku })
+	commodityMap := lo.SliceToMap(commodityModels, func(commodity *model.Commodity) (int64, *model.Commodity) {
+		return commodity.ID, commodity
+	})
+	sellInfos := make(map[int64]*do.SkuSellInfo, len(skuIds))
+	for _, skuId := range skuIds {
+		sellInfo := &do.SkuSellInfo{SellState: enum.SkuSellStateDeleted}
+		sellInfos[skuId] = sellInfo
+		skuModel, skuFound := skuMap[skuId]
+		if !skuFound {
+			continue
+		}
+		commodityModel := commodityMap[skuModel.CommodityId]
+		sellInfo.SellState = skuSellState(commodityModel, skuModel)
+		sellInfo.Sku = new(do.CommoditySku)
+		if err = util.CopyProperties(sellInfo.Sku, skuModel); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		if commodityModel != nil {
+			sellInfo.Commodity = new(do.Commodity)
+			if err = util.CopyProperties(sellInfo.Commodity, commodityModel); err != nil {
+				return nil, errcode.ErrCoverData.WithCause(err)
+			}
+		}
+	}
+	return sellInfos, nil
+}
+
+// CheckSkusSellable 检查SKU是否都可以售卖, 加购、结算和下单都以此为准
+func (cds *CommodityDomainSvc) CheckSkusSellable(skuIds []int64) (map[int64]*do.SkuSellInfo, error) {
+	sellInfos, err := cds.GetSkuSellInfos(skuIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, skuId := range skuIds {
+		switch sellInfos[skuId].SellState {
+		case enum.SkuSellStateDeleted:
+			return nil, errcode.ErrCommodityNotExists.WithCause(fmt.Errorf("SKU %d 已删除", skuId))
+		case enum.SkuSellStateOffShelf:
+			return nil, errcode.ErrCommodityOffShelf.WithCause(fmt.Errorf("SKU %d 已下架", skuId))
+		}
+	}
+	return sellInfos, nil
+}
+
+// skuSellState 商品的可售规则: 商品和SKU都未删除, 并且都是上架状态
+func skuSellState(commodity *model.Commodity, sku *model.CommoditySku) int {
+	if commodity == nil || sku == nil || commodity.IsDel != 0 || sku.IsDel != 0 {
+		return enum.SkuSellStateDeleted
+	}
+	if commodity.SellStatus != enum.CommoditySellStatusOn || sku.SellStatus != enum.CommoditySellStatusOn {
+		return enum.SkuSellStateOffShelf
+	}
+	return enum.SkuSellStateOnSale
+}
+
+// GetCommoditySkus 获取商品的规格项和全部SKU
+func (cds *CommodityDomainSvc) GetCommoditySkus(commodityId int64) ([]*do.CommoditySpec, []*do.CommoditySku, error) {
+	specModels, err := cds.commodityDao.GetCommoditySpecs(commodityId)
+	if err != nil {
+		return nil, nil, errcode.Wrap("GetCommoditySkusError", err)
+	}
+	valueModels, err := cds.commodityDao.GetCommoditySpecValues(commodityId)
+	if err != nil {
+		return nil, nil, errcode.Wrap("GetCommoditySkusError", err)
+	}
+	skuModels, err := cds.commodityDao.GetCommoditySkus(commodityId)
+	if err != nil {
+		return nil, nil, errcode.Wrap("GetCommoditySkusError", err)
+	}
+	specs := make([]*do.CommoditySpec, 0, len(specModels))
+	values := make([]*do.CommoditySpecValue, 0, len(valueModels))
+	skus := make([]*do.CommoditySku, 0, len(skuModels))
+	if err = util.CopyProperties(&specs, &specModels); err != nil {
+		return nil, nil, errcode.ErrCoverData.WithCause(err)
+	}
+	if err = util.CopyProperties(&values, &valueModels); err != nil {
+		return nil, nil, errcode.ErrCoverData.WithCause(err)
+	}
+	if err = util.CopyProperties(&skus, &skuModels); err != nil {
+		return nil, nil, errcode.ErrCoverData.WithCause(err)
+	}
+	specValues := lo.GroupBy(values, func(value *do.CommoditySpecValue) int64 {
+		return value.SpecId
+	})
+	for _, spec := range specs {
+		spec.Values = specValues[spec.ID]
+	}
+	return specs, skus, nil
+}
+
+// MigrateToSku 把还没有SKU的旧商品迁移到SPU/SKU模型: 同一分类下同名的旧商品合并为一个SPU,
+// 每个旧商品成为该SPU下的一个SKU且SKU ID沿用旧商品ID, 返回迁移的SPU数量
+func (cds *CommodityDomainSvc) MigrateToSku() (int, error) {
+	if err := cds.commodityDao.MigrateSkuSchema(); err != nil {
+		return 0, errcode.Wrap("MigrateSkuSchemaError", err)
+	}
+	commodityIds, err := cds.commodityDao.GetCommodityIdsWithoutSku()
+	if err != nil {
+		return 0, errcode.Wrap("MigrateToSkuError", err)
+	}
+	if len(commodityIds) == 0 {
+		return 0, nil
+	}
+	commodities, err := cds.commodityDao.FindCommodities(commodityIds)
+	if err != nil {
+		return 0, errcode.Wrap("MigrateToSkuError", err)
+	}
+	groups := lo.GroupBy(commodities, func(commodity *model.Commodity) string {
+		return fmt.Sprintf("%d:%s", commodity.CategoryId, commodity.Name)
+	})
+	groupKeys := lo.Keys(groups)
+	sort.Strings(groupKeys)
+	migrated := 0
+	for _, groupKey := range groupKeys {
+		migration := buildSkuMigration(groups[groupKey])
+		if err = cds.commodityDao.MigrateCommodityToSku(migration); err != nil {
+			return migrated, errcode.Wrap("MigrateToSkuError", err)
+		}
+		logger.New(cds.ctx).Info("CommodityMigratedToSku", "commodityId", migration.CommodityId,
+			"foldedIds", migration.FoldedIds, "skuCount", len(migration.Skus))
+		migrated++
+	}
+	return migrated, nil
+}
+
+// buildSkuMigration 为同名的一组旧商品生成迁移方案, 规格从各商品简介中不相同的词识别
+func buildSkuMigration(commodities []*model.Commodity) *do.CommoditySkuMigration {
+	sort.Slice(commodities, func(i, j int) bool { return commodities[i].ID < commodities[j].ID })
+	migration := &do.CommoditySkuMigration{CommodityId: commodities[0].ID}
+	for _, commodity := range commodities {
+		if commodity.ID != migration.CommodityId {
+			migration.FoldedIds = append(migration.FoldedIds, commodity.ID)
+		}
+		migration.Skus = append(migration.Skus, &do.CommoditySku{
+			ID:            commodity.ID,
+			CoverImg:      commodity.CoverImg,
+			Images:        commodity.Images,
+			OriginalPrice: commodity.OriginalPrice,
+			SellingPrice:  commodity.SellingPrice,
+			StockNum:      commodity.StockNum,
+			SellStatus:    commodity.SellStatus,
+		})
+	}
+	if len(commodities) == 1 {
+		// 单个商品迁移为没有规格项的默认SKU
+		return migration
+	}
+
+	skuSpecs := make([]map[string]string, len(commodities))
+	for i, commodity := range commodities {
+		skuSpecs[i] = classifySpecTokens(distinctIntroTokens(commodity.Intro, commodities))
+	}
+	specNames := lo.Filter([]string{skuSpecVersion, skuSpecStorage}, func(name string, _ int) bool {
+		return lo.SomeBy(skuSpecs, func(spec map[string]string) bool { return spec[name] != "" })
+	})
+	combinations := lo.Map(skuSpecs, func(spec map[string]string, _ int) string {
+		return strings.Join(lo.Map(specNames, func(name string, _ int) string { return spec[name] }), "|")
+	})
+	if len(specNames) == 0 || len(lo.Uniq(combinations)) != len(combinations) {
+		// 简介完全相同或识别出的规格有重复时, 合并为一个"款式"规格项, 重复的款式按顺序编号
+		combinationCount := lo.CountValues(combinations)
+		combinationSeq := make(map[string]int)
+		for i, spec := range skuSpecs {
+			style := strings.Join(lo.Compact(lo.Map(specNames, func(name string, _ int) string { return spec[name] })), skuSpecValueSep)
+			combinationSeq[combinations[i]]++
+			if style == "" {
+				style = fmt.Sprintf("%s%d", skuSpecStyle, combinationSeq[combinations[i]])
+			} else if combinationCount[combinations[i]] > 1 {
+				style = fmt.Sprintf("%s(%d)", style, combinationSeq[combinations[i]])
+			}
+			skuSpecs[i] = map[string]string{skuSpecStyle: style}
+		}
+		specNames = []string{skuSpecStyle}
+	}
+
+	skuValues := make([][]*do.CommoditySpecValue, len(commodities))
+	for rank, specName := range specNames {
+		spec := &do.CommoditySpec{Name: specName, Rank: rank}
+		if specName == skuSpecVersion && lo.EveryBy(skuSpecs, func(spec map[string]string) bool {
+			return skuColorRegexp.MatchString(spec[skuSpecVersion])
+		}) {
+			spec.Name = skuSpecColor
+		}
+		valueMap := make(map[string]*do.CommoditySpecValue)
+		for i := range skuSpecs {
+			valueText := skuSpecs[i][specName]
+			if valueText == "" {
+				valueText = skuSpecMissing
+			}
+			value, exists := valueMap[valueText]
+			if !exists {
+				value = &do.CommoditySpecValue{Value: valueText, Rank: len(spec.Values)}
+				valueMap[valueText] = value
+				spec.Values = append(spec.Values, value)
+			}
+			skuValues[i] = append(skuValues[i], value)
+		}
+		migration.Specs = append(migration.Specs, spec)
+	}
+	for i, sku := range migration.Skus {
+		sku.SpecDesc = strings.Join(lo.Map(skuValues[i], func(value *do.CommoditySpecValue, _ int) string {
+			return value.Value
+		}), skuSpecValueSep)
+	}
+	migration.SkuSpecValues = skuValues
+	return migration
+}
+
+// introTokens 把商品简介拆分为词, "全网通64G" 这类粘连的容量会被拆开
+func introTokens(intro string) []string {
+	tokens := make([]string, 0)
+	for _, token := range strings.Fields(intro) {
+		if matches := skuStorageJoinRegexp.FindStringSubmatch(token); matches != nil {
+			tokens = append(tokens, matches[1], matches[2])
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// distinctIntroTokens 返回简介中不是同组所有商品共有的词
+func distinctIntroTokens(intro string, commodities []*model.Commodity) []string {
+	common := lo.Reduce(commodities[1:], func(agg []string, commodity *model.Commodity, _ int) []string {
+		return lo.Intersect(agg, introTokens(commodity.Intro))
+	}, introTokens(commodities[0].Intro))
+	return lo.Without(introTokens(intro), common...)
+}
+
+// classifySpecTokens 把词归入 存储、版本 两个规格项, 同一规格项的多个词用空格连接
+func classifySpecTokens(tokens []string) map[string]string {
+	classified := make(map[string][]string)
+	for _, token := range tokens {
+		if skuStorageRegexp.MatchString(token) {
+			classified[skuSpecStorage] = append(classified[skuSpecStorage], normalizeStorage(token))
+		} else {
+			classified[skuSpecVersion] = append(classified[skuSpecVersion], token)
+		}
+	}
+	return lo.MapValues(classified, func(values []string, _ string) string {
+		return strings.Join(values, skuSpecValueSep)
+	})
+}
+
+// normalizeStorage 统一容量的写法, 128G 和 128GB 视为同一个规格值
+func normalizeStorage(token string) string {
+	matches := skuStorageRegexp.FindStringSubmatch(token)
+	return matches[1] + strings.TrimSuffix(matches[2], "B") + "B"
+}
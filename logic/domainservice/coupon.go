@@ -0,0 +1,345 @@
+package domainservice
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+type CouponDomainSvc struct {
+	ctx       context.Context
+	couponDao *dao.CouponDao
+}
+
+func NewCouponDomainSvc(ctx context.Context) *CouponDomainSvc {
+	return &CouponDomainSvc{
+		ctx:       ctx,
+		couponDao: dao.NewCouponDao(ctx),
+	}
+}
+
+// CreateTemplate 后台创建优惠券模板, 创建后回填模板ID
+func (cds *CouponDomainSvc) CreateTemplate(template *do.CouponTemplate) error {
+	if err := checkCouponTemplate(template); err != nil {
+		return err
+	}
+	templateModel := couponTemplateToModel(template)
+	templateModel.Status = enum.CouponTemplateStatusOn
+	if err := cds.couponDao.CreateTemplate(templateModel); err != nil {
+		return errcode.Wrap("CreateCouponTemplateError", err)
+	}
+	template.ID = templateModel.ID
+	template.Status = templateModel.Status
+	return nil
+}
+
+// SetTemplateStatus 开始或停止领取和发放优惠券, 已领取的优惠券不受影响
+func (cds *CouponDomainSvc) SetTemplateStatus(templateId int64, status int) error {
+	template, err := cds.couponDao.GetTemplateById(templateId)
+	if err != nil {
+		return errcode.Wrap("SetCouponTemplateStatusError", err)
+	}
+	if template.ID == 0 {
+		return errcode.ErrCouponNotExists
+	}
+	if _, err = cds.couponDao.UpdateTemplateStatus(templateId, status); err != nil {
+		return errcode.Wrap("SetCouponTemplateStatusError", err)
+	}
+	return nil
+}
+
+// GetClaimableTemplates 当前可以领取的优惠券模板
+func (cds *CouponDomainSvc) GetClaimableTemplates() ([]*do.CouponTemplate, error) {
+	templateModels, err := cds.couponDao.GetClaimableTemplates(time.Now())
+	if err != nil {
+		return nil, errcode.Wrap("GetClaimableTemplatesError", err)
+	}
+	return lo.Map(templateModels, func(templateModel *model.CouponTemplate, _ int) *do.CouponTemplate {
+		return couponTemplateFromModel(templateModel)
+	}), nil
+}
+
+// ClaimCoupon 用户领取一张优惠券, 模板需要在领取时间内且未领完, 每个用户最多领取 PerUserLimit 张
+func (cds *CouponDomainSvc) ClaimCoupon(userId, templateId int64) (*do.UserCoupon, error) {
+	template, err := cds.getTemplate(templateId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if template.Status != enum.CouponTemplateStatusOn || now.Before(template.ValidStartAt) || !now.Before(template.ValidEndAt) {
+		return nil, errcode.ErrCouponNotClaimable
+	}
+	couponModel := newUserCouponModel(template, userId, now)
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		// 先更新模板的已发放数量, 模板记录被锁住后再统计用户已领取的数量
+		affected, err := cds.couponDao.IncrTemplateIssuedInTx(tx, templateId, 1)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errcode.ErrCouponSoldOut
+		}
+		counts, err := cds.couponDao.CountUserTemplateCouponsInTx(tx, templateId, []int64{userId})
+		if err != nil {
+			return err
+		}
+		if counts[userId] >= template.PerUserLimit {
+			return errcode.ErrCouponClaimLimit
+		}
+		return cds.couponDao.CreateUserCouponsInTx(tx, []*model.UserCoupon{couponModel})
+	})
+	if err != nil {
+		return nil, errcode.Wrap("ClaimCouponError", err)
+	}
+	coupon := userCouponFromModel(couponModel)
+	coupon.Template = template
+	return coupon, nil
+}
+
+// IssueCoupons 后台给一批用户各发放一张优惠券, 已达到领取上限的用户跳过, 返回发放的数量.
+// 模板需要可以发放且未到结束时间, 剩余数量不够发给所有用户时不发放
+func (cds *CouponDomainSvc) IssueCoupons(templateId int64, userIds []int64) (int, error) {
+	template, err := cds.getTemplate(templateId)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if template.Status != enum.CouponTemplateStatusOn || !now.Before(template.ValidEndAt) {
+		return 0, errcode.ErrCouponNotClaimable
+	}
+	userIds = lo.Uniq(userIds)
+	issued := 0
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		// 先锁住模板记录, 与用户领取串行执行
+		if _, err := cds.couponDao.IncrTemplateIssuedInTx(tx, templateId, 0); err != nil {
+			return err
+		}
+		counts, err := cds.couponDao.CountUserTemplateCouponsInTx(tx, templateId, userIds)
+		if err != nil {
+			return err
+		}
+		couponModels := lo.FilterMap(userIds, func(userId int64, _ int) (*model.UserCoupon, bool) {
+			return newUserCouponModel(template, userId, now), counts[userId] < template.PerUserLimit
+		})
+		if len(couponModels) == 0 {
+			return nil
+		}
+		affected, err := cds.couponDao.IncrTemplateIssuedInTx(tx, templateId, len(couponModels))
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errcode.ErrCouponSoldOut
+		}
+		issued = len(couponModels)
+		return cds.couponDao.CreateUserCouponsInTx(tx, couponModels)
+	})
+	if err != nil {
+		return 0, errcode.Wrap("IssueCouponsError", err)
+	}
+	return issued, nil
+}
+
+// GetUserCoupons 用户的优惠券列表, status 为 0 时返回所有状态的优惠券
+func (cds *CouponDomainSvc) GetUserCoupons(userId int64, status int, pagination *app.Pagination) ([]*do.UserCoupon, error) {
+	couponModels, totalRows, err := cds.couponDao.GetUserCoupons(userId, status, pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("GetUserCouponsError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+	return cds.fillInTemplates(couponModels)
+}
+
+// GetUsableCoupons 用户可以用于这些购物项的优惠券, 按优惠金额从高到低排序
+func (cds *CouponDomainSvc) GetUsableCoupons(userId int64, items []*do.ShoppingCartItem) ([]*do.CouponDiscount, error) {
+	couponModels, err := cds.couponDao.GetUsableUserCoupons(userId, time.Now())
+	if err != nil {
+		return nil, errcode.Wrap("GetUsableCouponsError", err)
+	}
+	coupons, err := cds.fillInTemplates(couponModels)
+	if err != nil {
+		return nil, err
+	}
+	discounts := lo.FilterMap(coupons, func(coupon *do.UserCoupon, _ int) (*do.CouponDiscount, bool) {
+		discount := CouponDiscount(coupon, items)
+		return discount, discount.DiscountMoney > 0
+	})
+	// 优惠金额相同时先用快要过期的
+	sort.SliceStable(discounts, func(i, j int) bool {
+		return discounts[i].DiscountMoney > discounts[j].DiscountMoney
+	})
+	return discounts, nil
+}
+
+// LockOrderCouponInTx 下单时用订单锁定用户的优惠券, 优惠券已被使用或过期时返回 ErrCouponUnavailable
+func (cds *CouponDomainSvc) LockOrderCouponInTx(tx *gorm.DB, userCouponId, userId, orderId int64) error {
+	affected, err := cds.couponDao.LockUserCouponInTx(tx, userCouponId, userId, orderId, time.Now())
+	if err != nil {
+		return errcode.Wrap("LockOrderCouponError", err)
+	}
+	if affected == 0 {
+		return errcode.ErrCouponUnavailable
+	}
+	return nil
+}
+
+// UseOrderCouponInTx 订单支付成功后把订单锁定的优惠券标记为已使用
+func (cds *CouponDomainSvc) UseOrderCouponInTx(tx *gorm.DB, orderId int64, usedAt time.Time) error {
+	return cds.couponDao.UseOrderCouponInTx(tx, orderId, usedAt)
+}
+
+// ReleaseOrderCoupon 订单取消、超时关闭或退款后把订单锁定或使用的优惠券退回给用户, 订单没有使用优惠券时直接返回
+func (cds *CouponDomainSvc) ReleaseOrderCoupon(orderId int64) error {
+	if _, err := cds.couponDao.ReleaseOrderCoupon(orderId); err != nil {
+		return errcode.Wrap("ReleaseOrderCouponError", err)
+	}
+	return nil
+}
+
+func (cds *CouponDomainSvc) getTemplate(templateId int64) (*do.CouponTemplate, error) {
+	templateModel, err := cds.couponDao.GetTemplateById(templateId)
+	if err != nil {
+		return nil, errcode.Wrap("GetCouponTemplateError", err)
+	}
+	if templateModel.ID == 0 {
+		return nil, errcode.ErrCouponNotExists
+	}
+	return couponTemplateFromModel(templateModel), nil
+}
+
+// fillInTemplates 转换用户优惠券并带上优惠券模板, 模板被删除的优惠券不返回
+func (cds *CouponDomainSvc) fillInTemplates(couponModels []*model.UserCoupon) ([]*do.UserCoupon, error) {
+	templateIds := lo.Uniq(lo.Map(couponModels, func(couponModel *model.UserCoupon, _ int) int64 {
+		return couponModel.TemplateId
+	}))
+	if len(templateIds) == 0 {
+		return []*do.UserCoupon{}, nil
+	}
+	templateModels, err := cds.couponDao.FindTemplates(templateIds)
+	if err != nil {
+		return nil, errcode.Wrap("FindCouponTemplatesError", err)
+	}
+	templates := lo.SliceToMap(templateModels, func(templateModel *model.CouponTemplate) (int64, *do.CouponTemplate) {
+		return templateModel.ID, couponTemplateFromModel(templateModel)
+	})
+	return lo.FilterMap(couponModels, func(couponModel *model.UserCoupon, _ int) (*do.UserCoupon, bool) {
+		coupon := userCouponFromModel(couponModel)
+		coupon.Template = templates[couponModel.TemplateId]
+		return coupon, coupon.Template != nil
+	}), nil
+}
+
+// CouponDiscount 计算优惠券用于购物项时的优惠金额, 只有使用范围内的商品参与门槛和优惠的计算
+func CouponDiscount(coupon *do.UserCoupon, items []*do.ShoppingCartItem) *do.CouponDiscount {
+	template := coupon.Template
+	scopeAmount := itemsAmount(promotionScopeItems(template.ScopeType, template.ScopeIds, items))
+	discount := &do.CouponDiscount{UserCoupon: coupon, ScopeAmount: scopeAmount}
+	if scopeAmount <= 0 || scopeAmount < template.Threshold {
+		return discount
+	}
+	switch template.DiscountType {
+	case enum.CouponDiscountTypeAmount:
+		discount.DiscountMoney = min(template.DiscountValue, scopeAmount)
+	case enum.CouponDiscountTypePercent:
+		discount.DiscountMoney = scopeAmount * template.DiscountValue / 100
+		if template.MaxDiscount > 0 {
+			discount.DiscountMoney = min(discount.DiscountMoney, template.MaxDiscount)
+		}
+	}
+	return discount
+}
+
+func checkCouponTemplate(template *do.CouponTemplate) error {
+	if !template.ValidEndAt.After(template.ValidStartAt) {
+		return errcode.ErrParams.WithCause(fmt.Errorf("优惠券结束时间需要晚于开始时间"))
+	}
+	if template.DiscountType == enum.CouponDiscountTypePercent && template.DiscountValue >= 100 {
+		return errcode.ErrParams.WithCause(fmt.Errorf("折扣券减免的百分比需要小于100"))
+	}
+	if template.ScopeType != enum.PromotionScopeAll && len(template.ScopeIds) == 0 {
+		return errcode.ErrParams.WithCause(fmt.Errorf("指定范围的优惠券需要设置分类或商品"))
+	}
+	if template.PerUserLimit <= 0 {
+		template.PerUserLimit = 1
+	}
+	return nil
+}
+
+// newUserCouponModel 按模板生成用户优惠券, 设置了有效天数时从领取时开始计算, 不超过模板的结束时间
+func newUserCouponModel(template *do.CouponTemplate, userId int64, now time.Time) *model.UserCoupon {
+	validStartAt := lo.Ternary(now.After(template.ValidStartAt), now, template.ValidStartAt)
+	validEndAt := template.ValidEndAt
+	if template.ValidDays > 0 {
+		validEndAt = lo.Ternary(validStartAt.AddDate(0, 0, template.ValidDays).Before(validEndAt),
+			validStartAt.AddDate(0, 0, template.ValidDays), validEndAt)
+	}
+	return &model.UserCoupon{
+		UserId:       userId,
+		TemplateId:   template.ID,
+		Status:       enum.UserCouponStatusUnused,
+		ValidStartAt: validStartAt,
+		ValidEndAt:   validEndAt,
+		UsedAt:       time.Unix(0, 0),
+	}
+}
+
+func couponTemplateFromModel(templateModel *model.CouponTemplate) *do.CouponTemplate {
+	return &do.CouponTemplate{
+		ID:            templateModel.ID,
+		Name:          templateModel.Name,
+		DiscountType:  templateModel.DiscountType,
+		DiscountValue: templateModel.DiscountValue,
+		MaxDiscount:   templateModel.MaxDiscount,
+		Threshold:     templateModel.Threshold,
+		ScopeType:     templateModel.ScopeType,
+		ScopeIds:      parseScopeIds(templateModel.ScopeIds),
+		TotalNum:      templateModel.TotalNum,
+		IssuedNum:     templateModel.IssuedNum,
+		PerUserLimit:  templateModel.PerUserLimit,
+		ValidStartAt:  templateModel.ValidStartAt,
+		ValidEndAt:    templateModel.ValidEndAt,
+		ValidDays:     templateModel.ValidDays,
+		Status:        templateModel.Status,
+		CreatedAt:     templateModel.CreatedAt,
+	}
+}
+
+func couponTemplateToModel(template *do.CouponTemplate) *model.CouponTemplate {
+	return &model.CouponTemplate{
+		Name:          template.Name,
+		DiscountType:  template.DiscountType,
+		DiscountValue: template.DiscountValue,
+		MaxDiscount:   template.MaxDiscount,
+		Threshold:     template.Threshold,
+		ScopeType:     template.ScopeType,
+		ScopeIds:      joinScopeIds(template.ScopeIds),
+		TotalNum:      template.TotalNum,
+		PerUserLimit:  template.PerUserLimit,
+		ValidStartAt:  template.ValidStartAt,
+		ValidEndAt:    template.ValidEndAt,
+		ValidDays:     template.ValidDays,
+	}
+}
+
+func userCouponFromModel(couponModel *model.UserCoupon) *do.UserCoupon {
+	return &do.UserCoupon{
+		ID:           couponModel.ID,
+		UserId:       couponModel.UserId,
+		TemplateId:   couponModel.TemplateId,
+		Status:       couponModel.Status,
+		OrderId:      couponModel.OrderId,
+		ValidStartAt: couponModel.ValidStartAt,
+		ValidEndAt:   couponModel.ValidEndAt,
+		UsedAt:       couponModel.UsedAt,
+		CreatedAt:    couponModel.CreatedAt,
+	}
+}
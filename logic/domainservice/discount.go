@@ -0,0 +1,230 @@
+package domainservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"sort"
+	"time"
+)
+
+type DiscountDomainSvc struct {
+	ctx         context.Context
+	discountDao *dao.DiscountDao
+}
+
+func NewDiscountDomainSvc(ctx context.Context) *DiscountDomainSvc {
+	return &DiscountDomainSvc{
+		ctx:         ctx,
+		discountDao: dao.NewDiscountDao(ctx),
+	}
+}
+
+// CreateCampaign 后台创建满减活动, 创建后回填活动ID
+func (dds *DiscountDomainSvc) CreateCampaign(campaign *do.DiscountCampaign) error {
+	if err := checkDiscountCampaign(campaign); err != nil {
+		return err
+	}
+	campaignModel, err := discountCampaignToModel(campaign)
+	if err != nil {
+		return err
+	}
+	campaignModel.Status = enum.DiscountStatusOn
+	if err = dds.discountDao.CreateCampaign(campaignModel); err != nil {
+		return errcode.Wrap("CreateDiscountCampaignError", err)
+	}
+	campaign.ID = campaignModel.ID
+	campaign.Status = campaignModel.Status
+	return nil
+}
+
+// SetCampaignStatus 上线或下线满减活动
+func (dds *DiscountDomainSvc) SetCampaignStatus(campaignId int64, status int) error {
+	campaign, err := dds.discountDao.GetCampaignById(campaignId)
+	if err != nil {
+		return errcode.Wrap("SetDiscountCampaignStatusError", err)
+	}
+	if campaign.ID == 0 {
+		return errcode.ErrDiscountNotExists
+	}
+	if err = dds.discountDao.UpdateCampaignStatus(campaignId, status); err != nil {
+		return errcode.Wrap("SetDiscountCampaignStatusError", err)
+	}
+	return nil
+}
+
+func (dds *DiscountDomainSvc) GetCampaigns(pagination *app.Pagination) ([]*do.DiscountCampaign, error) {
+	campaignModels, totalRows, err := dds.discountDao.GetCampaigns(pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("GetDiscountCampaignsError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+	return discountCampaignsFromModels(campaignModels)
+}
+
+// EvaluateCampaigns 计算当前生效的每个满减活动用于购物项的优惠, 返回所有活动的计算结果和使用的活动.
+// 同时满足门槛的活动中使用优先级最高的一个, 优先级相同时使用优惠金额最多的, 没有满足的活动时 applied 为 nil
+func (dds *DiscountDomainSvc) EvaluateCampaigns(items []*do.ShoppingCartItem) (evaluations []*do.DiscountEvaluation, applied *do.DiscountEvaluation, err error) {
+	campaignModels, err := dds.discountDao.GetActiveCampaigns(time.Now())
+	if err != nil {
+		return nil, nil, errcode.Wrap("EvaluateDiscountCampaignsError", err)
+	}
+	campaigns, err := discountCampaignsFromModels(campaignModels)
+	if err != nil {
+		return nil, nil, err
+	}
+	evaluations, applied = EvaluateDiscountCampaigns(campaigns, items)
+	return evaluations, applied, nil
+}
+
+// EvaluateDiscountCampaigns 计算每个满减活动用于购物项的优惠, 返回所有活动的计算结果和使用的活动.
+// 同时满足门槛的活动中使用优先级最高的一个, 优先级相同时使用优惠金额最多的, 没有满足的活动时 applied 为 nil
+func EvaluateDiscountCampaigns(campaigns []*do.DiscountCampaign, items []*do.ShoppingCartItem) (evaluations []*do.DiscountEvaluation, applied *do.DiscountEvaluation) {
+	evaluations = lo.Map(campaigns, func(campaign *do.DiscountCampaign, _ int) *do.DiscountEvaluation {
+		return evaluateDiscount(campaign, items)
+	})
+	for _, evaluation := range evaluations {
+		if evaluation.DiscountMoney <= 0 {
+			continue
+		}
+		if applied == nil || evaluation.Campaign.Priority > applied.Campaign.Priority ||
+			evaluation.Campaign.Priority == applied.Campaign.Priority && evaluation.DiscountMoney > applied.DiscountMoney {
+			applied = evaluation
+		}
+	}
+	for _, evaluation := range evaluations {
+		if evaluation.DiscountMoney > 0 {
+			evaluation.Result = lo.Ternary(evaluation == applied, enum.DiscountResultApplied, enum.DiscountResultNotPreferred)
+		}
+	}
+	return evaluations, applied
+}
+
+// evaluateDiscount 计算满减活动用于购物项的优惠, 只有适用范围内的商品参与门槛和优惠的计算
+func evaluateDiscount(campaign *do.DiscountCampaign, items []*do.ShoppingCartItem) *do.DiscountEvaluation {
+	scopeItems := promotionScopeItems(campaign.ScopeType, campaign.ScopeIds, items)
+	evaluation := &do.DiscountEvaluation{
+		Campaign:    campaign,
+		ScopeAmount: itemsAmount(scopeItems),
+		ScopeNum:    lo.SumBy(scopeItems, func(item *do.ShoppingCartItem) int { return item.CommodityNum }),
+		Result:      enum.DiscountResultThresholdUnmet,
+	}
+	if len(scopeItems) == 0 {
+		evaluation.Result = enum.DiscountResultNoEligibleItem
+		return evaluation
+	}
+	// 档位按门槛从高到低排序, 使用满足的最高一档
+	switch campaign.Type {
+	case enum.DiscountTypeFullReduction:
+		evaluation.Tier, _ = lo.Find(campaign.Tiers, func(tier *do.DiscountTier) bool {
+			return evaluation.ScopeAmount >= tier.Threshold
+		})
+		if evaluation.Tier != nil {
+			evaluation.DiscountMoney = min(evaluation.Tier.Value, evaluation.ScopeAmount)
+		}
+	case enum.DiscountTypeBuyN:
+		evaluation.Tier, _ = lo.Find(campaign.Tiers, func(tier *do.DiscountTier) bool {
+			return evaluation.ScopeNum >= tier.Threshold
+		})
+		if evaluation.Tier != nil {
+			evaluation.DiscountMoney = evaluation.ScopeAmount * evaluation.Tier.Value / 100
+		}
+	case enum.DiscountTypeBundle:
+		tier := campaign.Tiers[0]
+		if evaluation.ScopeNum < tier.Threshold {
+			break
+		}
+		evaluation.Tier = tier
+		evaluation.DiscountMoney = max(bundleDiscount(scopeItems, tier), 0)
+	}
+	return evaluation
+}
+
+// bundleDiscount 组合一口价的优惠金额, 从单价最高的商品开始组合, 每满门槛件数按组合价格计价
+func bundleDiscount(items []*do.ShoppingCartItem, tier *do.DiscountTier) int {
+	prices := make([]int, 0)
+	for _, item := range items {
+		for i := 0; i < item.CommodityNum; i++ {
+			prices = append(prices, item.CommoditySellingPrice)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(prices)))
+	bundles := len(prices) / tier.Threshold
+	return lo.Sum(prices[:bundles*tier.Threshold]) - bundles*tier.Value
+}
+
+func checkDiscountCampaign(campaign *do.DiscountCampaign) error {
+	if !campaign.EndTime.After(campaign.StartTime) {
+		return errcode.ErrParams.WithCause(fmt.Errorf("活动结束时间需要晚于开始时间"))
+	}
+	if campaign.ScopeType != enum.PromotionScopeAll && len(campaign.ScopeIds) == 0 {
+		return errcode.ErrParams.WithCause(fmt.Errorf("指定范围的活动需要设置分类或商品"))
+	}
+	if len(campaign.Tiers) == 0 {
+		return errcode.ErrParams.WithCause(fmt.Errorf("活动需要设置门槛和优惠"))
+	}
+	for _, tier := range campaign.Tiers {
+		if tier.Threshold <= 0 || tier.Value <= 0 {
+			return errcode.ErrParams.WithCause(fmt.Errorf("活动的门槛和优惠需要大于0"))
+		}
+		if campaign.Type == enum.DiscountTypeFullReduction && tier.Value >= tier.Threshold {
+			return errcode.ErrParams.WithCause(fmt.Errorf("满减金额需要小于门槛金额"))
+		}
+		if campaign.Type == enum.DiscountTypeBuyN && tier.Value >= 100 {
+			return errcode.ErrParams.WithCause(fmt.Errorf("满件折扣减免的百分比需要小于100"))
+		}
+	}
+	if campaign.Type == enum.DiscountTypeBundle && (len(campaign.Tiers) != 1 || campaign.Tiers[0].Threshold < 2) {
+		return errcode.ErrParams.WithCause(fmt.Errorf("组合一口价只能设置一档, 且组合件数至少为2"))
+	}
+	return nil
+}
+
+func discountCampaignsFromModels(campaignModels []*model.DiscountCampaign) ([]*do.DiscountCampaign, error) {
+	campaigns := make([]*do.DiscountCampaign, 0, len(campaignModels))
+	for _, campaignModel := range campaignModels {
+		tiers := make([]*do.DiscountTier, 0)
+		if err := json.Unmarshal([]byte(campaignModel.Tiers), &tiers); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i].Threshold > tiers[j].Threshold })
+		campaigns = append(campaigns, &do.DiscountCampaign{
+			ID:        campaignModel.ID,
+			Name:      campaignModel.Name,
+			Type:      campaignModel.Type,
+			Tiers:     tiers,
+			ScopeType: campaignModel.ScopeType,
+			ScopeIds:  parseScopeIds(campaignModel.ScopeIds),
+			Priority:  campaignModel.Priority,
+			StartTime: campaignModel.StartTime,
+			EndTime:   campaignModel.EndTime,
+			Status:    campaignModel.Status,
+			CreatedAt: campaignModel.CreatedAt,
+		})
+	}
+	return campaigns, nil
+}
+
+func discountCampaignToModel(campaign *do.DiscountCampaign) (*model.DiscountCampaign, error) {
+	tiers, err := json.Marshal(campaign.Tiers)
+	if err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return &model.DiscountCampaign{
+		Name:      campaign.Name,
+		Type:      campaign.Type,
+		Tiers:     string(tiers),
+		ScopeType: campaign.ScopeType,
+		ScopeIds:  joinScopeIds(campaign.ScopeIds),
+		Priority:  campaign.Priority,
+		StartTime: campaign.StartTime,
+		EndTime:   campaign.EndTime,
+	}, nil
+}
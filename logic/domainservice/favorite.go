@@ -0,0 +1,142 @@
+package domainservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"time"
+)
+
+type FavoriteDomainSvc struct {
+	ctx         context.Context
+	favoriteDao *dao.FavoriteDao
+}
+
+func NewFavoriteDomainSvc(ctx context.Context) *FavoriteDomainSvc {
+	return &FavoriteDomainSvc{
+		ctx:         ctx,
+		favoriteDao: dao.NewFavoriteDao(ctx),
+	}
+}
+
+// AddFavorite 收藏在售的商品, 以当前售价作为降价提醒的参考价, 重复收藏直接成功
+func (fds *FavoriteDomainSvc) AddFavorite(userId, commodityId int64) error {
+	commodity := NewCommodityDomainSvc(fds.ctx).GetCommodityInfo(commodityId)
+	if commodity == nil {
+		return errcode.ErrCommodityNotExists
+	}
+	if commodity.SellStatus != enum.CommoditySellStatusOn {
+		return errcode.ErrCommodityOffShelf
+	}
+	err := fds.favoriteDao.AddFavorite(&model.UserFavorite{
+		UserId:         userId,
+		CommodityId:    commodityId,
+		FavoritedPrice: commodity.SellingPrice,
+	})
+	if err != nil {
+		return errcode.Wrap("AddFavoriteError", err)
+	}
+	return nil
+}
+
+// RemoveFavorite 取消收藏, 没有收藏过的商品返回 ErrFavoriteNotExists
+func (fds *FavoriteDomainSvc) RemoveFavorite(userId, commodityId int64) error {
+	deleted, err := fds.favoriteDao.DeleteFavorite(userId, commodityId)
+	if err != nil {
+		return errcode.Wrap("RemoveFavoriteError", err)
+	}
+	if deleted == 0 {
+		return errcode.ErrFavoriteNotExists
+	}
+	return nil
+}
+
+// CheckFavorite 确认用户收藏了商品
+func (fds *FavoriteDomainSvc) CheckFavorite(userId, commodityId int64) error {
+	favorite, err := fds.favoriteDao.GetUserFavorite(userId, commodityId)
+	if err != nil {
+		return errcode.Wrap("CheckFavoriteError", err)
+	}
+	if favorite.ID == 0 {
+		return errcode.ErrFavoriteNotExists
+	}
+	return nil
+}
+
+// GetUserFavorites 用户的收藏列表, 带上商品当前的售价、库存和上架状态, 已删除的商品也会保留在列表中
+func (fds *FavoriteDomainSvc) GetUserFavorites(userId int64, pagination *app.Pagination) ([]*do.UserFavorite, error) {
+	favoriteModels, totalRows, err := fds.favoriteDao.GetUserFavorites(userId, pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("GetUserFavoritesError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+
+	commodityIds := lo.Map(favoriteModels, func(favorite *model.UserFavorite, _ int) int64 { return favorite.CommodityId })
+	commodityModels, err := dao.NewCommodityDao(fds.ctx).FindCommoditiesUnscoped(commodityIds)
+	if err != nil {
+		return nil, errcode.Wrap("GetUserFavoritesError", err)
+	}
+	commodityMap := lo.SliceToMap(commodityModels, func(commodity *model.Commodity) (int64, *model.Commodity) {
+		return commodity.ID, commodity
+	})
+
+	favorites := make([]*do.UserFavorite, 0, len(favoriteModels))
+	for _, favoriteModel := range favoriteModels {
+		favorite := new(do.UserFavorite)
+		if err = util.CopyProperties(favorite, favoriteModel); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		commodityModel, ok := commodityMap[favoriteModel.CommodityId]
+		if !ok {
+			// 商品数据被物理删除时不展示
+			continue
+		}
+		favorite.Commodity = new(do.Commodity)
+		if err = util.CopyProperties(favorite.Commodity, commodityModel); err != nil {
+			return nil, errcode.ErrCoverData.WithCause(err)
+		}
+		favorites = append(favorites, favorite)
+	}
+	return favorites, nil
+}
+
+// NotifyPriceDrop 商品售价下降后, 给参考价高于新售价的收藏用户发出降价事件, 并把参考价更新为新售价,
+// 同一次降价只提醒一次
+func (fds *FavoriteDomainSvc) NotifyPriceDrop(commodity *do.Commodity) error {
+	droppedAt := time.Now()
+	var afterId int64
+	for {
+		favorites, err := fds.favoriteDao.FindFavoritesPricedAbove(commodity.ID, commodity.SellingPrice, afterId, enum.FavoritePriceDropBatchSize)
+		if err != nil {
+			return errcode.Wrap("NotifyPriceDropError", err)
+		}
+		if len(favorites) == 0 {
+			return nil
+		}
+		events := lo.Map(favorites, func(favorite *model.UserFavorite, _ int) *do.FavoritePriceDrop {
+			return &do.FavoritePriceDrop{
+				UserId:        favorite.UserId,
+				CommodityId:   commodity.ID,
+				CommodityName: commodity.Name,
+				OldPrice:      favorite.FavoritedPrice,
+				NewPrice:      commodity.SellingPrice,
+				DroppedAt:     droppedAt,
+			}
+		})
+		if err = cache.PushFavoritePriceDrops(fds.ctx, events); err != nil {
+			return errcode.Wrap("NotifyPriceDropError", err)
+		}
+		favoriteIds := lo.Map(favorites, func(favorite *model.UserFavorite, _ int) int64 { return favorite.ID })
+		if err = fds.favoriteDao.UpdateFavoritedPrice(favoriteIds, commodity.SellingPrice); err != nil {
+			return errcode.Wrap("NotifyPriceDropError", err)
+		}
+		afterId = favoriteIds[len(favoriteIds)-1]
+	}
+}
@@ -2,16 +2,20 @@ package domainservice
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Ian-zy0329/go-mall/common/app"
 	"github.com/Ian-zy0329/go-mall/common/enum"
 	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
 	"github.com/Ian-zy0329/go-mall/dal/dao"
 	"github.com/Ian-zy0329/go-mall/dal/model"
 	"github.com/Ian-zy0329/go-mall/library"
 	"github.com/Ian-zy0329/go-mall/logic/do"
 	"github.com/samber/lo"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -27,8 +31,18 @@ func NewOrderDomainSvc(ctx context.Context) *OrderDomainSvc {
 	}
 }
 
-func (ods *OrderDomainSvc) CreateOrder(items []*do.ShoppingCartItem, userAddressInfo *do.UserAddressInfo) (*do.Order, error) {
-	billInfo, err := NewCartBillChecker(items, userAddressInfo.UserId).GetBill()
+// CreateOrder 创建订单, userCouponId 为用户选择的优惠券, 为 0 时自动使用优惠金额最高的优惠券, 使用的优惠券被订单锁定;
+// usePoints 为用户要使用的积分, 实际抵扣的积分在创建订单的事务中扣减
+func (ods *OrderDomainSvc) CreateOrder(items []*do.ShoppingCartItem, userAddressInfo *do.UserAddressInfo, userCouponId int64, usePoints int) (*do.Order, error) {
+	// 下单前重新检查每个购物项是否可售, 商品可能在加购或结算之后被下架、删除
+	skuIds := lo.Map(items, func(item *do.ShoppingCartItem, _ int) int64 { return item.SkuId })
+	if _, err := NewCommodityDomainSvc(ods.ctx).CheckSkusSellable(skuIds); err != nil {
+		return nil, err
+	}
+	if err := checkOrderLimits(ods.ctx, userAddressInfo.UserId, items); err != nil {
+		return nil, err
+	}
+	billInfo, err := NewCartBillChecker(ods.ctx, items, userAddressInfo.UserId).UseCoupon(userCouponId).UsePoints(usePoints).GetBill()
 	if err != nil {
 		return nil, errcode.Wrap("CreateOrderError", err)
 	}
@@ -48,40 +62,103 @@ func (ods *OrderDomainSvc) CreateOrder(items []*do.ShoppingCartItem, userAddress
 		return nil, errcode.ErrCoverData.WithCause(err)
 	}
 
-	tx := dao.DBMaster().Begin()
-	panicked := true
-	defer func() {
-		if err != nil && panicked {
-			tx.Rollback()
-		} else {
-			tx.Commit()
+	// 库存在Redis中预占, 支付成功后确认, 超时未支付由定时任务释放
+	// 预占放在事务的最后一步, 预占失败时回滚订单数据; 事务提交失败时释放已经预占的库存
+	reserved := false
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		if err := ods.orderDao.CreateOrder(tx, order); err != nil {
+			return err
 		}
-	}()
-	err = ods.orderDao.CreateOrder(tx, order)
+		if billInfo.Coupon.CouponId > 0 {
+			err := NewCouponDomainSvc(ods.ctx).LockOrderCouponInTx(tx, billInfo.Coupon.CouponId, order.UserId, order.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if billInfo.Points.Points > 0 {
+			err := NewPointsDomainSvc(ods.ctx).SpendOrderPointsInTx(tx, order.UserId, order.ID, billInfo.Points.Points)
+			if err != nil {
+				return err
+			}
+		}
+		if err := NewStockDomainSvc(ods.ctx).ReserveOrderStock(order); err != nil {
+			return err
+		}
+		reserved = true
+		return nil
+	})
 	if err != nil {
+		if reserved {
+			ods.releaseUncommittedOrderStock(order.OrderNo)
+		}
 		return nil, err
 	}
-	cartDao := dao.NewCartDao(ods.ctx)
-	cartItems := lo.Map(items, func(item *do.ShoppingCartItem, index int) int64 {
-		return item.CartItemId
-	})
-	err = cartDao.DeleteMultiCartItemInTx(tx, cartItems)
+	return order, nil
+}
+
+// releaseUncommittedOrderStock 订单事务提交失败时释放已经预占的库存, 释放失败时预占到期后由定时任务释放
+func (ods *OrderDomainSvc) releaseUncommittedOrderStock(orderNo string) {
+	if _, err := NewStockDomainSvc(ods.ctx).ReleaseOrderStock(orderNo); err != nil {
+		logger.New(ods.ctx).Error("ReleaseUncommittedOrderStockError", "orderNo", orderNo, "err", err)
+	}
+}
+
+// CreateSeckillOrder 按秒杀价创建订单并预占商品库存, 秒杀订单不经过购物车, 支付和超时关闭与普通订单一致
+func (ods *OrderDomainSvc) CreateSeckillOrder(campaign *do.SeckillCampaign, request *do.SeckillRequest, userAddressInfo *do.UserAddressInfo) (*do.Order, error) {
+	num := request.Num
+	sellInfos, err := NewCommodityDomainSvc(ods.ctx).CheckSkusSellable([]int64{campaign.SkuId})
 	if err != nil {
 		return nil, err
 	}
-	if billInfo.Coupon.CouponId > 0 {
-		//couponDao.LockCoupon(tx,coupon)
-	}
-	if billInfo.Discount.DiscountId > 0 {
-		//discountDao.recordDiscount(tx,discount)
+	sku, commodity := sellInfos[campaign.SkuId].Sku, sellInfos[campaign.SkuId].Commodity
+	order := do.OrderNew()
+	order.UserId = userAddressInfo.UserId
+	order.OrderNo = util.GenOrderNo(order.UserId)
+	order.BillMoney = sku.SellingPrice * num
+	order.PayMoney = campaign.SeckillPrice * num
+	order.OrderStatus = enum.OrderStatusCreated
+	order.Items = []*do.OrderItem{{
+		CommodityId:           commodity.ID,
+		SkuId:                 sku.ID,
+		CommodityName:         commodity.Name,
+		SkuSpecDesc:           sku.SpecDesc,
+		CommodityImg:          lo.Ternary(sku.CoverImg != "", sku.CoverImg, commodity.CoverImg),
+		CommoditySellingPrice: campaign.SeckillPrice,
+		CommodityNum:          num,
+	}}
+	if err = util.CopyProperties(&order.Address, &userAddressInfo); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
 	}
-	commodityDao := dao.NewCommodityDao(ods.ctx)
-	//err = commodityDao.ReduceStuckInOrderCreate(tx, order.Items)
-	err = commodityDao.ReduceStuckInOrderCreateByLua(tx, order.Items, order.UserId)
+
+	reserved := false
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		if err := ods.orderDao.CreateOrder(tx, order); err != nil {
+			return err
+		}
+		seckillOrder := &model.SeckillOrder{
+			CampaignId: campaign.ID,
+			RequestId:  request.RequestId,
+			OrderId:    order.ID,
+			OrderNo:    order.OrderNo,
+			UserId:     order.UserId,
+			Num:        num,
+		}
+		if err := dao.NewSeckillDao(ods.ctx).CreateSeckillOrderInTx(tx, seckillOrder); err != nil {
+			return err
+		}
+		// 预占失败时回滚订单数据
+		if err := NewStockDomainSvc(ods.ctx).ReserveOrderStock(order); err != nil {
+			return err
+		}
+		reserved = true
+		return nil
+	})
 	if err != nil {
+		if reserved {
+			ods.releaseUncommittedOrderStock(order.OrderNo)
+		}
 		return nil, err
 	}
-	panicked = false
 	return order, nil
 }
 
@@ -161,16 +238,270 @@ func (ods *OrderDomainSvc) CancelUserOrder(orderNo string, userId int64) error {
 	if order.OrderStatus >= enum.OrderStatusPaid {
 		return errcode.ErrOrderCanNotBeChanged
 	}
-
-	err = ods.orderDao.UpdateOrderStatus(order.ID, enum.OrderStatusUserQuit)
+	// 只关闭仍未支付的订单, 与支付结果回调并发时以先更新订单状态的为准
+	affected, err := ods.orderDao.CloseUnpaidOrder(order.ID, enum.OrderStatusUserQuit)
 	if err != nil {
 		return errcode.Wrap("CancelOrderError", err)
 	}
-	commodityDao := dao.NewCommodityDao(ods.ctx)
-	err = commodityDao.RecoverOrderCommodityStuck(order.Items)
+	if affected == 0 {
+		return errcode.ErrOrderCanNotBeChanged
+	}
+	if err = NewCouponDomainSvc(ods.ctx).ReleaseOrderCoupon(order.ID); err != nil {
+		return err
+	}
+	if err = NewPointsDomainSvc(ods.ctx).ReturnOrderPoints(order.ID); err != nil {
+		return err
+	}
+	if err = NewSeckillDomainSvc(ods.ctx).RevertOrderQuota(order.ID); err != nil {
+		return err
+	}
+	_, err = NewStockDomainSvc(ods.ctx).ReleaseOrderStock(order.OrderNo)
 	return err
 }
 
+// ConfirmReceipt 买家确认收货, 只有已发货的订单可以确认收货, 确认后订单等待评价, 评价完成后订单才获得成长值和积分
+func (ods *OrderDomainSvc) ConfirmReceipt(orderNo string, userId int64) error {
+	order, err := ods.GetSpecifiedUserOrder(orderNo, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := ods.orderDao.ConfirmOrderReceipt(order.ID)
+	if err != nil {
+		return errcode.Wrap("ConfirmReceiptError", err)
+	}
+	if affected == 0 {
+		return errcode.ErrOrderCanNotBeChanged
+	}
+	return nil
+}
+
+// SettleOrderPaid 订单支付成功后结算: 确认订单的库存预占, 扣减MySQL库存并回填支付信息, 发放订单中购买的会员
+// 支付已经完成不能拒绝, 订单已关闭或库存已释放且重新预占不足时, 回填支付信息并标记为待退款, 由商家关闭订单退款
+func (ods *OrderDomainSvc) SettleOrderPaid(orderNo, payTransId string, paidAt time.Time) error {
+	orderModel, err := ods.orderDao.GetOrderByNo(orderNo)
+	if err != nil {
+		return errcode.Wrap("SettleOrderPaidError", err)
+	}
+	if orderModel.ID == 0 {
+		return errcode.ErrOrderParams
+	}
+	if orderModel.PayState == enum.PayStatePaid {
+		// 支付平台会重复通知, 已结算的订单直接返回
+		return nil
+	}
+	orderModel.PayTransId = payTransId
+	orderModel.PaidAt = paidAt
+	if orderModel.OrderStatus >= enum.OrderStatusPaid {
+		// 未支付但状态已经越过待支付, 说明订单在支付过程中被取消或超时关闭
+		return ods.settleOrderPaidToRefund(orderModel, "OrderClosed")
+	}
+	orderItems, err := ods.orderDao.GetOrderItems(orderModel.ID)
+	if err != nil {
+		return errcode.Wrap("SettleOrderPaidError", err)
+	}
+	items := make([]*do.OrderItem, 0, len(orderItems))
+	if err = util.CopyProperties(&items, &orderItems); err != nil {
+		return errcode.ErrCoverData.WithCause(err)
+	}
+	// 先在Redis中确认预占, 与超时释放互斥, 确认成功后预占不会再被释放; 重复确认会直接成功,
+	// 下面写库失败时支付平台重试通知可以继续完成结算
+	stockDomainSvc := NewStockDomainSvc(ods.ctx)
+	err = stockDomainSvc.ConfirmOrderStock(orderNo)
+	if errors.Is(err, cache.ErrStockReservationNotFound) {
+		// 预占已经超时释放而订单还未关闭, 按原订单重新预占库存再确认
+		order := &do.Order{OrderNo: orderModel.OrderNo, UserId: orderModel.UserId, Items: items}
+		err = stockDomainSvc.ReconfirmOrderStock(order)
+		if errors.Is(err, errcode.ErrCommodityStockOut) {
+			return ods.settleOrderPaidToRefund(orderModel, "StockOut")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	orderModel.PayState = enum.PayStatePaid
+	orderModel.OrderStatus = enum.OrderStatusPaid
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		affected, err := ods.orderDao.UpdateOrderPaidInTx(tx, orderModel)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errcode.ErrOrderCanNotBeChanged
+		}
+		if err = NewCouponDomainSvc(ods.ctx).UseOrderCouponInTx(tx, orderModel.ID, paidAt); err != nil {
+			return err
+		}
+		if err = NewVipDomainSvc(ods.ctx).GrantPurchasedMembershipInTx(tx, orderModel.UserId, orderModel.ID, items); err != nil {
+			return err
+		}
+		return dao.NewCommodityDao(ods.ctx).ReduceSkuStockInTx(tx, items)
+	})
+	if errors.Is(err, errcode.ErrOrderCanNotBeChanged) {
+		// 确认预占后订单被并发关闭, Redis中已确认的库存由库存对账修正
+		return ods.settleOrderPaidToRefund(orderModel, "OrderClosed")
+	}
+	if err != nil {
+		logger.New(ods.ctx).Error("SettleOrderPaidDBError", "orderNo", orderNo, "err", err)
+		return errcode.Wrap("SettleOrderPaidError", err)
+	}
+	return nil
+}
+
+// settleOrderPaidToRefund 订单无法继续履约, 回填支付信息并标记为待退款, 不扣减库存也不核销优惠券,
+// 订单锁定的优惠券、抵扣的积分和秒杀名额在标记后立即退回
+func (ods *OrderDomainSvc) settleOrderPaidToRefund(orderModel *model.Order, reason string) error {
+	affected, err := ods.orderDao.UpdateOrderPaidToRefund(orderModel)
+	if err != nil {
+		return errcode.Wrap("SettleOrderPaidError", err)
+	}
+	log := logger.New(ods.ctx)
+	log.Warn("OrderPaidToRefund", "orderNo", orderModel.OrderNo, "reason", reason, "payTransId", orderModel.PayTransId)
+	if affected == 0 {
+		return nil
+	}
+	// 支付结果已经记录, 退回失败只记录日志, 商家关闭订单退款时会再次退回
+	if err = ods.refundOrderBenefits(orderModel.ID); err != nil {
+		log.Error("RefundOrderBenefitsError", "orderNo", orderModel.OrderNo, "err", err)
+	}
+	return nil
+}
+
+// ReleaseExpiredStockReservations 释放超时未支付订单的库存预占并关闭订单
+func (ods *OrderDomainSvc) ReleaseExpiredStockReservations() error {
+	stockDomainSvc := NewStockDomainSvc(ods.ctx)
+	orderNos, err := stockDomainSvc.GetExpiredReservationOrderNos(100)
+	if err != nil {
+		return err
+	}
+	log := logger.New(ods.ctx)
+	for _, orderNo := range orderNos {
+		released, err := stockDomainSvc.ReleaseOrderStock(orderNo)
+		if err != nil {
+			log.Error("ReleaseExpiredStockReservationError", "orderNo", orderNo, "err", err)
+			continue
+		}
+		if !released {
+			continue
+		}
+		orderModel, err := ods.orderDao.GetOrderByNo(orderNo)
+		if err != nil || orderModel.ID == 0 {
+			log.Warn("ReleaseExpiredStockOrderNotFound", "orderNo", orderNo, "err", err)
+			continue
+		}
+		// 订单已经支付或关闭时不再退回优惠券、积分和秒杀名额, 已支付订单的库存由支付结果处理重新确认
+		closed, err := ods.orderDao.CloseUnpaidOrder(orderModel.ID, enum.OrderStatusUnpaidClose)
+		if err != nil {
+			log.Error("CloseUnpaidOrderError", "orderNo", orderNo, "err", err)
+			continue
+		}
+		if closed == 0 {
+			continue
+		}
+		if err = NewCouponDomainSvc(ods.ctx).ReleaseOrderCoupon(orderModel.ID); err != nil {
+			log.Error("ReleaseExpiredOrderCouponError", "orderNo", orderNo, "err", err)
+		}
+		if err = NewPointsDomainSvc(ods.ctx).ReturnOrderPoints(orderModel.ID); err != nil {
+			log.Error("ReturnExpiredOrderPointsError", "orderNo", orderNo, "err", err)
+		}
+		if err = NewSeckillDomainSvc(ods.ctx).RevertOrderQuota(orderModel.ID); err != nil {
+			log.Error("RevertExpiredSeckillQuotaError", "orderNo", orderNo, "err", err)
+		}
+	}
+	return nil
+}
+
+// MerchantCloseOrder 商家关闭订单, 已关闭的订单不能再关闭:
+// 未支付的订单释放库存预占; 已支付还未出库的订单把库存加回Redis和MySQL; 已出库或待退款的订单只关闭.
+// 已支付的订单由商家在支付平台退款, 关闭后退回订单锁定或使用的优惠券和抵扣的积分, 收回订单完成时获得的积分和成长值,
+// 扣回订单购买的会员, 秒杀订单退回名额
+func (ods *OrderDomainSvc) MerchantCloseOrder(orderNo string) error {
+	orderModel, err := ods.orderDao.GetOrderByNo(orderNo)
+	if err != nil {
+		return errcode.Wrap("MerchantCloseOrderError", err)
+	}
+	if orderModel.ID == 0 {
+		return errcode.ErrOrderParams
+	}
+	fromStatus := orderModel.OrderStatus
+	if lo.Contains([]int{enum.OrderStatusUserQuit, enum.OrderStatusUnpaidClose, enum.OrderStatusMerchantClose}, fromStatus) {
+		return errcode.ErrOrderCanNotBeChanged
+	}
+	closeOrder := func(tx *gorm.DB) error {
+		affected, err := ods.orderDao.CloseOrderInTx(tx, orderModel.ID, fromStatus, enum.OrderStatusMerchantClose)
+		if err != nil {
+			return errcode.Wrap("MerchantCloseOrderError", err)
+		}
+		if affected == 0 {
+			return errcode.ErrOrderCanNotBeChanged
+		}
+		return nil
+	}
+	stockDomainSvc := NewStockDomainSvc(ods.ctx)
+	switch {
+	case orderModel.PayState != enum.PayStatePaid:
+		// 先关闭订单再释放预占, 关闭后才到达的支付结果会把订单标记为待退款
+		if err = closeOrder(dao.DBMaster()); err != nil {
+			return err
+		}
+		if _, err = stockDomainSvc.ReleaseOrderStock(orderNo); err != nil {
+			return err
+		}
+	case fromStatus == enum.OrderStatusPaid || fromStatus == enum.OrderStatusChecked:
+		orderItems, err := ods.orderDao.GetOrderItems(orderModel.ID)
+		if err != nil {
+			return errcode.Wrap("MerchantCloseOrderError", err)
+		}
+		items := make([]*do.OrderItem, 0, len(orderItems))
+		if err = util.CopyProperties(&items, &orderItems); err != nil {
+			return errcode.ErrCoverData.WithCause(err)
+		}
+		stockDeltas := make(map[int64]int, len(items))
+		for _, item := range items {
+			stockDeltas[item.SkuId] += item.CommodityNum
+		}
+		err = stockDomainSvc.AdjustSkuStock(stockDeltas, func() error {
+			return dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+				if err := closeOrder(tx); err != nil {
+					return err
+				}
+				return dao.NewCommodityDao(ods.ctx).RestoreSkuStockInTx(tx, items)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		if err = closeOrder(dao.DBMaster()); err != nil {
+			return err
+		}
+	}
+	return ods.refundOrderBenefits(orderModel.ID)
+}
+
+// refundOrderBenefits 订单退款时退回订单锁定或使用的优惠券和抵扣的积分, 收回订单完成时获得的积分和成长值,
+// 扣回订单购买的会员, 秒杀订单退回名额; 每一项都只处理一次, 重复调用不会重复退回或收回
+func (ods *OrderDomainSvc) refundOrderBenefits(orderId int64) error {
+	if err := NewCouponDomainSvc(ods.ctx).ReleaseOrderCoupon(orderId); err != nil {
+		return err
+	}
+	pointsDomainSvc := NewPointsDomainSvc(ods.ctx)
+	if err := pointsDomainSvc.ReturnOrderPoints(orderId); err != nil {
+		return err
+	}
+	if err := pointsDomainSvc.RevokeOrderPoints(orderId); err != nil {
+		return err
+	}
+	vipDomainSvc := NewVipDomainSvc(ods.ctx)
+	if err := vipDomainSvc.RevokeOrderGrowth(orderId); err != nil {
+		return err
+	}
+	if err := vipDomainSvc.RevokePurchasedMembership(orderId); err != nil {
+		return err
+	}
+	return NewSeckillDomainSvc(ods.ctx).RevertOrderQuota(orderId)
+}
+
 func (ods *OrderDomainSvc) CreateOrderWxPay(orderNo string, userId int64) (payInfo *library.WxPayInvokeInfo, err error) {
 	order, err := ods.GetSpecifiedUserOrder(orderNo, userId)
 	if err != nil {
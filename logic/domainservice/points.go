@@ -0,0 +1,348 @@
+package domainservice
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// 积分规则:
+//   - 订单完成(确认收货并全部评价)后按支付金额获得积分, 每 points.earn_unit 分1积分, 积分在 points.expire_days 天后过期
+//   - 结算时可以使用积分抵扣, 每积分抵扣 points.redeem_value 分, 最多抵扣商品金额的 points.max_redeem_rate%
+//   - 下单时在创建订单的事务中扣减积分, 订单取消或超时关闭后退回, 退回的积分沿用被扣减积分中最晚的过期时间
+//   - 订单退款后收回订单获得的积分, 余额不足时只收回剩余的部分
+//   - 使用、过期和收回积分都按过期时间从早到晚扣减每笔获得的积分, 过期的积分由定时任务处理
+
+type PointsDomainSvc struct {
+	ctx       context.Context
+	pointsDao *dao.PointsDao
+}
+
+func NewPointsDomainSvc(ctx context.Context) *PointsDomainSvc {
+	return &PointsDomainSvc{
+		ctx:       ctx,
+		pointsDao: dao.NewPointsDao(ctx),
+	}
+}
+
+// GetUserBalance 用户可用的积分
+func (pds *PointsDomainSvc) GetUserBalance(userId int64) (int, error) {
+	account, err := pds.pointsDao.GetAccount(userId)
+	if err != nil {
+		return 0, errcode.Wrap("GetUserPointsBalanceError", err)
+	}
+	return account.Balance, nil
+}
+
+// GetUserEntries 用户的积分流水, 最近的在前
+func (pds *PointsDomainSvc) GetUserEntries(userId int64, pagination *app.Pagination) ([]*do.PointsEntry, error) {
+	entryModels, totalRows, err := pds.pointsDao.GetUserEntries(userId, pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("GetUserPointsEntriesError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+	return lo.Map(entryModels, func(entryModel *model.UserPointsEntry, _ int) *do.PointsEntry {
+		return pointsEntryFromModel(entryModel)
+	}), nil
+}
+
+// EarnOrderPointsInTx 在完成订单的事务中按支付金额给用户增加积分, 每个订单只增加一次
+func (pds *PointsDomainSvc) EarnOrderPointsInTx(tx *gorm.DB, order *do.Order) error {
+	points := order.PayMoney / pointsEarnUnit()
+	if points <= 0 {
+		return nil
+	}
+	added, err := pds.pointsDao.AddEntryInTx(tx, &model.UserPointsEntry{
+		UserId:    order.UserId,
+		Type:      enum.PointsEntryEarn,
+		Points:    points,
+		Remaining: points,
+		ExpireAt:  time.Now().AddDate(0, 0, pointsExpireDays()),
+		OrderId:   order.ID,
+		BizKey:    orderPointsBizKey("earn", order.ID),
+		Remark:    "订单完成获得积分: " + order.OrderNo,
+	})
+	if err != nil || !added {
+		return err
+	}
+	return pds.pointsDao.IncrBalanceInTx(tx, order.UserId, points)
+}
+
+// SpendOrderPointsInTx 在创建订单的事务中扣减订单使用的积分, 余额不足时返回 ErrPointsInsufficient
+func (pds *PointsDomainSvc) SpendOrderPointsInTx(tx *gorm.DB, userId, orderId int64, points int) error {
+	affected, err := pds.pointsDao.DecrBalanceInTx(tx, userId, points)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errcode.ErrPointsInsufficient
+	}
+	latestExpireAt, err := pds.consumeInTx(tx, userId, points)
+	if err != nil {
+		return err
+	}
+	if latestExpireAt.IsZero() {
+		// 余额中没有对应的剩余积分可以扣减, 退回时按新获得的积分计算过期时间
+		latestExpireAt = time.Now().AddDate(0, 0, pointsExpireDays())
+	}
+	// 记录被扣减积分中最晚的过期时间, 订单取消退回积分时使用
+	_, err = pds.pointsDao.AddEntryInTx(tx, &model.UserPointsEntry{
+		UserId:   userId,
+		Type:     enum.PointsEntrySpend,
+		Points:   -points,
+		ExpireAt: latestExpireAt,
+		OrderId:  orderId,
+		BizKey:   orderPointsBizKey("spend", orderId),
+		Remark:   "下单使用积分抵扣",
+	})
+	return err
+}
+
+// ReturnOrderPoints 订单取消、超时关闭或退款后退回订单使用的积分, 订单没有使用积分或已经退回过时不处理
+func (pds *PointsDomainSvc) ReturnOrderPoints(orderId int64) error {
+	err := dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		spent, err := pds.pointsDao.GetEntryByBizKeyInTx(tx, orderPointsBizKey("spend", orderId))
+		if err != nil || spent.ID == 0 {
+			return err
+		}
+		added, err := pds.pointsDao.AddEntryInTx(tx, &model.UserPointsEntry{
+			UserId:    spent.UserId,
+			Type:      enum.PointsEntryAdjust,
+			Points:    -spent.Points,
+			Remaining: -spent.Points,
+			ExpireAt:  returnedPointsExpireAt(spent.ExpireAt, time.Now()),
+			OrderId:   orderId,
+			BizKey:    orderPointsBizKey("return", orderId),
+			Remark:    "订单关闭退回抵扣的积分",
+		})
+		if err != nil || !added {
+			return err
+		}
+		return pds.pointsDao.IncrBalanceInTx(tx, spent.UserId, -spent.Points)
+	})
+	if err != nil {
+		return errcode.Wrap("ReturnOrderPointsError", err)
+	}
+	return nil
+}
+
+// RevokeOrderPoints 已完成的订单退款后收回订单获得的积分, 获得的积分已经被使用时只收回剩余的余额,
+// 订单没有获得积分或已经收回过时不处理
+func (pds *PointsDomainSvc) RevokeOrderPoints(orderId int64) error {
+	err := dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		earned, err := pds.pointsDao.GetEntryByBizKeyInTx(tx, orderPointsBizKey("earn", orderId))
+		if err != nil || earned.ID == 0 {
+			return err
+		}
+		account, err := pds.pointsDao.LockAccountInTx(tx, earned.UserId)
+		if err != nil {
+			return err
+		}
+		points := min(earned.Points, account.Balance)
+		added, err := pds.pointsDao.AddEntryInTx(tx, &model.UserPointsEntry{
+			UserId:   earned.UserId,
+			Type:     enum.PointsEntryAdjust,
+			Points:   -points,
+			ExpireAt: time.Unix(0, 0),
+			OrderId:  orderId,
+			BizKey:   orderPointsBizKey("revoke", orderId),
+			Remark:   "订单退款收回获得的积分",
+		})
+		if err != nil || !added || points <= 0 {
+			return err
+		}
+		if _, err = pds.consumeInTx(tx, earned.UserId, points); err != nil {
+			return err
+		}
+		_, err = pds.pointsDao.DecrBalanceInTx(tx, earned.UserId, points)
+		return err
+	})
+	if err != nil {
+		return errcode.Wrap("RevokeOrderPointsError", err)
+	}
+	return nil
+}
+
+// ExpirePoints 处理所有已过期的积分, 返回过期的积分数量
+func (pds *PointsDomainSvc) ExpirePoints() (int, error) {
+	now := time.Now()
+	expired := 0
+	var afterId int64
+	for {
+		entries, err := pds.pointsDao.GetExpiredEntriesAfter(afterId, now, enum.PointsExpireBatchSize)
+		if err != nil {
+			return expired, errcode.Wrap("ExpirePointsError", err)
+		}
+		if len(entries) == 0 {
+			return expired, nil
+		}
+		for _, entry := range entries {
+			points, err := pds.expireEntry(entry)
+			if err != nil {
+				return expired, errcode.Wrap("ExpirePointsError", err)
+			}
+			expired += points
+		}
+		afterId = entries[len(entries)-1].ID
+	}
+}
+
+// expireEntry 把一笔获得的积分中剩余的部分标记为过期并扣减余额, 返回过期的积分数量.
+// 与下单使用积分一样先锁积分账户再锁流水
+func (pds *PointsDomainSvc) expireEntry(expiredEntry *model.UserPointsEntry) (int, error) {
+	expired := 0
+	err := dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		account, err := pds.pointsDao.LockAccountInTx(tx, expiredEntry.UserId)
+		if err != nil {
+			return err
+		}
+		entry, err := pds.pointsDao.LockEntryInTx(tx, expiredEntry.ID)
+		// 读取之后积分已经被使用
+		if err != nil || entry.Remaining <= 0 {
+			return err
+		}
+		if err = pds.pointsDao.DecrEntryRemainingInTx(tx, entry.ID, entry.Remaining); err != nil {
+			return err
+		}
+		points := min(entry.Remaining, account.Balance)
+		_, err = pds.pointsDao.AddEntryInTx(tx, &model.UserPointsEntry{
+			UserId:   entry.UserId,
+			Type:     enum.PointsEntryExpire,
+			Points:   -points,
+			ExpireAt: time.Unix(0, 0),
+			OrderId:  entry.OrderId,
+			BizKey:   fmt.Sprintf("expire:entry:%d", entry.ID),
+			Remark:   "积分过期",
+		})
+		if err != nil || points <= 0 {
+			return err
+		}
+		if _, err = pds.pointsDao.DecrBalanceInTx(tx, entry.UserId, points); err != nil {
+			return err
+		}
+		expired = points
+		return nil
+	})
+	return expired, err
+}
+
+// consumeInTx 按过期时间从早到晚扣减用户获得的积分中剩余的部分, 返回被扣减积分中最晚的过期时间, 没有扣减到积分时返回零值
+func (pds *PointsDomainSvc) consumeInTx(tx *gorm.DB, userId int64, points int) (time.Time, error) {
+	entries, err := pds.pointsDao.LockRemainingEntriesInTx(tx, userId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	consumptions, latestExpireAt := ConsumePoints(lo.Map(entries, func(entry *model.UserPointsEntry, _ int) *do.PointsEntry {
+		return pointsEntryFromModel(entry)
+	}), points)
+	for _, consumption := range consumptions {
+		if err = pds.pointsDao.DecrEntryRemainingInTx(tx, consumption.EntryId, consumption.Points); err != nil {
+			return latestExpireAt, err
+		}
+	}
+	return latestExpireAt, nil
+}
+
+// ConsumePoints 按过期时间从早到晚扣减积分流水中剩余的积分, 过期时间相同时先扣减先获得的.
+// 返回每条流水扣减的积分和被扣减积分中最晚的过期时间, 没有扣减到积分时返回零值, 剩余积分不够时扣完为止
+func ConsumePoints(entries []*do.PointsEntry, points int) ([]*do.PointsConsumption, time.Time) {
+	sorted := make([]*do.PointsEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ExpireAt.Equal(sorted[j].ExpireAt) {
+			return sorted[i].ExpireAt.Before(sorted[j].ExpireAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	consumptions := make([]*do.PointsConsumption, 0)
+	var latestExpireAt time.Time
+	for _, entry := range sorted {
+		if points <= 0 {
+			break
+		}
+		if entry.Remaining <= 0 {
+			continue
+		}
+		consumed := min(entry.Remaining, points)
+		consumptions = append(consumptions, &do.PointsConsumption{EntryId: entry.ID, Points: consumed})
+		points -= consumed
+		latestExpireAt = entry.ExpireAt
+	}
+	return consumptions, latestExpireAt
+}
+
+// returnedPointsExpireAt 退回积分的过期时间: 沿用被扣减积分的过期时间, 但至少还能使用 PointsReturnGraceDays 天;
+// 没有记录过期时间的扣减按新获得的积分计算
+func returnedPointsExpireAt(spentExpireAt, now time.Time) time.Time {
+	if spentExpireAt.Unix() <= 0 {
+		return now.AddDate(0, 0, pointsExpireDays())
+	}
+	graceExpireAt := now.AddDate(0, 0, enum.PointsReturnGraceDays)
+	if spentExpireAt.Before(graceExpireAt) {
+		return graceExpireAt
+	}
+	return spentExpireAt
+}
+
+// pointsRedemption 结算时实际使用的积分和抵扣金额: 不超过用户要使用的积分和余额, 抵扣金额不超过商品金额的 max_redeem_rate%
+func pointsRedemption(usePoints, balance, originalTotalPrice int) (points, money int) {
+	maxRedeemMoney := originalTotalPrice * pointsMaxRedeemRate() / 100
+	points = min(usePoints, balance, maxRedeemMoney/pointsRedeemValue())
+	return points, points * pointsRedeemValue()
+}
+
+func pointsEntryFromModel(entryModel *model.UserPointsEntry) *do.PointsEntry {
+	return &do.PointsEntry{
+		ID:        entryModel.ID,
+		UserId:    entryModel.UserId,
+		Type:      entryModel.Type,
+		Points:    entryModel.Points,
+		Remaining: entryModel.Remaining,
+		ExpireAt:  entryModel.ExpireAt,
+		OrderId:   entryModel.OrderId,
+		Remark:    entryModel.Remark,
+		CreatedAt: entryModel.CreatedAt,
+	}
+}
+
+func orderPointsBizKey(action string, orderId int64) string {
+	return fmt.Sprintf("%s:order:%d", action, orderId)
+}
+
+func pointsEarnUnit() int {
+	if config.App.Points.EarnUnit > 0 {
+		return config.App.Points.EarnUnit
+	}
+	return enum.DefaultPointsEarnUnit
+}
+
+func pointsRedeemValue() int {
+	if config.App.Points.RedeemValue > 0 {
+		return config.App.Points.RedeemValue
+	}
+	return enum.DefaultPointsRedeemValue
+}
+
+func pointsMaxRedeemRate() int {
+	if config.App.Points.MaxRedeemRate > 0 {
+		return min(config.App.Points.MaxRedeemRate, 100)
+	}
+	return enum.DefaultPointsMaxRedeemRate
+}
+
+func pointsExpireDays() int {
+	if config.App.Points.ExpireDays > 0 {
+		return config.App.Points.ExpireDays
+	}
+	return enum.DefaultPointsExpireDays
+}
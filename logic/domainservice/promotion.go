@@ -0,0 +1,52 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"strconv"
+	"strings"
+)
+
+// inPromotionScope 购物项是否在优惠券或满减活动的适用范围内
+func inPromotionScope(scopeType int, scopeIds []int64, item *do.ShoppingCartItem) bool {
+	switch scopeType {
+	case enum.PromotionScopeCategory:
+		return lo.Contains(scopeIds, item.CategoryId)
+	case enum.PromotionScopeCommodity:
+		return lo.Contains(scopeIds, item.CommodityId)
+	}
+	return true
+}
+
+// promotionScopeItems 适用范围内的购物项
+func promotionScopeItems(scopeType int, scopeIds []int64, items []*do.ShoppingCartItem) []*do.ShoppingCartItem {
+	return lo.Filter(items, func(item *do.ShoppingCartItem, _ int) bool {
+		return inPromotionScope(scopeType, scopeIds, item)
+	})
+}
+
+// itemsAmount 购物项按售价计算的金额合计
+func itemsAmount(items []*do.ShoppingCartItem) int {
+	return lo.SumBy(items, func(item *do.ShoppingCartItem) int {
+		return item.CommoditySellingPrice * item.CommodityNum
+	})
+}
+
+// parseScopeIds 把逗号分隔的适用范围ID转换为列表
+func parseScopeIds(scopeIds string) []int64 {
+	ids := make([]int64, 0)
+	for _, idStr := range strings.Split(scopeIds, ",") {
+		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// joinScopeIds 把适用范围ID列表转换为逗号分隔的字符串保存
+func joinScopeIds(scopeIds []int64) string {
+	return strings.Join(lo.Map(scopeIds, func(id int64, _ int) string {
+		return strconv.FormatInt(id, 10)
+	}), ",")
+}
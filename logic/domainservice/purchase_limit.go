@@ -0,0 +1,106 @@
+package domainservice
+
+import (
+	"context"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"sort"
+)
+
+// 购物车和订单的数量限制:
+//   - 用户购物车最多容纳 cart.max_items 个SKU
+//   - 购物车或单个订单中同一商品(所有SKU合计)最多 cart.commodity_max_num 件
+//   - 设置了限购数量的商品, 购物车或订单中的数量加上用户未关闭订单中的数量不能超过限购数量
+//   - 单个订单最多 order.max_num 件商品
+
+// cartMaxItems 用户购物车最多容纳的SKU数量
+func cartMaxItems() int {
+	if config.App.Cart.MaxItems > 0 {
+		return config.App.Cart.MaxItems
+	}
+	return enum.DefaultCartMaxItems
+}
+
+// commodityMaxNum 购物车或单个订单中同一商品的最大数量
+func commodityMaxNum() int {
+	if config.App.Cart.CommodityMaxNum > 0 {
+		return config.App.Cart.CommodityMaxNum
+	}
+	return enum.DefaultCommodityMaxNum
+}
+
+// orderMaxNum 单个订单最多购买的商品件数
+func orderMaxNum() int {
+	if config.App.Order.MaxNum > 0 {
+		return config.App.Order.MaxNum
+	}
+	return enum.DefaultOrderMaxNum
+}
+
+// sumCommodityNums 按商品合计购物项的数量
+func sumCommodityNums(items []*do.ShoppingCartItem) map[int64]int {
+	nums := make(map[int64]int)
+	for _, item := range items {
+		nums[item.CommodityId] += item.CommodityNum
+	}
+	return nums
+}
+
+// checkPurchaseLimits 校验用户购买每个商品的数量不超过限制, commodityNums 为购物车或订单中每个商品的数量合计
+func checkPurchaseLimits(ctx context.Context, userId int64, commodityNums map[int64]int) error {
+	maxNum := commodityMaxNum()
+	// 先检查单次购买的数量, 超出时不用再查询商品和历史订单
+	if err := CheckPurchaseNums(commodityNums, maxNum, nil, nil); err != nil {
+		return err
+	}
+	commodities, err := dao.NewCommodityDao(ctx).FindCommodities(lo.Keys(commodityNums))
+	if err != nil {
+		return errcode.Wrap("CheckPurchaseLimitsError", err)
+	}
+	limits := make(map[int64]int)
+	for _, commodity := range commodities {
+		if commodity.PurchaseLimit > 0 {
+			limits[commodity.ID] = commodity.PurchaseLimit
+		}
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+	purchased, err := dao.NewOrderDao(ctx).SumUserPurchasedNum(userId, lo.Keys(limits))
+	if err != nil {
+		return errcode.Wrap("CheckPurchaseLimitsError", err)
+	}
+	return CheckPurchaseNums(commodityNums, maxNum, limits, purchased)
+}
+
+// CheckPurchaseNums 按商品ID从小到大校验购买数量, 返回第一个超出限制的商品的错误:
+// 同一商品的数量不能超过 maxNum; limits 中设置了限购数量的商品, 数量加上用户已购买的数量 purchased 不能超过限购数量
+func CheckPurchaseNums(commodityNums map[int64]int, maxNum int, limits, purchased map[int64]int) error {
+	commodityIds := lo.Keys(commodityNums)
+	sort.Slice(commodityIds, func(i, j int) bool { return commodityIds[i] < commodityIds[j] })
+	for _, commodityId := range commodityIds {
+		num := commodityNums[commodityId]
+		if num > maxNum {
+			return errcode.ErrCartPurchaseLimit.WithCause(fmt.Errorf("商品 %d 最多购买 %d 件", commodityId, maxNum))
+		}
+		if limit := limits[commodityId]; limit > 0 && purchased[commodityId]+num > limit {
+			return errcode.ErrCartPurchaseLimit.WithCause(fmt.Errorf("商品 %d 每人限购 %d 件, 已购买 %d 件",
+				commodityId, limit, purchased[commodityId]))
+		}
+	}
+	return nil
+}
+
+// checkOrderLimits 校验订单的商品总件数和每个商品的购买数量
+func checkOrderLimits(ctx context.Context, userId int64, items []*do.ShoppingCartItem) error {
+	totalNum := lo.SumBy(items, func(item *do.ShoppingCartItem) int { return item.CommodityNum })
+	if totalNum > orderMaxNum() {
+		return errcode.ErrOrderNumLimit.WithCause(fmt.Errorf("订单最多购买 %d 件商品", orderMaxNum()))
+	}
+	return checkPurchaseLimits(ctx, userId, sumCommodityNums(items))
+}
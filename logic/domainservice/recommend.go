@@ -0,0 +1,190 @@
+package domainservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"sort"
+	"time"
+)
+
+// relatedWriteBatchSize 每批写入Redis的商品数量
+const relatedWriteBatchSize = 500
+
+type RecommendDomainSvc struct {
+	ctx          context.Context
+	orderDao     *dao.OrderDao
+	commodityDao *dao.CommodityDao
+}
+
+func NewRecommendDomainSvc(ctx context.Context) *RecommendDomainSvc {
+	return &RecommendDomainSvc{
+		ctx:          ctx,
+		orderDao:     dao.NewOrderDao(ctx),
+		commodityDao: dao.NewCommodityDao(ctx),
+	}
+}
+
+// RefreshRelatedCommodities 统计最近已支付订单中商品两两一起购买的订单数, 每个商品保留订单数最多的
+// 若干个相关商品写入Redis, 返回有相关商品的商品数量. 其他实例正在计算时直接返回
+func (rds *RecommendDomainSvc) RefreshRelatedCommodities() (int, error) {
+	log := logger.New(rds.ctx)
+	token, err := cache.LockRelatedRefresh(rds.ctx)
+	if err != nil {
+		log.Info("RefreshRelatedCommoditiesSkipped", "err", err)
+		return 0, nil
+	}
+	defer func() {
+		if err := cache.UnlockRelatedRefresh(rds.ctx, token); err != nil {
+			log.Error("UnlockRelatedRefreshError", "err", err)
+		}
+	}()
+
+	pairCounts := make(map[int64]map[int64]int)
+	since := time.Now().Add(-enum.RelatedOrderWindow)
+	var afterId int64
+	for {
+		orderIds, err := rds.orderDao.GetPaidOrderIdsSince(since, afterId, enum.RelatedOrderBatchSize)
+		if err != nil {
+			return 0, errcode.Wrap("RefreshRelatedCommoditiesError", err)
+		}
+		if len(orderIds) == 0 {
+			break
+		}
+		ordersItems, err := rds.orderDao.GetMultiOrdersItems(orderIds)
+		if err != nil {
+			return 0, errcode.Wrap("RefreshRelatedCommoditiesError", err)
+		}
+		for _, orderItems := range ordersItems {
+			countCoPurchases(pairCounts, orderItems)
+		}
+		afterId = orderIds[len(orderIds)-1]
+	}
+
+	relatedMap := topRelatedCommodities(pairCounts, enum.RelatedKeepSize)
+	batch := make(map[int64][]*do.RelatedCommodity, relatedWriteBatchSize)
+	for commodityId, relatedList := range relatedMap {
+		batch[commodityId] = relatedList
+		if len(batch) < relatedWriteBatchSize {
+			continue
+		}
+		if err = cache.SetRelatedCommodities(rds.ctx, batch); err != nil {
+			return 0, errcode.Wrap("RefreshRelatedCommoditiesError", err)
+		}
+		batch = make(map[int64][]*do.RelatedCommodity, relatedWriteBatchSize)
+	}
+	if err = cache.SetRelatedCommodities(rds.ctx, batch); err != nil {
+		return 0, errcode.Wrap("RefreshRelatedCommoditiesError", err)
+	}
+	return len(relatedMap), nil
+}
+
+// GetRelatedCommodities 获取经常与商品一起购买的在售商品, 不足 size 个时用同分类的热销商品补充
+func (rds *RecommendDomainSvc) GetRelatedCommodities(commodity *do.Commodity, size int) ([]*do.Commodity, error) {
+	log := logger.New(rds.ctx)
+	commodities := make([]*do.Commodity, 0, size)
+	relatedIds, err := cache.GetRelatedCommodityIds(rds.ctx, commodity.ID, enum.RelatedKeepSize)
+	if err != nil {
+		// 读不到相关商品时只用热销商品
+		log.Error("GetRelatedCommodityIdsError", "commodityId", commodity.ID, "err", err)
+	}
+	if len(relatedIds) > 0 {
+		commodityModels, err := rds.commodityDao.FindCommoditiesUnscoped(relatedIds)
+		if err != nil {
+			return nil, errcode.Wrap("GetRelatedCommoditiesError", err)
+		}
+		commodityMap := lo.SliceToMap(commodityModels, func(commodity *model.Commodity) (int64, *model.Commodity) {
+			return commodity.ID, commodity
+		})
+		for _, relatedId := range relatedIds {
+			commodityModel, ok := commodityMap[relatedId]
+			if !ok || commodityModel.IsDel != 0 || commodityModel.SellStatus != enum.CommoditySellStatusOn {
+				continue
+			}
+			related := new(do.Commodity)
+			if err = util.CopyProperties(related, commodityModel); err != nil {
+				return nil, errcode.ErrCoverData.WithCause(err)
+			}
+			commodities = append(commodities, related)
+			if len(commodities) == size {
+				return commodities, nil
+			}
+		}
+	}
+
+	bestSellers, err := cache.GetCategoryBestSellers(rds.ctx, commodity.CategoryId, func() ([]*do.Commodity, error) {
+		return rds.loadCategoryBestSellers(commodity.CategoryId)
+	})
+	if err != nil {
+		return nil, errcode.Wrap("GetRelatedCommoditiesError", err)
+	}
+	for _, bestSeller := range bestSellers {
+		if len(commodities) == size {
+			break
+		}
+		if bestSeller.ID == commodity.ID || lo.ContainsBy(commodities, func(related *do.Commodity) bool {
+			return related.ID == bestSeller.ID
+		}) {
+			continue
+		}
+		commodities = append(commodities, bestSeller)
+	}
+	return commodities, nil
+}
+
+// loadCategoryBestSellers 从MySQL读取分类的热销商品, 多取一个以便排除商品自身后仍然足够
+func (rds *RecommendDomainSvc) loadCategoryBestSellers(categoryId int64) ([]*do.Commodity, error) {
+	commodityModels, err := rds.commodityDao.FindCategoryBestSellers(categoryId, enum.RelatedKeepSize+1)
+	if err != nil {
+		return nil, errcode.Wrap("FindCategoryBestSellersError", err)
+	}
+	commodities := make([]*do.Commodity, 0, len(commodityModels))
+	if err = util.CopyProperties(&commodities, &commodityModels); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return commodities, nil
+}
+
+// countCoPurchases 累加一个订单中每两个不同商品一起购买的次数, 同一商品的多个SKU只算一次
+func countCoPurchases(pairCounts map[int64]map[int64]int, orderItems []*model.OrderItem) {
+	commodityIds := lo.Uniq(lo.Map(orderItems, func(item *model.OrderItem, _ int) int64 { return item.CommodityId }))
+	if len(commodityIds) < 2 || len(commodityIds) > enum.RelatedOrderMaxCommodities {
+		return
+	}
+	for _, commodityId := range commodityIds {
+		if pairCounts[commodityId] == nil {
+			pairCounts[commodityId] = make(map[int64]int)
+		}
+		for _, relatedId := range commodityIds {
+			if relatedId != commodityId {
+				pairCounts[commodityId][relatedId]++
+			}
+		}
+	}
+}
+
+// topRelatedCommodities 每个商品按一起购买的订单数从多到少保留前 keepSize 个相关商品
+func topRelatedCommodities(pairCounts map[int64]map[int64]int, keepSize int) map[int64][]*do.RelatedCommodity {
+	relatedMap := make(map[int64][]*do.RelatedCommodity, len(pairCounts))
+	for commodityId, counts := range pairCounts {
+		relatedList := make([]*do.RelatedCommodity, 0, len(counts))
+		for relatedId, count := range counts {
+			relatedList = append(relatedList, &do.RelatedCommodity{CommodityId: relatedId, OrderCount: count})
+		}
+		sort.Slice(relatedList, func(i, j int) bool {
+			if relatedList[i].OrderCount != relatedList[j].OrderCount {
+				return relatedList[i].OrderCount > relatedList[j].OrderCount
+			}
+			return relatedList[i].CommodityId < relatedList[j].CommodityId
+		})
+		relatedMap[commodityId] = lo.Slice(relatedList, 0, keepSize)
+	}
+	return relatedMap
+}
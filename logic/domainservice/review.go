@@ -0,0 +1,193 @@
+package domainservice
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Ian-zy0329/go-mall/common/app"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+type ReviewDomainSvc struct {
+	ctx       context.Context
+	reviewDao *dao.ReviewDao
+	orderDao  *dao.OrderDao
+}
+
+func NewReviewDomainSvc(ctx context.Context) *ReviewDomainSvc {
+	return &ReviewDomainSvc{
+		ctx:       ctx,
+		reviewDao: dao.NewReviewDao(ctx),
+		orderDao:  dao.NewOrderDao(ctx),
+	}
+}
+
+// CreateReview 买家评价订单中购买的SKU, 订单必须属于买家且已确认收货, 每个购物项只能评价一次;
+// 订单的购物项全部评价后订单变为已完成, 并在同一事务中按支付金额增加会员成长值和积分. 返回评价ID
+func (rds *ReviewDomainSvc) CreateReview(orderNo string, review *do.CommodityReview) (int64, error) {
+	orderModel, err := rds.orderDao.GetOrderByNo(orderNo)
+	if err != nil {
+		return 0, errcode.Wrap("CreateReviewError", err)
+	}
+	if orderModel.ID == 0 || orderModel.UserId != review.UserId {
+		return 0, errcode.ErrReviewNotAllowed
+	}
+	orderItems, err := rds.orderDao.GetOrderItems(orderModel.ID)
+	if err != nil {
+		return 0, errcode.Wrap("CreateReviewError", err)
+	}
+	orderItem, found := lo.Find(orderItems, func(item *model.OrderItem) bool {
+		return item.SkuId == review.SkuId
+	})
+	if !found {
+		return 0, errcode.ErrReviewNotAllowed
+	}
+	existing, err := rds.reviewDao.GetReviewByOrderItem(orderItem.ID)
+	if err != nil {
+		return 0, errcode.Wrap("CreateReviewError", err)
+	}
+	if existing.ID != 0 {
+		return 0, errcode.ErrReviewDuplicated
+	}
+	if orderModel.OrderStatus != enum.OrderStatusConfirmReceipt {
+		return 0, errcode.ErrReviewNotAllowed
+	}
+
+	images, _ := json.Marshal(lo.Ternary(review.Images == nil, []string{}, review.Images))
+	reviewModel := &model.CommodityReview{
+		UserId:      review.UserId,
+		OrderId:     orderModel.ID,
+		OrderItemId: orderItem.ID,
+		CommodityId: orderItem.CommodityId,
+		SkuId:       orderItem.SkuId,
+		SkuSpecDesc: orderItem.SkuSpecDesc,
+		Rating:      review.Rating,
+		Content:     review.Content,
+		Images:      string(images),
+	}
+	err = dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		// order_item_id 上有唯一索引, 并发重复提交时只有一条能写入
+		if err := rds.reviewDao.CreateReviewInTx(tx, reviewModel); err != nil {
+			return err
+		}
+		reviewedCount, err := rds.reviewDao.CountOrderReviewsInTx(tx, orderModel.ID)
+		if err != nil {
+			return err
+		}
+		if reviewedCount < int64(len(orderItems)) {
+			return nil
+		}
+		completed, err := rds.orderDao.CompleteOrderInTx(tx, orderModel.ID)
+		if err != nil || completed == 0 {
+			return err
+		}
+		order := &do.Order{ID: orderModel.ID, OrderNo: orderModel.OrderNo, UserId: orderModel.UserId, PayMoney: orderModel.PayMoney}
+		if err = NewVipDomainSvc(rds.ctx).AddOrderGrowthInTx(tx, order); err != nil {
+			return err
+		}
+		return NewPointsDomainSvc(rds.ctx).EarnOrderPointsInTx(tx, order)
+	})
+	if err != nil {
+		return 0, errcode.Wrap("CreateReviewError", err)
+	}
+	return reviewModel.ID, nil
+}
+
+// GetCommodityReviews 商品的评价列表, rating 为0时不限星级
+func (rds *ReviewDomainSvc) GetCommodityReviews(commodityId int64, rating int, pagination *app.Pagination) ([]*do.CommodityReview, error) {
+	reviewModels, totalRows, err := rds.reviewDao.GetCommodityReviews(commodityId, rating, pagination.Offset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, errcode.Wrap("GetCommodityReviewsError", err)
+	}
+	pagination.SetTotalRows(int(totalRows))
+
+	userIds := lo.Uniq(lo.Map(reviewModels, func(review *model.CommodityReview, _ int) int64 { return review.UserId }))
+	users, err := dao.NewUserDao(rds.ctx).FindUsers(userIds)
+	if err != nil {
+		return nil, errcode.Wrap("GetCommodityReviewsError", err)
+	}
+	userMap := lo.SliceToMap(users, func(user *model.User) (int64, *model.User) { return user.ID, user })
+
+	reviews := make([]*do.CommodityReview, 0, len(reviewModels))
+	for _, reviewModel := range reviewModels {
+		review := reviewFromModel(reviewModel)
+		if err = json.Unmarshal([]byte(reviewModel.Images), &review.Images); err != nil {
+			logger.New(rds.ctx).Warn("ReviewImagesDecodeError", "reviewId", reviewModel.ID, "err", err)
+		}
+		if user, ok := userMap[reviewModel.UserId]; ok {
+			review.UserNickname = user.Nickname
+			review.UserAvatar = user.Avatar
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+// GetCommodityRatingSummary 汇总商品评价的星级分布、平均星级和好评率
+func (rds *ReviewDomainSvc) GetCommodityRatingSummary(commodityId int64) (*do.CommodityRatingSummary, error) {
+	ratingCounts, err := rds.reviewDao.CountCommodityRatings(commodityId)
+	if err != nil {
+		return nil, errcode.Wrap("GetCommodityRatingSummaryError", err)
+	}
+	return newRatingSummary(ratingCounts), nil
+}
+
+// ReplyReview 商家回复评价
+func (rds *ReviewDomainSvc) ReplyReview(reviewId int64, content string) error {
+	reviewModel, err := rds.reviewDao.GetReviewById(reviewId)
+	if err != nil {
+		return errcode.Wrap("ReplyReviewError", err)
+	}
+	if reviewModel.ID == 0 {
+		return errcode.ErrReviewNotExists
+	}
+	if err = rds.reviewDao.UpdateMerchantReply(reviewId, content, time.Now()); err != nil {
+		return errcode.Wrap("ReplyReviewError", err)
+	}
+	return nil
+}
+
+// newRatingSummary 根据各星级的评价数量计算汇总, 没有评价时平均星级和好评率都为0
+func newRatingSummary(ratingCounts map[int]int64) *do.CommodityRatingSummary {
+	summary := &do.CommodityRatingSummary{RatingCounts: make(map[int]int64, enum.ReviewRatingMax)}
+	var ratingSum, goodCount int64
+	for rating := enum.ReviewRatingMin; rating <= enum.ReviewRatingMax; rating++ {
+		count := ratingCounts[rating]
+		summary.RatingCounts[rating] = count
+		summary.ReviewCount += count
+		ratingSum += int64(rating) * count
+		if rating >= enum.ReviewGoodRatingMin {
+			goodCount += count
+		}
+	}
+	if summary.ReviewCount > 0 {
+		summary.AverageRating = math.Round(float64(ratingSum)/float64(summary.ReviewCount)*10) / 10
+		summary.GoodRate = float64(goodCount) / float64(summary.ReviewCount)
+	}
+	return summary
+}
+
+func reviewFromModel(reviewModel *model.CommodityReview) *do.CommodityReview {
+	return &do.CommodityReview{
+		ID:            reviewModel.ID,
+		UserId:        reviewModel.UserId,
+		OrderId:       reviewModel.OrderId,
+		OrderItemId:   reviewModel.OrderItemId,
+		CommodityId:   reviewModel.CommodityId,
+		SkuId:         reviewModel.SkuId,
+		SkuSpecDesc:   reviewModel.SkuSpecDesc,
+		Rating:        reviewModel.Rating,
+		Content:       reviewModel.Content,
+		MerchantReply: reviewModel.MerchantReply,
+		RepliedAt:     reviewModel.RepliedAt,
+		CreatedAt:     reviewModel.CreatedAt,
+	}
+}
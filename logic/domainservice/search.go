@@ -0,0 +1,122 @@
+package domainservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// 搜索词日志保存在Redis中:
+// 热度集合按时间衰减排序用于补全, 每日热搜集合按当天的搜索次数排序, 无结果集合留给运营查看后补充商品或同义词
+
+type SearchDomainSvc struct {
+	ctx          context.Context
+	commodityDao *dao.CommodityDao
+}
+
+func NewSearchDomainSvc(ctx context.Context) *SearchDomainSvc {
+	return &SearchDomainSvc{
+		ctx:          ctx,
+		commodityDao: dao.NewCommodityDao(ctx),
+	}
+}
+
+// RecordQuery 记录一次搜索及其结果数量, 记录失败不影响搜索, 只打日志
+func (sds *SearchDomainSvc) RecordQuery(keyword string, resultCount int64) {
+	query := util.NormalizeSearchQuery(keyword)
+	if query == "" || utf8.RuneCountInString(query) > enum.SearchQueryMaxLength {
+		return
+	}
+	if err := cache.RecordSearchQuery(sds.ctx, query, resultCount, time.Now()); err != nil {
+		logger.New(sds.ctx).Error("RecordSearchQueryError", "query", query, "err", err)
+	}
+}
+
+// Suggest 返回以 prefix 开头的补全词: 先是有结果的历史搜索词(按热度排序), 再用商品名称补足
+func (sds *SearchDomainSvc) Suggest(prefix string, limit int) ([]*do.SearchSuggestion, error) {
+	query := util.NormalizeSearchQuery(prefix)
+	suggestions := make([]*do.SearchSuggestion, 0, limit)
+	if query == "" {
+		return suggestions, nil
+	}
+	candidates, err := cache.GetSearchQueriesWithPrefix(sds.ctx, query, enum.SearchSuggestScanSize)
+	if err != nil {
+		return nil, errcode.Wrap("GetSearchSuggestError", err)
+	}
+	trends, resultCounts, err := cache.GetSearchQueryStats(sds.ctx, candidates)
+	if err != nil {
+		return nil, errcode.Wrap("GetSearchSuggestError", err)
+	}
+	type rankedQuery struct {
+		query string
+		trend float64
+	}
+	rankedQueries := make([]rankedQuery, 0, len(candidates))
+	for i, candidate := range candidates {
+		if resultCounts[i] > 0 {
+			rankedQueries = append(rankedQueries, rankedQuery{candidate, trends[i]})
+		}
+	}
+	sort.SliceStable(rankedQueries, func(i, j int) bool { return rankedQueries[i].trend > rankedQueries[j].trend })
+	seen := make(map[string]struct{})
+	for _, ranked := range rankedQueries {
+		if len(suggestions) >= limit {
+			break
+		}
+		seen[ranked.query] = struct{}{}
+		suggestions = append(suggestions, &do.SearchSuggestion{Text: ranked.query, Source: enum.SearchSuggestSourceQuery})
+	}
+	if len(suggestions) >= limit {
+		return suggestions, nil
+	}
+
+	names, err := sds.commodityDao.FindCommodityNamesWithPrefix(prefix, limit)
+	if err != nil {
+		return nil, errcode.Wrap("GetSearchSuggestError", err)
+	}
+	for _, name := range names {
+		if len(suggestions) >= limit {
+			break
+		}
+		if _, exists := seen[util.NormalizeSearchQuery(name)]; exists {
+			continue
+		}
+		suggestions = append(suggestions, &do.SearchSuggestion{Text: name, Source: enum.SearchSuggestSourceCommodity})
+	}
+	return suggestions, nil
+}
+
+// GetHotQueries 获取某一天的热搜榜
+func (sds *SearchDomainSvc) GetHotQueries(day time.Time, limit int) ([]*do.SearchQueryStat, error) {
+	hotQueries, err := cache.GetHotSearchQueries(sds.ctx, day, int64(limit))
+	if err != nil {
+		return nil, errcode.Wrap("GetHotSearchQueriesError", err)
+	}
+	return hotQueries, nil
+}
+
+// GetZeroResultQueries 获取搜索次数最多的无结果搜索词
+func (sds *SearchDomainSvc) GetZeroResultQueries(limit int) ([]*do.SearchQueryStat, error) {
+	zeroResultQueries, err := cache.GetZeroResultQueries(sds.ctx, int64(limit))
+	if err != nil {
+		return nil, errcode.Wrap("GetZeroResultQueriesError", err)
+	}
+	return zeroResultQueries, nil
+}
+
+// TrimQueries 清理热度最低的搜索词, 避免补全用的集合无限增长
+func (sds *SearchDomainSvc) TrimQueries() (int, error) {
+	trimmed, err := cache.TrimSearchQueries(sds.ctx, enum.SearchTrendKeepSize)
+	if err != nil {
+		return 0, errcode.Wrap("TrimSearchQueriesError", err)
+	}
+	return trimmed, nil
+}
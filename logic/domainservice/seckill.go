@@ -0,0 +1,313 @@
+package domainservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/common/util"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"time"
+)
+
+// 秒杀流程:
+// 买家请求只访问Redis, 由Lua脚本完成限流、活动时间、用户限购和名额预扣, 成功后请求进入下单队列;
+// 后台协程从队列中取出请求创建订单并预占商品库存, 失败时退回名额; 买家通过请求ID轮询处理结果;
+// 秒杀订单取消或超时未支付关闭时同样退回名额
+
+// seckillScriptErrors 秒杀脚本错误码对应的业务错误
+var seckillScriptErrors = map[string]*errcode.AppError{
+	"E_TOO_MANY_REQUESTS":    errcode.ErrTooManyRequests,
+	"E_CAMPAIGN_NOT_STARTED": errcode.ErrSeckillNotStarted,
+	"E_CAMPAIGN_ENDED":       errcode.ErrSeckillEnded,
+	"E_USER_LIMIT":           errcode.ErrSeckillUserLimit,
+	"E_QUOTA_INSUFFICIENT":   errcode.ErrSeckillSoldOut,
+}
+
+type SeckillDomainSvc struct {
+	ctx        context.Context
+	seckillDao *dao.SeckillDao
+}
+
+func NewSeckillDomainSvc(ctx context.Context) *SeckillDomainSvc {
+	return &SeckillDomainSvc{
+		ctx:        ctx,
+		seckillDao: dao.NewSeckillDao(ctx),
+	}
+}
+
+func (sds *SeckillDomainSvc) GetCampaign(campaignId int64) (*do.SeckillCampaign, error) {
+	campaignModel, err := sds.seckillDao.GetCampaignById(campaignId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSeckillCampaignError", err)
+	}
+	if campaignModel.ID == 0 || campaignModel.Status != enum.SeckillCampaignStatusOnline {
+		return nil, errcode.ErrSeckillNotExists
+	}
+	campaign := new(do.SeckillCampaign)
+	if err = util.CopyProperties(campaign, campaignModel); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return campaign, nil
+}
+
+// GetActiveCampaigns 获取进行中和 ahead 时间内即将开始的活动
+func (sds *SeckillDomainSvc) GetActiveCampaigns(ahead time.Duration) ([]*do.SeckillCampaign, error) {
+	now := time.Now()
+	campaignModels, err := sds.seckillDao.GetActiveCampaigns(now, now.Add(ahead))
+	if err != nil {
+		return nil, errcode.Wrap("GetActiveSeckillCampaignsError", err)
+	}
+	campaigns := make([]*do.SeckillCampaign, 0, len(campaignModels))
+	if err = util.CopyProperties(&campaigns, &campaignModels); err != nil {
+		return nil, errcode.ErrCoverData.WithCause(err)
+	}
+	return campaigns, nil
+}
+
+// GetCampaignQuota 获取活动的剩余名额, 活动还未加载到Redis时剩余名额为活动配置的名额
+func (sds *SeckillDomainSvc) GetCampaignQuota(campaign *do.SeckillCampaign) (*do.SeckillQuota, error) {
+	quota, err := cache.GetSeckillQuota(sds.ctx, campaign.ID)
+	if err != nil {
+		return nil, errcode.Wrap("GetSeckillQuotaError", err)
+	}
+	if quota == nil {
+		quota = &do.SeckillQuota{CampaignId: campaign.ID, Remaining: campaign.Quota}
+	}
+	return quota, nil
+}
+
+// PreloadCampaigns 把进行中和即将开始的活动名额加载到Redis, 避免活动开始时大量请求回源MySQL
+func (sds *SeckillDomainSvc) PreloadCampaigns() error {
+	campaigns, err := sds.GetActiveCampaigns(enum.SeckillCampaignPreloadAhead)
+	if err != nil {
+		return err
+	}
+	for _, campaign := range campaigns {
+		if err = cache.InitSeckillCampaign(sds.ctx, campaign); err != nil {
+			return errcode.Wrap("PreloadSeckillCampaignError", err)
+		}
+	}
+	return nil
+}
+
+// SubmitRequest 预扣秒杀名额并排队创建订单, 返回用于轮询结果的请求ID
+func (sds *SeckillDomainSvc) SubmitRequest(campaignId, userId, userAddressId int64, num int) (string, error) {
+	request := &do.SeckillRequest{
+		RequestId:     fmt.Sprintf("%d%s", time.Now().UnixNano(), util.RandNumStr(6)),
+		CampaignId:    campaignId,
+		UserId:        userId,
+		Num:           num,
+		UserAddressId: userAddressId,
+		CreatedAt:     time.Now(),
+	}
+	rateLimit := config.App.Seckill.RateLimit
+	queueMax := config.App.Seckill.QueueMax
+	if queueMax <= 0 {
+		queueMax = enum.DefaultSeckillQueueMax
+	}
+	err := cache.DeductSeckillQuota(sds.ctx, request, rateLimit, queueMax)
+	var scriptErr *cache.StockScriptError
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_CAMPAIGN_NOT_FOUND" {
+		// 活动未被定时任务预加载, 从MySQL加载后重试一次
+		campaign, campaignErr := sds.GetCampaign(campaignId)
+		if campaignErr != nil {
+			return "", campaignErr
+		}
+		if err = cache.InitSeckillCampaign(sds.ctx, campaign); err != nil {
+			return "", errcode.Wrap("InitSeckillCampaignError", err)
+		}
+		err = cache.DeductSeckillQuota(sds.ctx, request, rateLimit, queueMax)
+	}
+	if errors.As(err, &scriptErr) {
+		if appErr, ok := seckillScriptErrors[scriptErr.Code]; ok {
+			return "", appErr
+		}
+	}
+	if err != nil {
+		return "", errcode.Wrap("SubmitSeckillRequestError", err)
+	}
+	return request.RequestId, nil
+}
+
+// GetUserResult 获取用户秒杀请求的处理结果
+func (sds *SeckillDomainSvc) GetUserResult(requestId string, userId int64) (*do.SeckillResult, error) {
+	result, err := cache.GetSeckillResult(sds.ctx, requestId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSeckillResultError", err)
+	}
+	if result == nil || result.UserId != userId {
+		return nil, errcode.ErrSeckillResultNotFound
+	}
+	return result, nil
+}
+
+// ConsumeRequest 从下单队列取出一个秒杀请求创建订单, 队列为空时等待 timeout 后返回;
+// 请求在写入处理结果后才从处理中列表移除, 处理过程中协程退出的请求由 RecoverRequests 放回队列
+func (sds *SeckillDomainSvc) ConsumeRequest(timeout time.Duration) error {
+	request, payload, err := cache.PopSeckillRequest(sds.ctx, timeout)
+	if err != nil {
+		return errcode.Wrap("PopSeckillRequestError", err)
+	}
+	if request == nil {
+		return nil
+	}
+	if err = sds.handleRequest(request); err != nil {
+		return err
+	}
+	if err = cache.AckSeckillRequest(sds.ctx, payload); err != nil {
+		return errcode.Wrap("AckSeckillRequestError", err)
+	}
+	return nil
+}
+
+// handleRequest 为秒杀请求创建订单并写入处理结果, 请求可能被重复处理, 同一请求只会创建一个订单
+func (sds *SeckillDomainSvc) handleRequest(request *do.SeckillRequest) error {
+	log := logger.New(sds.ctx)
+	result := &do.SeckillResult{
+		RequestId:  request.RequestId,
+		CampaignId: request.CampaignId,
+		UserId:     request.UserId,
+		Status:     enum.SeckillResultSuccess,
+	}
+	lastResult, err := cache.GetSeckillResult(sds.ctx, request.RequestId)
+	if err != nil {
+		return errcode.Wrap("GetSeckillResultError", err)
+	}
+	if lastResult != nil && lastResult.Status != enum.SeckillResultQueued {
+		// 已经处理过的请求被放回了队列
+		return nil
+	}
+	seckillOrder, err := sds.seckillDao.GetSeckillOrderByRequestId(request.RequestId)
+	if err != nil {
+		return errcode.Wrap("GetSeckillOrderError", err)
+	}
+	if seckillOrder.ID != 0 {
+		// 订单已经创建, 上次处理在写入结果前中断
+		result.OrderNo = seckillOrder.OrderNo
+		return sds.setResult(result)
+	}
+	order, err := sds.createOrder(request)
+	if err == nil {
+		result.OrderNo = order.OrderNo
+		return sds.setResult(result)
+	}
+	// 同一请求被并发处理时, 另一方已经创建了订单
+	seckillOrder, getErr := sds.seckillDao.GetSeckillOrderByRequestId(request.RequestId)
+	if getErr == nil && seckillOrder.ID != 0 {
+		result.OrderNo = seckillOrder.OrderNo
+		return sds.setResult(result)
+	}
+	log.Error("CreateSeckillOrderError", "request", request, "err", err)
+	result.Status = enum.SeckillResultFailed
+	result.Reason = seckillFailedReason(err)
+	// 先写入失败结果再退回名额, 中断后请求不会再被处理, 名额最多少退不会多退
+	if err = sds.setResult(result); err != nil {
+		return err
+	}
+	if revertErr := cache.RevertSeckillQuota(sds.ctx, request); revertErr != nil {
+		log.Error("RevertSeckillQuotaError", "request", request, "err", revertErr)
+	}
+	return nil
+}
+
+func (sds *SeckillDomainSvc) setResult(result *do.SeckillResult) error {
+	if err := cache.SetSeckillResult(sds.ctx, result); err != nil {
+		return errcode.Wrap("SetSeckillResultError", err)
+	}
+	return nil
+}
+
+// RecoverRequests 检查处理中列表: 已经写入处理结果的请求直接移除, 从队列取出超过 SeckillProcessingTimeout 仍没有结果的请求放回队列重新处理
+func (sds *SeckillDomainSvc) RecoverRequests() error {
+	requests, err := cache.GetProcessingSeckillRequests(sds.ctx)
+	if err != nil {
+		return errcode.Wrap("GetProcessingSeckillRequestsError", err)
+	}
+	log := logger.New(sds.ctx)
+	for _, processing := range requests {
+		result, err := cache.GetSeckillResult(sds.ctx, processing.Request.RequestId)
+		if err != nil {
+			return errcode.Wrap("GetSeckillResultError", err)
+		}
+		if result != nil && result.Status != enum.SeckillResultQueued {
+			if err = cache.AckSeckillRequest(sds.ctx, processing.Payload); err != nil {
+				return errcode.Wrap("AckSeckillRequestError", err)
+			}
+			continue
+		}
+		if time.Since(processing.PoppedAt) < enum.SeckillProcessingTimeout {
+			continue
+		}
+		log.Warn("RequeueSeckillRequest", "request", processing.Request, "poppedAt", processing.PoppedAt)
+		if err = cache.RequeueSeckillRequest(sds.ctx, processing.Payload); err != nil {
+			return errcode.Wrap("RequeueSeckillRequestError", err)
+		}
+	}
+	return nil
+}
+
+func (sds *SeckillDomainSvc) createOrder(request *do.SeckillRequest) (*do.Order, error) {
+	campaign, err := sds.GetCampaign(request.CampaignId)
+	if err != nil {
+		return nil, err
+	}
+	userAddressInfo, err := NewUserDomainSvc(sds.ctx).GetSingleAddress(request.UserAddressId)
+	if err != nil {
+		return nil, err
+	}
+	if userAddressInfo.UserId != request.UserId {
+		return nil, errcode.ErrOrderParams
+	}
+	return NewOrderDomainSvc(sds.ctx).CreateSeckillOrder(campaign, request, userAddressInfo)
+}
+
+// RevertOrderQuota 秒杀订单取消或关闭时退回预扣的秒杀名额和用户已抢数量, 不是秒杀订单时什么也不做
+func (sds *SeckillDomainSvc) RevertOrderQuota(orderId int64) error {
+	seckillOrder, err := sds.seckillDao.GetSeckillOrderByOrderId(orderId)
+	if err != nil {
+		return errcode.Wrap("RevertSeckillOrderQuotaError", err)
+	}
+	if seckillOrder.ID == 0 || seckillOrder.Reverted == 1 {
+		return nil
+	}
+	// 先标记再退回, 并发关闭同一订单时只有一方退回名额
+	affected, err := sds.seckillDao.MarkSeckillOrderReverted(seckillOrder.ID)
+	if err != nil {
+		return errcode.Wrap("RevertSeckillOrderQuotaError", err)
+	}
+	if affected == 0 {
+		return nil
+	}
+	request := &do.SeckillRequest{
+		RequestId:  seckillOrder.RequestId,
+		CampaignId: seckillOrder.CampaignId,
+		UserId:     seckillOrder.UserId,
+		Num:        seckillOrder.Num,
+	}
+	if err = cache.RevertSeckillQuota(sds.ctx, request); err != nil {
+		return errcode.Wrap("RevertSeckillOrderQuotaError", err)
+	}
+	return nil
+}
+
+// seckillFailedReason 返回给买家的失败原因, 非业务错误不暴露内部信息
+func seckillFailedReason(err error) string {
+	for _, appErr := range []*errcode.AppError{
+		errcode.ErrCommodityStockOut,
+		errcode.ErrCommodityNotExists,
+		errcode.ErrCommodityOffShelf,
+		errcode.ErrSeckillNotExists,
+		errcode.ErrOrderParams,
+	} {
+		if errors.Is(err, appErr) {
+			return appErr.Msg()
+		}
+	}
+	return errcode.ErrServer.Msg()
+}
@@ -0,0 +1,439 @@
+package domainservice
+
+import (
+	"context"
+	"errors"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// 库存两阶段模型:
+// 下单时在Redis中预占库存(stock -> reserved), 支付成功后确认为已售(reserved -> sold),
+// 订单取消或预占到期未支付时释放预占(reserved -> stock)
+
+const defaultStockReserveTTL = 15 * time.Minute
+
+type StockDomainSvc struct {
+	ctx          context.Context
+	commodityDao *dao.CommodityDao
+	orderDao     *dao.OrderDao
+}
+
+func NewStockDomainSvc(ctx context.Context) *StockDomainSvc {
+	return &StockDomainSvc{
+		ctx:          ctx,
+		commodityDao: dao.NewCommodityDao(ctx),
+		orderDao:     dao.NewOrderDao(ctx),
+	}
+}
+
+func stockReserveTTL() time.Duration {
+	if config.App.Order.StockReserveTTL > 0 {
+		return config.App.Order.StockReserveTTL
+	}
+	return defaultStockReserveTTL
+}
+
+// ReserveOrderStock 为订单的所有购物项预占库存
+// SKU的Redis库存过期或从未加载时, 从MySQL预热该SKU的库存后重试, 每个SKU只预热一次
+func (sds *StockDomainSvc) ReserveOrderStock(order *do.Order) error {
+	deadline := time.Now().Add(stockReserveTTL())
+	warmedItems := make(map[int64]struct{})
+	var err error
+	for {
+		err = retryStockItemLocked(func() error {
+			return cache.ReserveOrderStock(sds.ctx, order.OrderNo, order.UserId, order.Items, deadline)
+		})
+		var scriptErr *cache.StockScriptError
+		if !errors.As(err, &scriptErr) || scriptErr.Code != "E_ITEM_NOT_FOUND" {
+			break
+		}
+		if _, warmed := warmedItems[scriptErr.ItemId]; warmed {
+			break
+		}
+		warmedItems[scriptErr.ItemId] = struct{}{}
+		if warmErr := sds.WarmUpStockItem(scriptErr.ItemId); warmErr != nil {
+			return warmErr
+		}
+	}
+	var scriptErr *cache.StockScriptError
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_STOCK_INSUFFICIENT" {
+		return errcode.ErrCommodityStockOut.WithCause(err)
+	}
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_ITEM_LOCKED" {
+		return errcode.ErrTooManyRequests.WithCause(err)
+	}
+	if err != nil {
+		return errcode.Wrap("ReserveOrderStockError", err)
+	}
+	return nil
+}
+
+// ConfirmOrderStock 订单支付成功, 预占的库存转为已售
+func (sds *StockDomainSvc) ConfirmOrderStock(orderNo string) error {
+	err := retryStockItemLocked(func() error {
+		return cache.SettleOrderStockReservation(sds.ctx, orderNo, cache.StockSettleConfirm)
+	})
+	if errors.Is(err, cache.ErrStockReservationNotFound) {
+		// 预占已经超时释放, 库存可能已被其他订单占用, 不能再确认
+		return errcode.ErrOrderCanNotBeChanged.WithCause(err)
+	}
+	if err != nil {
+		return errcode.Wrap("ConfirmOrderStockError", err)
+	}
+	return nil
+}
+
+// ReconfirmOrderStock 预占已经超时释放后才收到支付结果时, 重新为订单预占库存并确认, 库存不足时返回 ErrCommodityStockOut
+func (sds *StockDomainSvc) ReconfirmOrderStock(order *do.Order) error {
+	if err := sds.ReserveOrderStock(order); err != nil {
+		return err
+	}
+	return sds.ConfirmOrderStock(order.OrderNo)
+}
+
+// ReleaseOrderStock 释放订单的库存预占, 预占已被处理过时直接返回成功
+func (sds *StockDomainSvc) ReleaseOrderStock(orderNo string) (released bool, err error) {
+	err = cache.SettleOrderStockReservation(sds.ctx, orderNo, cache.StockSettleRelease)
+	if errors.Is(err, cache.ErrStockReservationNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errcode.Wrap("ReleaseOrderStockError", err)
+	}
+	return true, nil
+}
+
+// GetExpiredReservationOrderNos 获取库存预占已到期的订单号
+func (sds *StockDomainSvc) GetExpiredReservationOrderNos(limit int64) ([]string, error) {
+	orderNos, err := cache.GetExpiredStockReservations(sds.ctx, time.Now(), limit)
+	if err != nil {
+		return nil, errcode.Wrap("GetExpiredReservationOrderNosError", err)
+	}
+	return orderNos, nil
+}
+
+// GetSkuStock 获取SKU的可售、预占、已售库存, Redis中没有时先从MySQL预热
+func (sds *StockDomainSvc) GetSkuStock(skuId int64) (*do.StockItem, error) {
+	stockItem, err := cache.GetStockItem(sds.ctx, skuId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSkuStockError", err)
+	}
+	if stockItem != nil {
+		return stockItem, nil
+	}
+	if err = sds.WarmUpStockItem(skuId); err != nil {
+		return nil, err
+	}
+	stockItem, err = cache.GetStockItem(sds.ctx, skuId)
+	if err != nil {
+		return nil, errcode.Wrap("GetSkuStockError", err)
+	}
+	if stockItem == nil {
+		logger.New(sds.ctx).Warn("SkuStockNotInitialized", "skuId", skuId)
+		return nil, errcode.ErrCommodityNotExists
+	}
+	return stockItem, nil
+}
+
+// WarmUpStockItem 从MySQL加载单个SKU的库存到Redis, 并登记到 mall:stock:init
+// 同一SKU只有抢到预热锁的请求访问MySQL, 其他请求等待预热完成, 避免库存key不存在时大量请求同时回源
+func (sds *StockDomainSvc) WarmUpStockItem(skuId int64) error {
+	lockToken, err := cache.LockStockWarmUp(sds.ctx, skuId, enum.StockWarmUpLockDuration)
+	if err != nil {
+		return sds.waitStockWarmUp(skuId)
+	}
+	defer cache.UnlockStockWarmUp(sds.ctx, skuId, lockToken)
+
+	exists, err := cache.StockItemExists(sds.ctx, skuId)
+	if err != nil {
+		return errcode.Wrap("WarmUpStockItemError", err)
+	}
+	if exists {
+		return nil
+	}
+	sku, err := sds.commodityDao.FindSkuById(skuId)
+	if err != nil {
+		return errcode.Wrap("WarmUpStockItemError", err)
+	}
+	if sku.ID == 0 {
+		return errcode.ErrCommodityNotExists
+	}
+	stockItem := &do.StockItem{
+		ItemID:    sku.ID,
+		Stock:     sku.StockNum,
+		InitStock: sku.StockNum,
+		Version:   1,
+		Modified:  time.Now(),
+	}
+	if err = cache.InitStockItems(sds.ctx, []*do.StockItem{stockItem}); err != nil {
+		return errcode.Wrap("WarmUpStockItemError", err)
+	}
+	logger.New(sds.ctx).Info("StockItemWarmedUp", "skuId", skuId, "stock", sku.StockNum)
+	return nil
+}
+
+// InitSkuStock 为新建的SKU初始化Redis库存
+func (sds *StockDomainSvc) InitSkuStock(skus []*do.CommoditySku) error {
+	stockItems := lo.Map(skus, func(sku *do.CommoditySku, _ int) *do.StockItem {
+		return &do.StockItem{
+			ItemID:    sku.ID,
+			Stock:     sku.StockNum,
+			InitStock: sku.StockNum,
+			Version:   1,
+			Modified:  time.Now(),
+		}
+	})
+	if err := cache.InitStockItems(sds.ctx, stockItems); err != nil {
+		return errcode.Wrap("InitSkuStockError", err)
+	}
+	return nil
+}
+
+// AdjustSkuStock 按增减量调整SKU在Redis中的可售库存, 再由 persist 把同样的增减量写入MySQL, 写入失败时撤销Redis的调整;
+// 整个过程持有SKU的库存锁, 避免库存对账在两次写入之间修复. Redis库存不存在时先从MySQL预热再调整
+func (sds *StockDomainSvc) AdjustSkuStock(deltas map[int64]int, persist func() error) error {
+	skuIds := make([]int64, 0, len(deltas))
+	for skuId, delta := range deltas {
+		if delta != 0 {
+			skuIds = append(skuIds, skuId)
+		}
+	}
+	// 按SKU ID顺序加锁, 避免两个调整互相等待
+	sort.Slice(skuIds, func(i, j int) bool { return skuIds[i] < skuIds[j] })
+	lockTokens, err := sds.lockStockItems(skuIds)
+	if err != nil {
+		return err
+	}
+	defer sds.unlockStockItems(lockTokens)
+
+	adjusted := make([]int64, 0, len(skuIds))
+	revertAdjusted := func() {
+		for _, skuId := range adjusted {
+			if revertErr := cache.AdjustRedisStock(sds.ctx, skuId, -deltas[skuId], lockTokens[skuId]); revertErr != nil {
+				logger.New(sds.ctx).Error("RevertSkuStockAdjustError", "skuId", skuId, "delta", deltas[skuId], "err", revertErr)
+			}
+		}
+	}
+	for _, skuId := range skuIds {
+		if err = sds.adjustRedisStock(skuId, deltas[skuId], lockTokens[skuId]); err != nil {
+			revertAdjusted()
+			return err
+		}
+		adjusted = append(adjusted, skuId)
+	}
+	if err = persist(); err != nil {
+		revertAdjusted()
+		return err
+	}
+	return nil
+}
+
+func (sds *StockDomainSvc) adjustRedisStock(skuId int64, delta int, lockToken string) error {
+	err := cache.AdjustRedisStock(sds.ctx, skuId, delta, lockToken)
+	var scriptErr *cache.StockScriptError
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_ITEM_NOT_FOUND" {
+		if warmErr := sds.WarmUpStockItem(skuId); warmErr != nil {
+			return warmErr
+		}
+		err = cache.AdjustRedisStock(sds.ctx, skuId, delta, lockToken)
+	}
+	if errors.As(err, &scriptErr) && scriptErr.Code == "E_STOCK_INSUFFICIENT" {
+		return errcode.ErrCommodityStockOut.WithCause(err)
+	}
+	if err != nil {
+		return errcode.Wrap("AdjustSkuStockError", err)
+	}
+	return nil
+}
+
+// lockStockItems 依次获取SKU的库存锁, 被占用时最多等待 StockItemLockWaitTimeout, 失败时释放已获取的锁
+func (sds *StockDomainSvc) lockStockItems(skuIds []int64) (map[int64]string, error) {
+	lockTokens := make(map[int64]string, len(skuIds))
+	deadline := time.Now().Add(enum.StockItemLockWaitTimeout)
+	for _, skuId := range skuIds {
+		for {
+			lockToken, err := cache.LockStockItem(sds.ctx, skuId, enum.StockItemLockDuration)
+			if err == nil {
+				lockTokens[skuId] = lockToken
+				break
+			}
+			if time.Now().After(deadline) {
+				sds.unlockStockItems(lockTokens)
+				return nil, errcode.ErrTooManyRequests.WithCause(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return lockTokens, nil
+}
+
+func (sds *StockDomainSvc) unlockStockItems(lockTokens map[int64]string) {
+	for skuId, lockToken := range lockTokens {
+		if err := cache.UnlockStockItem(sds.ctx, skuId, lockToken); err != nil {
+			logger.New(sds.ctx).Error("UnlockStockItemError", "skuId", skuId, "err", err)
+		}
+	}
+}
+
+// retryStockItemLocked 执行库存脚本, 商品被对账修复或后台调整锁定时在 StockItemLockWaitTimeout 内重试
+func retryStockItemLocked(run func() error) error {
+	deadline := time.Now().Add(enum.StockItemLockWaitTimeout)
+	for {
+		err := run()
+		var scriptErr *cache.StockScriptError
+		if !errors.As(err, &scriptErr) || scriptErr.Code != "E_ITEM_LOCKED" || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitStockWarmUp 等待其他请求完成SKU的库存预热
+func (sds *StockDomainSvc) waitStockWarmUp(skuId int64) error {
+	deadline := time.Now().Add(enum.StockWarmUpWaitTimeout)
+	for time.Now().Before(deadline) {
+		exists, err := cache.StockItemExists(sds.ctx, skuId)
+		if err != nil {
+			return errcode.Wrap("WaitStockWarmUpError", err)
+		}
+		if exists {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return errcode.ErrTooManyRequests
+}
+
+// CheckStockConsistency 对账 mall:stock:init 中所有SKU的Redis库存与MySQL库存, 返回不一致的SKU,
+// repair 不为空时在SKU库存锁内以另一方为准修复 repair 指定的一方
+func (sds *StockDomainSvc) CheckStockConsistency(repair string) ([]*do.StockDiff, error) {
+	if repair != enum.StockRepairNone && repair != enum.StockRepairRedis && repair != enum.StockRepairMysql {
+		return nil, errcode.ErrParams
+	}
+	itemIds, err := cache.GetInitializedStockItemIds(sds.ctx)
+	if err != nil {
+		return nil, errcode.Wrap("CheckStockConsistencyError", err)
+	}
+	if len(itemIds) == 0 {
+		return nil, nil
+	}
+	skus, err := sds.commodityDao.FindSkus(itemIds)
+	if err != nil {
+		return nil, errcode.Wrap("CheckStockConsistencyError", err)
+	}
+	skuMap := lo.SliceToMap(skus, func(item *model.CommoditySku) (int64, *model.CommoditySku) {
+		return item.ID, item
+	})
+	log := logger.New(sds.ctx)
+	diffs := make([]*do.StockDiff, 0)
+	for _, itemId := range itemIds {
+		sku, exists := skuMap[itemId]
+		if !exists {
+			log.Warn("StockCheckSkuNotFound", "itemId", itemId)
+			continue
+		}
+		diff, err := sds.diffStockItem(dao.DB(), sku.ID, sku.StockNum)
+		if err != nil {
+			log.Error("StockCheckItemError", "itemId", itemId, "err", err)
+			continue
+		}
+		if diff == nil {
+			continue
+		}
+		if repair != enum.StockRepairNone && !diff.RedisMissing {
+			if err = sds.repairStockItem(diff.ItemID, repair); err != nil {
+				log.Error("StockRepairItemError", "itemId", itemId, "repair", repair, "err", err)
+			} else {
+				diff.Repaired = repair
+			}
+		}
+		log.Warn("StockInconsistent", "diff", diff)
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// diffStockItem 计算单个SKU的库存差异, 两边一致时返回 nil; db 为读取待落库订单使用的连接, 修复时传入事务
+func (sds *StockDomainSvc) diffStockItem(db *gorm.DB, itemId int64, mysqlStock int) (*do.StockDiff, error) {
+	stockItem, err := cache.GetStockItem(sds.ctx, itemId)
+	if err != nil {
+		return nil, err
+	}
+	diff := &do.StockDiff{ItemID: itemId, MysqlStock: mysqlStock}
+	if stockItem == nil {
+		diff.RedisMissing = true
+		return diff, nil
+	}
+	pending, err := sds.pendingConfirmedQty(db, itemId)
+	if err != nil {
+		return nil, err
+	}
+	diff.RedisStock = stockItem.Stock
+	diff.RedisReserved = stockItem.Reserved
+	diff.PendingConfirmed = pending
+	diff.Diff = stockItem.Stock + stockItem.Reserved - (mysqlStock - pending)
+	if diff.Diff == 0 {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// pendingConfirmedQty 统计已在Redis确认售出、但订单还没在MySQL结算(stock_num 尚未扣减)的数量,
+// 预占确认后订单不会再被超时关闭, 这样的订单一定还是待支付状态
+func (sds *StockDomainSvc) pendingConfirmedQty(db *gorm.DB, itemId int64) (int, error) {
+	orderSkuNums, err := sds.orderDao.GetUnpaidOrderSkuNumsInTx(db, itemId)
+	if err != nil {
+		return 0, err
+	}
+	if len(orderSkuNums) == 0 {
+		return 0, nil
+	}
+	orderNos := lo.Keys(orderSkuNums)
+	confirmed, err := cache.GetConfirmedStockReservations(sds.ctx, orderNos)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for orderNo, num := range orderSkuNums {
+		if confirmed[orderNo] {
+			pending += num
+		}
+	}
+	return pending, nil
+}
+
+// repairStockItem 持有SKU库存锁并在事务内锁住SKU行后重新计算差异并修复: 库存锁阻止Redis中的预占确认和后台调整,
+// 行锁阻止订单结算扣减MySQL库存, 修复基于同一时刻的两边数据, 也避免多个对账实例同时修复同一SKU
+func (sds *StockDomainSvc) repairStockItem(itemId int64, repair string) error {
+	lockToken, err := cache.LockStockItem(sds.ctx, itemId, enum.StockItemLockDuration)
+	if err != nil {
+		return err
+	}
+	defer cache.UnlockStockItem(sds.ctx, itemId, lockToken)
+
+	return dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		sku, err := sds.commodityDao.LockSkuInTx(tx, itemId)
+		if err != nil || sku.ID == 0 {
+			return err
+		}
+		diff, err := sds.diffStockItem(tx, itemId, sku.StockNum)
+		if err != nil || diff == nil || diff.RedisMissing {
+			return err
+		}
+		if repair == enum.StockRepairRedis {
+			return cache.RepairRedisStock(sds.ctx, itemId, diff.MysqlStock-diff.PendingConfirmed, lockToken)
+		}
+		return sds.commodityDao.UpdateSkuStockNumInTx(tx, itemId, diff.RedisStock+diff.RedisReserved+diff.PendingConfirmed)
+	})
+}
@@ -171,7 +171,8 @@ func (us *UserDomainSvc) RefreshToken(refreshToken string) (*do.TokenInfo, error
 	return tokenInfo, nil
 }
 
-func (us *UserDomainSvc) LoginUser(LoginName, plainPassword, platform string) (*do.TokenInfo, error) {
+// LoginUser 用户登录, cartToken 不为空时把未登录时的购物车合并到用户的购物车, 合并失败不影响登录
+func (us *UserDomainSvc) LoginUser(LoginName, plainPassword, platform, cartToken string) (*do.TokenInfo, error) {
 	existedUser, err := us.userDao.FindUserByLoginName(LoginName)
 	if err != nil {
 		return nil, errcode.Wrap("UserDomainSvcLoginUserError", err)
@@ -184,7 +185,15 @@ func (us *UserDomainSvc) LoginUser(LoginName, plainPassword, platform string) (*
 	}
 
 	tokenInfo, err := us.GenAuthToken(existedUser.ID, platform, "")
-	return tokenInfo, err
+	if err != nil {
+		return nil, err
+	}
+	if cartToken != "" {
+		if _, mergeErr := NewCartDomainSvc(us.ctx).MergeGuestCart(cartToken, existedUser.ID); mergeErr != nil {
+			logger.New(us.ctx).Error("LoginMergeGuestCartError", "userId", existedUser.ID, "err", mergeErr)
+		}
+	}
+	return tokenInfo, nil
 }
 
 func (us *UserDomainSvc) LogoutUser(userId int64, platform string) error {
@@ -0,0 +1,261 @@
+package domainservice
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/dao"
+	"github.com/Ian-zy0329/go-mall/dal/model"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// 会员等级规则:
+//   - 订单完成(确认收货并全部评价)后按支付金额获得成长值, 每 enum.VipGrowthMoneyUnit 分1点
+//   - 定时任务按最近 vip.growth_window_days 天的成长值计算可以达到的等级, 高于当前等级时升级, 有效期 vip.level_days 天
+//   - 等级到期后按成长值重新计算, 成长值不够时降级
+//   - 配置了 sku_id 的等级可以作为商品购买, 订单支付后获得该等级, 已有相同或更高等级时延长当前等级的有效期
+//   - 订单退款后扣回订单获得的成长值, 购买会员的订单从当前等级的有效期中扣回购买的天数
+
+type VipDomainSvc struct {
+	ctx    context.Context
+	vipDao *dao.VipDao
+}
+
+func NewVipDomainSvc(ctx context.Context) *VipDomainSvc {
+	return &VipDomainSvc{
+		ctx:    ctx,
+		vipDao: dao.NewVipDao(ctx),
+	}
+}
+
+// GetUserMembership 用户当前的会员等级和成长值
+func (vds *VipDomainSvc) GetUserMembership(userId int64) (*do.UserMembership, error) {
+	membershipModel, err := vds.vipDao.GetUserMembership(userId)
+	if err != nil {
+		return nil, errcode.Wrap("GetUserMembershipError", err)
+	}
+	growths, err := vds.vipDao.SumUsersGrowthSince([]int64{userId}, vipGrowthSince(time.Now()))
+	if err != nil {
+		return nil, errcode.Wrap("GetUserMembershipError", err)
+	}
+	membership := &do.UserMembership{
+		UserId:        userId,
+		Growth:        membershipModel.Growth,
+		WindowGrowth:  growths[userId],
+		LevelExpireAt: membershipModel.LevelExpireAt,
+	}
+	if membershipActive(membershipModel, time.Now()) {
+		membership.Level = membershipModel.Level
+		membership.LevelInfo = vipLevel(membershipModel.Level)
+	}
+	membership.NextLevel, _ = lo.Find(vipLevels(), func(level *do.VipLevel) bool {
+		return level.Level > membership.Level && level.MinGrowth > 0
+	})
+	return membership, nil
+}
+
+// GetUserActiveLevel 用户当前有效的会员等级, 普通用户或等级已到期时返回 nil
+func (vds *VipDomainSvc) GetUserActiveLevel(userId int64) (*do.VipLevel, error) {
+	membershipModel, err := vds.vipDao.GetUserMembership(userId)
+	if err != nil {
+		return nil, errcode.Wrap("GetUserActiveLevelError", err)
+	}
+	if !membershipActive(membershipModel, time.Now()) {
+		return nil, nil
+	}
+	return vipLevel(membershipModel.Level), nil
+}
+
+// AddOrderGrowthInTx 在完成订单的事务中按支付金额给用户增加成长值, 每个订单只增加一次
+func (vds *VipDomainSvc) AddOrderGrowthInTx(tx *gorm.DB, order *do.Order) error {
+	growth := order.PayMoney / enum.VipGrowthMoneyUnit
+	if growth <= 0 {
+		return nil
+	}
+	_, err := vds.vipDao.AddOrderGrowthInTx(tx, &model.UserGrowthLog{
+		UserId:  order.UserId,
+		OrderId: order.ID,
+		Growth:  growth,
+	})
+	return err
+}
+
+// GrantPurchasedMembershipInTx 订单支付后发放订单中购买的会员, 订单中有多个会员商品时使用最高的等级并累加天数
+func (vds *VipDomainSvc) GrantPurchasedMembershipInTx(tx *gorm.DB, userId, orderId int64, items []*do.OrderItem) error {
+	var purchased *do.VipLevel
+	days := 0
+	for _, item := range items {
+		level, found := lo.Find(vipLevels(), func(level *do.VipLevel) bool {
+			return level.SkuId > 0 && level.SkuId == item.SkuId && level.Days > 0
+		})
+		if !found {
+			continue
+		}
+		days += level.Days * item.CommodityNum
+		if purchased == nil || level.Level > purchased.Level {
+			purchased = level
+		}
+	}
+	if purchased == nil {
+		return nil
+	}
+	membership, err := vds.vipDao.LockUserMembershipInTx(tx, userId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if membershipActive(membership, now) && membership.Level >= purchased.Level {
+		membership.LevelExpireAt = membership.LevelExpireAt.AddDate(0, 0, days)
+	} else {
+		membership.Level = purchased.Level
+		membership.LevelExpireAt = now.AddDate(0, 0, days)
+	}
+	membership.UserId = userId
+	if err = vds.vipDao.SaveUserMembershipInTx(tx, membership); err != nil {
+		return err
+	}
+	return vds.vipDao.CreateMembershipPurchaseInTx(tx, &model.UserMembershipPurchase{
+		UserId:  userId,
+		OrderId: orderId,
+		Level:   purchased.Level,
+		Days:    days,
+	})
+}
+
+// RevokeOrderGrowth 订单退款后扣回订单获得的成长值, 订单没有获得成长值或已经扣回过时不处理
+func (vds *VipDomainSvc) RevokeOrderGrowth(orderId int64) error {
+	err := dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		_, err := vds.vipDao.RevokeOrderGrowthInTx(tx, orderId)
+		return err
+	})
+	if err != nil {
+		return errcode.Wrap("RevokeOrderGrowthError", err)
+	}
+	return nil
+}
+
+// RevokePurchasedMembership 订单退款后从会员当前等级的有效期中扣回订单购买的天数, 扣回后已经到期的等级
+// 由定时任务按成长值重新计算; 订单没有购买会员或已经扣回过时不处理
+func (vds *VipDomainSvc) RevokePurchasedMembership(orderId int64) error {
+	err := dao.DBMaster().Transaction(func(tx *gorm.DB) error {
+		purchase, err := vds.vipDao.LockMembershipPurchaseInTx(tx, orderId)
+		if err != nil || purchase.ID == 0 || purchase.Revoked == 1 {
+			return err
+		}
+		if err = vds.vipDao.MarkMembershipPurchaseRevokedInTx(tx, purchase.ID); err != nil {
+			return err
+		}
+		membership, err := vds.vipDao.LockUserMembershipInTx(tx, purchase.UserId)
+		if err != nil || membership.ID == 0 {
+			return err
+		}
+		membership.LevelExpireAt = membership.LevelExpireAt.AddDate(0, 0, -purchase.Days)
+		return vds.vipDao.SaveUserMembershipInTx(tx, membership)
+	})
+	if err != nil {
+		return errcode.Wrap("RevokePurchasedMembershipError", err)
+	}
+	return nil
+}
+
+// RefreshLevels 按成长值重新计算所有会员的等级, 返回等级有变化的会员数量
+func (vds *VipDomainSvc) RefreshLevels() (int, error) {
+	now := time.Now()
+	changed := 0
+	var afterId int64
+	for {
+		memberships, err := vds.vipDao.GetMembershipsAfter(afterId, enum.VipRefreshBatchSize)
+		if err != nil {
+			return changed, errcode.Wrap("RefreshVipLevelsError", err)
+		}
+		if len(memberships) == 0 {
+			return changed, nil
+		}
+		userIds := lo.Map(memberships, func(membership *model.UserMembership, _ int) int64 { return membership.UserId })
+		growths, err := vds.vipDao.SumUsersGrowthSince(userIds, vipGrowthSince(now))
+		if err != nil {
+			return changed, errcode.Wrap("RefreshVipLevelsError", err)
+		}
+		for _, membership := range memberships {
+			level, expireAt := refreshedLevel(membership, growths[membership.UserId], now)
+			if level == membership.Level && expireAt.Equal(membership.LevelExpireAt) {
+				continue
+			}
+			if err = vds.vipDao.UpdateMembershipLevel(membership, level, expireAt); err != nil {
+				return changed, errcode.Wrap("RefreshVipLevelsError", err)
+			}
+			changed++
+		}
+		afterId = memberships[len(memberships)-1].ID
+	}
+}
+
+// refreshedLevel 按统计周期内的成长值和配置的会员等级计算会员新的等级和到期时间
+func refreshedLevel(membership *model.UserMembership, growth int, now time.Time) (int, time.Time) {
+	return RefreshVipLevel(membership.Level, membership.LevelExpireAt, growth, vipLevels(), vipLevelDays(), now)
+}
+
+// RefreshVipLevel 按统计周期内的成长值计算会员新的等级和到期时间, levels 按等级从低到高排序, levelDays 为升级后等级的有效天数:
+// 可以达到的等级高于当前有效的等级时升级并重新计算有效期, 当前等级到期且成长值不够任何等级时降为普通用户
+func RefreshVipLevel(level int, expireAt time.Time, growth int, levels []*do.VipLevel, levelDays int, now time.Time) (int, time.Time) {
+	current := 0
+	if level > 0 && expireAt.After(now) {
+		current = level
+	}
+	earned := 0
+	for _, vipLevel := range levels {
+		if vipLevel.MinGrowth > 0 && growth >= vipLevel.MinGrowth {
+			earned = vipLevel.Level
+		}
+	}
+	if earned > current {
+		return earned, now.AddDate(0, 0, levelDays)
+	}
+	return current, expireAt
+}
+
+func membershipActive(membership *model.UserMembership, now time.Time) bool {
+	return membership.Level > 0 && membership.LevelExpireAt.After(now)
+}
+
+// vipLevels 配置的会员等级, 按等级从低到高排序
+func vipLevels() []*do.VipLevel {
+	levels := lo.Map(config.App.Vip.Levels, func(level *config.VipLevelConfig, _ int) *do.VipLevel {
+		return &do.VipLevel{
+			Level:        level.Level,
+			Name:         level.Name,
+			DiscountRate: level.DiscountRate,
+			MinGrowth:    level.MinGrowth,
+			SkuId:        level.SkuId,
+			Days:         level.Days,
+		}
+	})
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Level < levels[j].Level })
+	return levels
+}
+
+func vipLevel(level int) *do.VipLevel {
+	vipLevel, _ := lo.Find(vipLevels(), func(vipLevel *do.VipLevel) bool { return vipLevel.Level == level })
+	return vipLevel
+}
+
+// vipGrowthSince 计算会员等级时统计的成长值的开始时间
+func vipGrowthSince(now time.Time) time.Time {
+	days := config.App.Vip.GrowthWindowDays
+	if days <= 0 {
+		days = enum.DefaultVipGrowthWindowDays
+	}
+	return now.AddDate(0, 0, -days)
+}
+
+func vipLevelDays() int {
+	if config.App.Vip.LevelDays > 0 {
+		return config.App.Vip.LevelDays
+	}
+	return enum.DefaultVipLevelDays
+}
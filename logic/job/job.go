@@ -0,0 +1,125 @@
+package job
+
+import (
+	"context"
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/common/logger"
+	"github.com/Ian-zy0329/go-mall/config"
+	"github.com/Ian-zy0329/go-mall/dal/cache"
+	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"time"
+)
+
+// 后台定时任务, 随HTTP服务一起启动, ctx 取消后退出
+// 每个实例都会启动定时任务, 同一个任务同时只有抢到任务锁的实例执行; 秒杀队列的消费在所有实例上并行
+
+func Start(ctx context.Context) {
+	go every(ctx, time.Minute, "ReleaseExpiredStockReservations", func(ctx context.Context) error {
+		return appservice.NewOrderAppSvc(ctx).ReleaseExpiredStockReservations()
+	})
+	stockCheckInterval := config.App.StockCheck.Interval
+	if stockCheckInterval <= 0 {
+		stockCheckInterval = enum.DefaultStockCheckInterval
+	}
+	go every(ctx, stockCheckInterval, "CheckStockConsistency", func(ctx context.Context) error {
+		_, err := appservice.NewCommodityAppSvc(ctx).CheckStockConsistency(config.App.StockCheck.Repair)
+		return err
+	})
+	go every(ctx, time.Minute, "PreloadSeckillCampaigns", func(ctx context.Context) error {
+		return appservice.NewSeckillAppSvc(ctx).PreloadCampaigns()
+	})
+	go every(ctx, enum.SeckillRecoverInterval, "RecoverSeckillRequests", func(ctx context.Context) error {
+		return appservice.NewSeckillAppSvc(ctx).RecoverRequests()
+	})
+	go every(ctx, enum.DefaultSearchTrimInterval, "TrimSearchQueries", func(ctx context.Context) error {
+		_, err := appservice.NewCommodityAppSvc(ctx).TrimSearchQueries()
+		return err
+	})
+	go every(ctx, enum.DefaultRelatedRefreshInterval, "RefreshRelatedCommodities", func(ctx context.Context) error {
+		_, err := appservice.NewCommodityAppSvc(ctx).RefreshRelatedCommodities()
+		return err
+	})
+	vipRefreshInterval := config.App.Vip.RefreshInterval
+	if vipRefreshInterval <= 0 {
+		vipRefreshInterval = enum.DefaultVipRefreshInterval
+	}
+	go every(ctx, vipRefreshInterval, "RefreshVipLevels", func(ctx context.Context) error {
+		_, err := appservice.NewUserAppSvc(ctx).RefreshVipLevels()
+		return err
+	})
+	pointsExpireInterval := config.App.Points.ExpireInterval
+	if pointsExpireInterval <= 0 {
+		pointsExpireInterval = enum.DefaultPointsExpireInterval
+	}
+	go every(ctx, pointsExpireInterval, "ExpirePoints", func(ctx context.Context) error {
+		_, err := appservice.NewUserAppSvc(ctx).ExpirePoints()
+		return err
+	})
+	seckillWorkers := config.App.Seckill.Workers
+	if seckillWorkers <= 0 {
+		seckillWorkers = enum.DefaultSeckillWorkers
+	}
+	for i := 0; i < seckillWorkers; i++ {
+		go loop(ctx, "ConsumeSeckillRequest", func(ctx context.Context) error {
+			return appservice.NewSeckillAppSvc(ctx).ConsumeRequest()
+		})
+	}
+}
+
+func every(ctx context.Context, interval time.Duration, name string, task func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runLockedTask(ctx, interval, name, task)
+		}
+	}
+}
+
+// runLockedTask 抢到任务锁后执行一次任务, 锁在任务间隔后自动过期, 多个实例每个间隔内只有一个执行任务.
+// 任务执行成功时不释放锁, 其他实例的定时器在锁过期前触发时直接跳过; 任务失败时释放锁, 由其他实例下次触发时重试
+func runLockedTask(ctx context.Context, interval time.Duration, name string, task func(ctx context.Context) error) {
+	token, err := cache.LockJob(ctx, name, interval)
+	if err != nil {
+		return
+	}
+	if runTask(ctx, name, task) {
+		return
+	}
+	if err = cache.UnlockJob(ctx, name, token); err != nil {
+		logger.New(ctx).Error("UnlockJobError", "job", name, "err", err)
+	}
+}
+
+// loop 连续执行任务, 用于消费队列这类自身会阻塞等待的任务, 出错时等待一秒再继续
+func loop(ctx context.Context, name string, task func(ctx context.Context) error) {
+	for ctx.Err() == nil {
+		if !runTask(ctx, name, task) {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// runTask 执行一次任务, 任务出错或panic时返回 false
+func runTask(ctx context.Context, name string, task func(ctx context.Context) error) (ok bool) {
+	log := logger.New(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("JobPanic", "job", name, "panic", r)
+			ok = false
+		}
+	}()
+	if err := task(ctx); err != nil {
+		if ctx.Err() == nil {
+			log.Error("JobError", "job", name, "err", err)
+		}
+		return false
+	}
+	return true
+}
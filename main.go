@@ -7,6 +7,7 @@ import (
 	"github.com/Ian-zy0329/go-mall/common/logger"
 	"github.com/Ian-zy0329/go-mall/config"
 	"github.com/Ian-zy0329/go-mall/logic/appservice"
+	"github.com/Ian-zy0329/go-mall/logic/job"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"os"
@@ -30,11 +31,15 @@ func main() {
 	if err := stockService.InitRedisStock(); err != nil {
 		log.Error("Failed to init stock: %v", err)
 	}
+	//后台定时任务
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	job.Start(jobCtx)
 	//平滑关闭
 	done := make(chan os.Signal)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-done
+		stopJobs()
 		if err := server.Shutdown(context.Background()); err != nil {
 			log.Error("ShutdownServerError", "err", err)
 		}
@@ -0,0 +1,40 @@
+-- 后台调整商品的可售库存: stock 增加 delta(可以为负), 调整后不能小于0
+-- KEYS[1]: 库存key
+-- KEYS[2]: 库存流水key
+-- KEYS[3]: 库存锁key, 调整Redis和MySQL期间需要一直持有锁, 避免对账在两次写入之间修复
+-- ARGV[1]: 调整数量
+-- ARGV[2]: 当前时间
+-- ARGV[3]: 库存锁的token
+
+if redis.call("GET", KEYS[3]) ~= ARGV[3] then
+    return {"err", "E_ITEM_LOCKED", "Item lock not held", KEYS[1]}
+end
+if redis.call("EXISTS", KEYS[1]) == 0 then
+    return {"err", "E_ITEM_NOT_FOUND", "Item not found", KEYS[1]}
+end
+
+local oldStock = tonumber(redis.call("HGET", KEYS[1], "stock"))
+if oldStock == nil then
+    return {"err", "E_INVALID_STOCK_DATA", "Invalid stock data", KEYS[1]}
+end
+local delta = tonumber(ARGV[1])
+if oldStock + delta < 0 then
+    return {"err", "E_STOCK_INSUFFICIENT", "Insufficient stock", KEYS[1]}
+end
+
+local newStock = redis.call("HINCRBY", KEYS[1], "stock", delta)
+redis.call("HINCRBY", KEYS[1], "version", 1)
+redis.call("HSET", KEYS[1], "modified", ARGV[2])
+
+local logEntry = {
+    type = "adjust",
+    item_id = tonumber(string.match(KEYS[1], "item:(%d+)$")),
+    quantity = delta,
+    old_stock = oldStock,
+    new_stock = newStock,
+    timestamp = ARGV[2],
+    is_rollback = false
+}
+redis.call("RPUSH", KEYS[2], cjson.encode(logEntry))
+
+return {"SUCCESS", newStock}
@@ -0,0 +1,37 @@
+-- 按MySQL库存修复Redis库存: 可售库存 = 应有库存 - 当前预占, 在脚本内计算避免与预占操作并发时算错
+-- KEYS[1]: 库存key
+-- KEYS[2]: 库存流水key
+-- KEYS[3]: 库存锁key, 只有持有锁的对账任务可以修复
+-- ARGV[1]: 应有库存(可售 + 预占)
+-- ARGV[2]: 当前时间
+-- ARGV[3]: 库存锁的token
+
+if redis.call("GET", KEYS[3]) ~= ARGV[3] then
+    return {"err", "E_ITEM_LOCKED", "Item lock not held", KEYS[1]}
+end
+if redis.call("EXISTS", KEYS[1]) == 0 then
+    return {"err", "E_ITEM_NOT_FOUND", "Item not found", KEYS[1]}
+end
+
+local oldStock = tonumber(redis.call("HGET", KEYS[1], "stock")) or 0
+local reserved = tonumber(redis.call("HGET", KEYS[1], "reserved")) or 0
+local newStock = tonumber(ARGV[1]) - reserved
+if newStock < 0 then
+    newStock = 0
+end
+
+redis.call("HSET", KEYS[1], "stock", newStock, "modified", ARGV[2])
+redis.call("HINCRBY", KEYS[1], "version", 1)
+
+local logEntry = {
+    type = "repair",
+    item_id = tonumber(string.match(KEYS[1], "item:(%d+)$")),
+    quantity = newStock - oldStock,
+    old_stock = oldStock,
+    new_stock = newStock,
+    timestamp = ARGV[2],
+    is_rollback = false
+}
+redis.call("RPUSH", KEYS[2], cjson.encode(logEntry))
+
+return {"SUCCESS", newStock}
@@ -0,0 +1,79 @@
+-- 下单时预占库存: 库存从 stock 转移到 reserved, 并记录订单的预占明细
+-- 预占明细不设置过期时间, 由支付确认、取消或到期释放时处理, 不能让还没释放的预占随key过期丢失
+-- KEYS[1]: 订单预占记录key
+-- KEYS[2]: 预占到期时间有序集合key
+-- KEYS[3..]: 依次为每个商品的 库存key, 库存流水key, 库存锁key
+-- ARGV[1]: 订单号
+-- ARGV[2]: 用户ID
+-- ARGV[3]: 预占到期时间戳(秒)
+-- ARGV[4]: 当前时间
+-- ARGV[5..]: 每个商品的预占数量, 顺序与KEYS中的商品一致, 同一商品可以出现多次
+
+if redis.call("EXISTS", KEYS[1]) == 1 then
+    return {"err", "E_RESERVATION_EXISTS", "Reservation already exists"}
+end
+
+local itemCount = (#KEYS - 2) / 3
+
+-- 同一商品在订单中出现多次时合并数量, 按合计数量检查和预占
+local items = {}
+local quantities = {}
+for i = 1, itemCount do
+    local stockKey = KEYS[i * 3]
+    if quantities[stockKey] == nil then
+        quantities[stockKey] = 0
+        table.insert(items, i)
+    end
+    quantities[stockKey] = quantities[stockKey] + tonumber(ARGV[4 + i])
+end
+
+-- 先检查所有商品的库存, 全部满足后再扣减, 一个订单的预占要么全部成功要么全部失败
+-- 商品被对账修复或后台调整锁定时不预占, 由调用方稍后重试
+for _, i in ipairs(items) do
+    local stockKey = KEYS[i * 3]
+    local lockKey = KEYS[2 + i * 3]
+    local qty = quantities[stockKey]
+    if redis.call("EXISTS", lockKey) == 1 then
+        return {"err", "E_ITEM_LOCKED", "Item locked", stockKey}
+    end
+    if redis.call("EXISTS", stockKey) == 0 then
+        return {"err", "E_ITEM_NOT_FOUND", "Item not found", stockKey}
+    end
+    local stock = tonumber(redis.call("HGET", stockKey, "stock"))
+    if stock == nil then
+        return {"err", "E_INVALID_STOCK_DATA", "Invalid stock data", stockKey}
+    end
+    if stock < qty then
+        return {"err", "E_STOCK_INSUFFICIENT", "Insufficient stock", stockKey}
+    end
+end
+
+for _, i in ipairs(items) do
+    local stockKey = KEYS[i * 3]
+    local logKey = KEYS[1 + i * 3]
+    local qty = quantities[stockKey]
+    local itemId = string.match(stockKey, "item:(%d+)$")
+    local oldStock = tonumber(redis.call("HGET", stockKey, "stock"))
+    local newStock = redis.call("HINCRBY", stockKey, "stock", -qty)
+    redis.call("HINCRBY", stockKey, "reserved", qty)
+    redis.call("HINCRBY", stockKey, "version", 1)
+    redis.call("HSET", stockKey, "modified", ARGV[4])
+    redis.call("HINCRBY", KEYS[1], itemId, qty)
+
+    local logEntry = {
+        type = "reserve",
+        order_id = ARGV[1],
+        user_id = tonumber(ARGV[2]),
+        item_id = tonumber(itemId),
+        quantity = qty,
+        old_stock = oldStock,
+        new_stock = newStock,
+        timestamp = ARGV[4],
+        is_rollback = false
+    }
+    redis.call("RPUSH", logKey, cjson.encode(logEntry))
+end
+
+redis.call("ZADD", KEYS[2], tonumber(ARGV[3]), ARGV[1])
+
+return {"SUCCESS"}
@@ -0,0 +1,89 @@
+-- 秒杀预扣名额: 只在Redis中完成限流、排队长度检查、活动时间、用户限购和名额扣减,
+-- 成功后把请求放入下单队列并写入排队中的结果
+-- KEYS[1]: 活动名额key
+-- KEYS[2]: 活动名额流水key
+-- KEYS[3]: 活动用户已抢数量key
+-- KEYS[4]: 下单队列key
+-- KEYS[5]: 请求结果key
+-- KEYS[6]: 活动当前秒的请求计数key
+-- ARGV[1]: 用户ID
+-- ARGV[2]: 抢购数量
+-- ARGV[3]: 当前时间戳(秒)
+-- ARGV[4]: 当前时间
+-- ARGV[5]: 请求ID
+-- ARGV[6]: 入队的请求数据
+-- ARGV[7]: 排队中的结果数据
+-- ARGV[8]: 结果key的过期时间(秒)
+-- ARGV[9]: 活动每秒请求数上限, 0-不限制
+-- ARGV[10]: 下单队列长度上限
+
+if redis.call("EXISTS", KEYS[1]) == 0 then
+    return {"err", "E_CAMPAIGN_NOT_FOUND", "Campaign not found"}
+end
+
+local rateLimit = tonumber(ARGV[9])
+if rateLimit > 0 then
+    local count = redis.call("INCR", KEYS[6])
+    if count == 1 then
+        redis.call("EXPIRE", KEYS[6], 2)
+    end
+    if count > rateLimit then
+        return {"err", "E_TOO_MANY_REQUESTS", "Too many requests"}
+    end
+end
+
+if redis.call("LLEN", KEYS[4]) >= tonumber(ARGV[10]) then
+    return {"err", "E_TOO_MANY_REQUESTS", "Queue is full"}
+end
+
+local now = tonumber(ARGV[3])
+local campaign = redis.call("HMGET", KEYS[1], "quota", "userLimit", "startAt", "endAt")
+local quota = tonumber(campaign[1])
+local userLimit = tonumber(campaign[2])
+local startAt = tonumber(campaign[3])
+local endAt = tonumber(campaign[4])
+if quota == nil or userLimit == nil or startAt == nil or endAt == nil then
+    return {"err", "E_INVALID_CAMPAIGN_DATA", "Invalid campaign data"}
+end
+if now < startAt then
+    return {"err", "E_CAMPAIGN_NOT_STARTED", "Campaign not started"}
+end
+if now >= endAt then
+    return {"err", "E_CAMPAIGN_ENDED", "Campaign ended"}
+end
+
+local userId = ARGV[1]
+local qty = tonumber(ARGV[2])
+local bought = tonumber(redis.call("HGET", KEYS[3], userId) or "0")
+if bought + qty > userLimit then
+    return {"err", "E_USER_LIMIT", "Exceeds user limit"}
+end
+if quota < qty then
+    return {"err", "E_QUOTA_INSUFFICIENT", "Insufficient quota"}
+end
+
+local newQuota = redis.call("HINCRBY", KEYS[1], "quota", -qty)
+redis.call("HINCRBY", KEYS[1], "sold", qty)
+redis.call("HINCRBY", KEYS[1], "version", 1)
+redis.call("HSET", KEYS[1], "modified", ARGV[4])
+redis.call("HINCRBY", KEYS[3], userId, qty)
+-- 用户已抢数量与活动名额同时过期
+redis.call("PEXPIRE", KEYS[3], redis.call("PTTL", KEYS[1]))
+
+local logEntry = {
+    type = "seckill",
+    order_id = ARGV[5],
+    user_id = tonumber(userId),
+    item_id = tonumber(string.match(KEYS[1], "campaign:(%d+)$")),
+    quantity = qty,
+    old_stock = quota,
+    new_stock = newQuota,
+    timestamp = ARGV[4],
+    is_rollback = false
+}
+redis.call("RPUSH", KEYS[2], cjson.encode(logEntry))
+
+redis.call("LPUSH", KEYS[4], ARGV[6])
+redis.call("SET", KEYS[5], ARGV[7], "EX", tonumber(ARGV[8]))
+
+return {"SUCCESS"}
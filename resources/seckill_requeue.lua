@@ -0,0 +1,11 @@
+-- 把处理超时的秒杀请求从处理中列表放回下单队列的出队端, 请求已经不在处理中列表(已处理完成)时不放回
+-- KEYS[1]: 处理中列表key
+-- KEYS[2]: 处理中请求取出时间有序集合key
+-- KEYS[3]: 下单队列key
+-- ARGV[1]: 请求在列表中的原始数据
+
+redis.call("ZREM", KEYS[2], ARGV[1])
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+    return 0
+end
+return redis.call("RPUSH", KEYS[3], ARGV[1])
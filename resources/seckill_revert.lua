@@ -0,0 +1,39 @@
+-- 秒杀请求创建订单失败时退回名额和用户已抢数量
+-- KEYS[1]: 活动名额key
+-- KEYS[2]: 活动名额流水key
+-- KEYS[3]: 活动用户已抢数量key
+-- ARGV[1]: 用户ID
+-- ARGV[2]: 退回数量
+-- ARGV[3]: 当前时间
+-- ARGV[4]: 请求ID
+
+if redis.call("EXISTS", KEYS[1]) == 0 then
+    -- 活动数据已过期, 无需退回
+    return {"SUCCESS"}
+end
+
+local userId = ARGV[1]
+local qty = tonumber(ARGV[2])
+local oldQuota = tonumber(redis.call("HGET", KEYS[1], "quota"))
+local newQuota = redis.call("HINCRBY", KEYS[1], "quota", qty)
+redis.call("HINCRBY", KEYS[1], "sold", -qty)
+redis.call("HINCRBY", KEYS[1], "version", 1)
+redis.call("HSET", KEYS[1], "modified", ARGV[3])
+if redis.call("HINCRBY", KEYS[3], userId, -qty) <= 0 then
+    redis.call("HDEL", KEYS[3], userId)
+end
+
+local logEntry = {
+    type = "seckill_revert",
+    order_id = ARGV[4],
+    user_id = tonumber(userId),
+    item_id = tonumber(string.match(KEYS[1], "campaign:(%d+)$")),
+    quantity = qty,
+    old_stock = oldQuota,
+    new_stock = newQuota,
+    timestamp = ARGV[3],
+    is_rollback = true
+}
+redis.call("RPUSH", KEYS[2], cjson.encode(logEntry))
+
+return {"SUCCESS"}
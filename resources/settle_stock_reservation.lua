@@ -0,0 +1,87 @@
+-- 结算订单的库存预占
+--   confirm: 支付成功, 预占库存转为已售 reserved -> sold
+--   release: 订单取消或预占超时, 预占库存退回可售 reserved -> stock
+-- 释放后预占记录即删除; 确认后记录保留 settled 标记一段时间后过期, 重复确认直接返回成功,
+-- 保证同一预占只会被确认或释放一次, 并且支付通知重试时不会失败
+-- 确认会减少商品的 可售+预占, 商品被对账修复或后台调整锁定时不确认, 由调用方稍后重试; 释放不改变两者之和, 不受锁影响
+-- KEYS[1]: 订单预占记录key
+-- KEYS[2]: 预占到期时间有序集合key
+-- KEYS[3..]: 依次为每个商品的 库存key, 库存流水key, 库存锁key
+-- ARGV[1]: 订单号
+-- ARGV[2]: 结算方式 confirm | release
+-- ARGV[3]: 当前时间
+-- ARGV[4]: 确认后预占记录的保留时长(秒)
+-- 只有结算成功或预占已经结算过时才从到期时间有序集合中移除, 被锁拒绝的确认保留到期记录, 调用方放弃时仍由定时任务释放
+
+local reservation = redis.call("HGETALL", KEYS[1])
+if #reservation == 0 then
+    redis.call("ZREM", KEYS[2], ARGV[1])
+    return {"err", "E_RESERVATION_NOT_FOUND", "Reservation not found"}
+end
+
+local reserved = {}
+local settled = nil
+for i = 1, #reservation, 2 do
+    if reservation[i] == "settled" then
+        settled = reservation[i + 1]
+    else
+        reserved[reservation[i]] = tonumber(reservation[i + 1])
+    end
+end
+if settled ~= nil then
+    redis.call("ZREM", KEYS[2], ARGV[1])
+    if settled == ARGV[2] then
+        return {"SUCCESS"}
+    end
+    return {"err", "E_RESERVATION_NOT_FOUND", "Reservation already settled"}
+end
+
+local itemCount = (#KEYS - 2) / 3
+if ARGV[2] == "confirm" then
+    for i = 1, itemCount do
+        if redis.call("EXISTS", KEYS[2 + i * 3]) == 1 then
+            return {"err", "E_ITEM_LOCKED", "Item locked", KEYS[i * 3]}
+        end
+    end
+end
+
+for i = 1, itemCount do
+    local stockKey = KEYS[i * 3]
+    local logKey = KEYS[1 + i * 3]
+    local itemId = string.match(stockKey, "item:(%d+)$")
+    local qty = reserved[itemId]
+    if qty ~= nil and redis.call("EXISTS", stockKey) == 1 then
+        local oldStock = tonumber(redis.call("HGET", stockKey, "stock"))
+        local newStock = oldStock
+        redis.call("HINCRBY", stockKey, "reserved", -qty)
+        if ARGV[2] == "confirm" then
+            redis.call("HINCRBY", stockKey, "sold", qty)
+        else
+            newStock = redis.call("HINCRBY", stockKey, "stock", qty)
+        end
+        redis.call("HINCRBY", stockKey, "version", 1)
+        redis.call("HSET", stockKey, "modified", ARGV[3])
+
+        local logEntry = {
+            type = ARGV[2],
+            order_id = ARGV[1],
+            item_id = tonumber(itemId),
+            quantity = qty,
+            old_stock = oldStock,
+            new_stock = newStock,
+            timestamp = ARGV[3],
+            is_rollback = ARGV[2] == "release"
+        }
+        redis.call("RPUSH", logKey, cjson.encode(logEntry))
+    end
+end
+
+if ARGV[2] == "confirm" then
+    redis.call("HSET", KEYS[1], "settled", "confirm")
+    redis.call("EXPIRE", KEYS[1], tonumber(ARGV[4]))
+else
+    redis.call("DEL", KEYS[1])
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+
+return {"SUCCESS"}
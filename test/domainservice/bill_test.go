@@ -0,0 +1,109 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func newBillReductions(amounts map[string]int, chain ...string) []*do.BillReduction {
+	reductions := make([]*do.BillReduction, 0, len(chain))
+	for _, checker := range chain {
+		reductions = append(reductions, &do.BillReduction{Checker: checker, Amount: amounts[checker]})
+	}
+	return reductions
+}
+
+func TestSettleBillReductions(t *testing.T) {
+	Convey("Given coupon, discount and vip reductions on a 10000 bill", t, func() {
+		amounts := map[string]int{
+			enum.BillCheckerCoupon:   1000,
+			enum.BillCheckerDiscount: 2000,
+			enum.BillCheckerVip:      500,
+		}
+		chain := []string{enum.BillCheckerCoupon, enum.BillCheckerDiscount, enum.BillCheckerVip}
+
+		Convey("when no exclusive group is configured", func() {
+			reductions := newBillReductions(amounts, chain...)
+			payMoney := domainservice.SettleBillReductions(reductions, 10000, 1, nil)
+			Convey("Then every reduction should be applied in full", func() {
+				So(payMoney, ShouldEqual, 6500)
+				for _, reduction := range reductions {
+					So(reduction.Result, ShouldEqual, enum.BillReductionApplied)
+					So(reduction.DeductMoney, ShouldEqual, reduction.Amount)
+				}
+			})
+		})
+
+		Convey("when coupon and discount are exclusive", func() {
+			reductions := newBillReductions(amounts, chain...)
+			exclusive := [][]string{{enum.BillCheckerCoupon, enum.BillCheckerDiscount}}
+			payMoney := domainservice.SettleBillReductions(reductions, 10000, 1, exclusive)
+			Convey("Then only the larger one should be used", func() {
+				So(payMoney, ShouldEqual, 7500)
+				So(reductions[0].Result, ShouldEqual, enum.BillReductionExcluded)
+				So(reductions[0].ExcludedBy, ShouldEqual, enum.BillCheckerDiscount)
+				So(reductions[0].DeductMoney, ShouldEqual, 0)
+				So(reductions[1].Result, ShouldEqual, enum.BillReductionApplied)
+				So(reductions[2].Result, ShouldEqual, enum.BillReductionApplied)
+			})
+		})
+
+		Convey("when exclusive reductions have the same amount", func() {
+			amounts[enum.BillCheckerCoupon] = 2000
+			reductions := newBillReductions(amounts, chain...)
+			exclusive := [][]string{{enum.BillCheckerDiscount, enum.BillCheckerCoupon}}
+			domainservice.SettleBillReductions(reductions, 10000, 1, exclusive)
+			Convey("Then the one earlier in the chain should be kept", func() {
+				So(reductions[0].Result, ShouldEqual, enum.BillReductionApplied)
+				So(reductions[1].Result, ShouldEqual, enum.BillReductionExcluded)
+				So(reductions[1].ExcludedBy, ShouldEqual, enum.BillCheckerCoupon)
+			})
+		})
+	})
+
+	Convey("Given reductions larger than the bill allows", t, func() {
+		amounts := map[string]int{
+			enum.BillCheckerCoupon:   3000,
+			enum.BillCheckerDiscount: 1500,
+			enum.BillCheckerVip:      800,
+		}
+
+		Convey("when the minimum pay money is reached in the middle of the chain", func() {
+			reductions := newBillReductions(amounts, enum.BillCheckerCoupon, enum.BillCheckerDiscount, enum.BillCheckerVip)
+			payMoney := domainservice.SettleBillReductions(reductions, 5000, 1000, nil)
+			Convey("Then earlier reductions should deduct first and the rest be capped or left without room", func() {
+				So(payMoney, ShouldEqual, 1000)
+				So(reductions[0].DeductMoney, ShouldEqual, 3000)
+				So(reductions[0].Result, ShouldEqual, enum.BillReductionApplied)
+				So(reductions[1].DeductMoney, ShouldEqual, 1000)
+				So(reductions[1].Result, ShouldEqual, enum.BillReductionCapped)
+				So(reductions[2].DeductMoney, ShouldEqual, 0)
+				So(reductions[2].Result, ShouldEqual, enum.BillReductionNoRoom)
+			})
+		})
+
+		Convey("when the chain order is changed", func() {
+			reductions := newBillReductions(amounts, enum.BillCheckerVip, enum.BillCheckerDiscount, enum.BillCheckerCoupon)
+			payMoney := domainservice.SettleBillReductions(reductions, 5000, 1000, nil)
+			Convey("Then the reductions should deduct in the new order", func() {
+				So(payMoney, ShouldEqual, 1000)
+				So(reductions[0].DeductMoney, ShouldEqual, 800)
+				So(reductions[1].DeductMoney, ShouldEqual, 1500)
+				So(reductions[2].DeductMoney, ShouldEqual, 1700)
+				So(reductions[2].Result, ShouldEqual, enum.BillReductionCapped)
+			})
+		})
+
+		Convey("when the bill is below the minimum pay money", func() {
+			reductions := newBillReductions(amounts, enum.BillCheckerCoupon)
+			payMoney := domainservice.SettleBillReductions(reductions, 500, 1000, nil)
+			Convey("Then nothing should be deducted", func() {
+				So(payMoney, ShouldEqual, 500)
+				So(reductions[0].Result, ShouldEqual, enum.BillReductionNoRoom)
+			})
+		})
+	})
+}
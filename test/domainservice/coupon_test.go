@@ -0,0 +1,92 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	Convey("Given an amount coupon of 1000 for all commodities", t, func() {
+		coupon := &do.UserCoupon{ID: 1, Template: &do.CouponTemplate{
+			DiscountType: enum.CouponDiscountTypeAmount, DiscountValue: 1000, Threshold: 5000,
+		}}
+		Convey("when used for items reaching the threshold", func() {
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then the full amount should be discounted", func() {
+				So(discount.UserCoupon, ShouldEqual, coupon)
+				So(discount.ScopeAmount, ShouldEqual, 9000)
+				So(discount.DiscountMoney, ShouldEqual, 1000)
+			})
+		})
+		Convey("when the threshold is higher than the items", func() {
+			coupon.Template.Threshold = 10000
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then the coupon should not be usable", func() {
+				So(discount.DiscountMoney, ShouldEqual, 0)
+			})
+		})
+		Convey("when the amount is larger than the commodities in scope", func() {
+			coupon.Template.DiscountValue = 5000
+			coupon.Template.Threshold = 0
+			coupon.Template.ScopeType = enum.PromotionScopeCommodity
+			coupon.Template.ScopeIds = []int64{3}
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then at most the scope amount should be discounted", func() {
+				So(discount.ScopeAmount, ShouldEqual, 2000)
+				So(discount.DiscountMoney, ShouldEqual, 2000)
+			})
+		})
+	})
+
+	Convey("Given a 20 percent coupon", t, func() {
+		coupon := &do.UserCoupon{ID: 2, Template: &do.CouponTemplate{
+			DiscountType: enum.CouponDiscountTypePercent, DiscountValue: 20,
+		}}
+		Convey("when it has no max discount", func() {
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then 20 percent of the items should be discounted", func() {
+				So(discount.DiscountMoney, ShouldEqual, 1800)
+			})
+		})
+		Convey("when the max discount is 1500", func() {
+			coupon.Template.MaxDiscount = 1500
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then the discount should be capped", func() {
+				So(discount.DiscountMoney, ShouldEqual, 1500)
+			})
+		})
+	})
+
+	Convey("Given a coupon for category 10 with a threshold", t, func() {
+		coupon := &do.UserCoupon{ID: 3, Template: &do.CouponTemplate{
+			DiscountType: enum.CouponDiscountTypePercent, DiscountValue: 10, Threshold: 7000,
+			ScopeType: enum.PromotionScopeCategory, ScopeIds: []int64{10},
+		}}
+		Convey("when the commodities in the category reach the threshold", func() {
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then only the commodities in the category should be discounted", func() {
+				So(discount.ScopeAmount, ShouldEqual, 7000)
+				So(discount.DiscountMoney, ShouldEqual, 700)
+			})
+		})
+		Convey("when only the whole cart reaches the threshold", func() {
+			coupon.Template.Threshold = 8000
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then the coupon should not be usable", func() {
+				So(discount.ScopeAmount, ShouldEqual, 7000)
+				So(discount.DiscountMoney, ShouldEqual, 0)
+			})
+		})
+		Convey("when no item is in the category", func() {
+			coupon.Template.ScopeIds = []int64{30}
+			discount := domainservice.CouponDiscount(coupon, newPromotionItems())
+			Convey("Then nothing should be discounted", func() {
+				So(discount.ScopeAmount, ShouldEqual, 0)
+				So(discount.DiscountMoney, ShouldEqual, 0)
+			})
+		})
+	})
+}
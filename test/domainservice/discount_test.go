@@ -0,0 +1,150 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/enum"
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func newFullReduction(id int64, priority int, tiers ...*do.DiscountTier) *do.DiscountCampaign {
+	return &do.DiscountCampaign{ID: id, Type: enum.DiscountTypeFullReduction, Priority: priority, Tiers: tiers}
+}
+
+func TestEvaluateDiscountCampaigns(t *testing.T) {
+	Convey("Given a full reduction campaign with three tiers", t, func() {
+		campaign := newFullReduction(1, 0,
+			&do.DiscountTier{Threshold: 10000, Value: 1500},
+			&do.DiscountTier{Threshold: 8000, Value: 1000},
+			&do.DiscountTier{Threshold: 5000, Value: 500},
+		)
+		Convey("when evaluated for all commodities", func() {
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then the highest tier reached should be applied", func() {
+				So(applied, ShouldEqual, evaluations[0])
+				So(applied.Tier.Threshold, ShouldEqual, 8000)
+				So(applied.DiscountMoney, ShouldEqual, 1000)
+				So(applied.Result, ShouldEqual, enum.DiscountResultApplied)
+			})
+		})
+		Convey("when only category 10 is in scope", func() {
+			campaign.ScopeType = enum.PromotionScopeCategory
+			campaign.ScopeIds = []int64{10}
+			_, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then only the commodities in the category should count for the tier", func() {
+				So(applied.ScopeAmount, ShouldEqual, 7000)
+				So(applied.Tier.Threshold, ShouldEqual, 5000)
+				So(applied.DiscountMoney, ShouldEqual, 500)
+			})
+		})
+		Convey("when no item is in scope", func() {
+			campaign.ScopeType = enum.PromotionScopeCommodity
+			campaign.ScopeIds = []int64{4}
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then the campaign should have no eligible item", func() {
+				So(applied, ShouldBeNil)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultNoEligibleItem)
+			})
+		})
+		Convey("when the lowest tier is not reached", func() {
+			campaign.Tiers = campaign.Tiers[:1]
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then no campaign should be applied", func() {
+				So(applied, ShouldBeNil)
+				So(evaluations[0].Tier, ShouldBeNil)
+				So(evaluations[0].DiscountMoney, ShouldEqual, 0)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultThresholdUnmet)
+			})
+		})
+	})
+
+	Convey("Given a buy n campaign of 10 percent off for 3 pieces and 20 percent off for 5", t, func() {
+		campaign := &do.DiscountCampaign{ID: 1, Type: enum.DiscountTypeBuyN, Tiers: []*do.DiscountTier{
+			{Threshold: 5, Value: 20}, {Threshold: 3, Value: 10},
+		}}
+		Convey("when evaluated for 4 pieces", func() {
+			_, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then the percent of 3 pieces should be applied", func() {
+				So(applied.Tier.Threshold, ShouldEqual, 3)
+				So(applied.DiscountMoney, ShouldEqual, 900)
+				So(applied.Result, ShouldEqual, enum.DiscountResultApplied)
+			})
+		})
+	})
+
+	Convey("Given a bundle campaign", t, func() {
+		campaign := &do.DiscountCampaign{ID: 1, Type: enum.DiscountTypeBundle}
+		Convey("when 3 pieces are bundled for 5000", func() {
+			campaign.Tiers = []*do.DiscountTier{{Threshold: 3, Value: 5000}}
+			_, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then the most expensive pieces should be bundled", func() {
+				So(applied.DiscountMoney, ShouldEqual, 3000)
+				So(applied.Result, ShouldEqual, enum.DiscountResultApplied)
+			})
+		})
+		Convey("when the bundle price is higher than the pieces", func() {
+			campaign.Tiers = []*do.DiscountTier{{Threshold: 2, Value: 7000}}
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns([]*do.DiscountCampaign{campaign}, newPromotionItems())
+			Convey("Then the campaign should not be applied", func() {
+				So(applied, ShouldBeNil)
+				So(evaluations[0].DiscountMoney, ShouldEqual, 0)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultThresholdUnmet)
+			})
+		})
+	})
+
+	Convey("Given two full reduction campaigns reached at the same time", t, func() {
+		Convey("when the one with less discount has a higher priority", func() {
+			campaigns := []*do.DiscountCampaign{
+				newFullReduction(1, 1, &do.DiscountTier{Threshold: 5000, Value: 1000}),
+				newFullReduction(2, 2, &do.DiscountTier{Threshold: 5000, Value: 500}),
+			}
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns(campaigns, newPromotionItems())
+			Convey("Then the higher priority should be applied", func() {
+				So(applied.Campaign.ID, ShouldEqual, 2)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultNotPreferred)
+				So(evaluations[1].Result, ShouldEqual, enum.DiscountResultApplied)
+			})
+		})
+		Convey("when they have the same priority", func() {
+			campaigns := []*do.DiscountCampaign{
+				newFullReduction(1, 1, &do.DiscountTier{Threshold: 5000, Value: 500}),
+				newFullReduction(2, 1, &do.DiscountTier{Threshold: 5000, Value: 1000}),
+			}
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns(campaigns, newPromotionItems())
+			Convey("Then the one with more discount should be applied", func() {
+				So(applied.Campaign.ID, ShouldEqual, 2)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultNotPreferred)
+				So(evaluations[1].Result, ShouldEqual, enum.DiscountResultApplied)
+			})
+		})
+		Convey("when they have the same priority and discount", func() {
+			campaigns := []*do.DiscountCampaign{
+				newFullReduction(1, 1, &do.DiscountTier{Threshold: 5000, Value: 500}),
+				newFullReduction(2, 1, &do.DiscountTier{Threshold: 5000, Value: 500}),
+			}
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns(campaigns, newPromotionItems())
+			Convey("Then the earlier one should be applied", func() {
+				So(applied.Campaign.ID, ShouldEqual, 1)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultApplied)
+				So(evaluations[1].Result, ShouldEqual, enum.DiscountResultNotPreferred)
+			})
+		})
+	})
+
+	Convey("Given a higher priority campaign whose threshold is not reached", t, func() {
+		campaigns := []*do.DiscountCampaign{
+			newFullReduction(1, 1, &do.DiscountTier{Threshold: 5000, Value: 500}),
+			newFullReduction(2, 9, &do.DiscountTier{Threshold: 10000, Value: 2000}),
+		}
+		Convey("when evaluated together", func() {
+			evaluations, applied := domainservice.EvaluateDiscountCampaigns(campaigns, newPromotionItems())
+			Convey("Then the reached campaign should be applied", func() {
+				So(applied.Campaign.ID, ShouldEqual, 1)
+				So(evaluations[0].Result, ShouldEqual, enum.DiscountResultApplied)
+				So(evaluations[1].Result, ShouldEqual, enum.DiscountResultThresholdUnmet)
+			})
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestConsumePoints(t *testing.T) {
+	Convey("Given points entries out of order with 200 points remaining", t, func() {
+		day1 := time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)
+		day2 := day1.AddDate(0, 0, 1)
+		day3 := day1.AddDate(0, 0, 2)
+		entries := []*do.PointsEntry{
+			{ID: 4, Remaining: 100, ExpireAt: day3},
+			{ID: 2, Remaining: 30, ExpireAt: day2},
+			{ID: 3, Remaining: 0, ExpireAt: day1},
+			{ID: 1, Remaining: 50, ExpireAt: day2},
+			{ID: 5, Remaining: 20, ExpireAt: day1},
+		}
+
+		Convey("when consuming no points", func() {
+			consumptions, latestExpireAt := domainservice.ConsumePoints(entries, 0)
+			Convey("Then nothing should be consumed", func() {
+				So(consumptions, ShouldBeEmpty)
+				So(latestExpireAt.IsZero(), ShouldBeTrue)
+			})
+		})
+		Convey("when consuming less than the earliest entry", func() {
+			consumptions, latestExpireAt := domainservice.ConsumePoints(entries, 15)
+			Convey("Then only the earliest expiring entry with points left should be consumed", func() {
+				So(consumptions, ShouldResemble, []*do.PointsConsumption{{EntryId: 5, Points: 15}})
+				So(latestExpireAt, ShouldEqual, day1)
+			})
+		})
+		Convey("when consuming into entries expiring at the same time", func() {
+			consumptions, latestExpireAt := domainservice.ConsumePoints(entries, 40)
+			Convey("Then the entry earned earlier should be consumed first", func() {
+				So(consumptions, ShouldResemble, []*do.PointsConsumption{{EntryId: 5, Points: 20}, {EntryId: 1, Points: 20}})
+				So(latestExpireAt, ShouldEqual, day2)
+			})
+		})
+		Convey("when consuming across all entries", func() {
+			consumptions, latestExpireAt := domainservice.ConsumePoints(entries, 120)
+			Convey("Then the entries should be consumed by expire time and the last one partly", func() {
+				So(consumptions, ShouldResemble, []*do.PointsConsumption{
+					{EntryId: 5, Points: 20}, {EntryId: 1, Points: 50}, {EntryId: 2, Points: 30}, {EntryId: 4, Points: 20},
+				})
+				So(latestExpireAt, ShouldEqual, day3)
+			})
+			Convey("Then the given entries should keep their order", func() {
+				So(entries[0].ID, ShouldEqual, 4)
+			})
+		})
+		Convey("when consuming more than remaining", func() {
+			consumptions, _ := domainservice.ConsumePoints(entries, 500)
+			Convey("Then all remaining points should be consumed", func() {
+				So(consumptions, ShouldHaveLength, 4)
+				So(consumptions[3], ShouldResemble, &do.PointsConsumption{EntryId: 4, Points: 100})
+			})
+		})
+	})
+}
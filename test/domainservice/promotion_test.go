@@ -0,0 +1,13 @@
+package domainservice
+
+import "github.com/Ian-zy0329/go-mall/logic/do"
+
+// newPromotionItems 优惠券和满减活动测试共用的购物项, 合计 9000 分 4 件:
+// 分类 10 下的商品 1(3000 x 2) 和商品 2(1000 x 1), 分类 20 下的商品 3(2000 x 1)
+func newPromotionItems() []*do.ShoppingCartItem {
+	return []*do.ShoppingCartItem{
+		{CommodityId: 1, CategoryId: 10, CommoditySellingPrice: 3000, CommodityNum: 2},
+		{CommodityId: 2, CategoryId: 10, CommoditySellingPrice: 1000, CommodityNum: 1},
+		{CommodityId: 3, CategoryId: 20, CommoditySellingPrice: 2000, CommodityNum: 1},
+	}
+}
@@ -0,0 +1,57 @@
+package domainservice
+
+import (
+	"errors"
+	"github.com/Ian-zy0329/go-mall/common/errcode"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCheckPurchaseNums(t *testing.T) {
+	Convey("Given commodities without purchase limits and at most 10 per commodity", t, func() {
+		Convey("when buying no more than 10 of each commodity", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{1: 5, 2: 10}, 10, nil, nil)
+			Convey("Then the check should pass", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+		Convey("when buying more than 10 of two commodities", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{3: 12, 2: 11}, 10, nil, nil)
+			Convey("Then the commodity with the smaller id should be reported", func() {
+				So(errors.Is(err, errcode.ErrCartPurchaseLimit), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "商品 2 最多购买 10 件")
+			})
+		})
+	})
+
+	Convey("Given commodity 1 limited to 3 per user and already bought 2 times", t, func() {
+		limits := map[int64]int{1: 3}
+		purchased := map[int64]int{1: 2, 2: 100}
+		Convey("when buying 1 more", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{1: 1}, 10, limits, purchased)
+			Convey("Then the check should pass", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+		Convey("when buying 2 more", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{1: 2}, 10, limits, purchased)
+			Convey("Then the purchase limit should be reported with the bought number", func() {
+				So(errors.Is(err, errcode.ErrCartPurchaseLimit), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "商品 1 每人限购 3 件, 已购买 2 件")
+			})
+		})
+		Convey("when buying a commodity without purchase limit bought many times before", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{2: 8}, 10, limits, purchased)
+			Convey("Then only the number per commodity should be checked", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+		Convey("when buying more than the number per commodity", func() {
+			err := domainservice.CheckPurchaseNums(map[int64]int{1: 11}, 10, map[int64]int{1: 20}, nil)
+			Convey("Then the number per commodity should be reported first", func() {
+				So(err.Error(), ShouldContainSubstring, "商品 1 最多购买 10 件")
+			})
+		})
+	})
+}
@@ -0,0 +1,31 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/common/util"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	Convey("Given a keyword mixed with chinese and latin words", t, func() {
+		keyword := "华为Mate60 手机"
+		Convey("when split it into search terms", func() {
+			terms := util.SearchTerms(keyword)
+			Convey("Then chinese should be split into bigrams and latin words lowercased", func() {
+				So(terms, ShouldResemble, []string{"华为", "mate60", "手机"})
+			})
+		})
+	})
+}
+
+func TestHighlightTerms(t *testing.T) {
+	Convey("Given a text containing the search terms", t, func() {
+		text := "华为手机 <Mate60> 8GB"
+		Convey("when highlight the terms in it", func() {
+			result := util.HighlightTerms(text, util.SearchTerms("为手 MATE60"))
+			Convey("Then matched parts should be wrapped with em and the rest escaped", func() {
+				So(result, ShouldEqual, "华<em>为手</em>机 &lt;<em>Mate60</em>&gt; 8GB")
+			})
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package domainservice
+
+import (
+	"github.com/Ian-zy0329/go-mall/logic/do"
+	"github.com/Ian-zy0329/go-mall/logic/domainservice"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestRefreshVipLevel(t *testing.T) {
+	Convey("Given level 1 and 2 reached by growth, level 3 only for purchase and levels valid for 365 days", t, func() {
+		levels := []*do.VipLevel{
+			{Level: 1, MinGrowth: 1000},
+			{Level: 2, MinGrowth: 5000},
+			{Level: 3, MinGrowth: 0},
+		}
+		now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+		renewed := now.AddDate(0, 0, 365)
+		neverExpire := time.Unix(0, 0)
+
+		Convey("when a normal user has not enough growth", func() {
+			level, expireAt := domainservice.RefreshVipLevel(0, neverExpire, 999, levels, 365, now)
+			Convey("Then the user should stay a normal user", func() {
+				So(level, ShouldEqual, 0)
+				So(expireAt, ShouldEqual, neverExpire)
+			})
+		})
+		Convey("when a normal user has growth for level 2", func() {
+			level, expireAt := domainservice.RefreshVipLevel(0, neverExpire, 8000, levels, 365, now)
+			Convey("Then the user should be upgraded to level 2 from now on", func() {
+				So(level, ShouldEqual, 2)
+				So(expireAt, ShouldEqual, renewed)
+			})
+		})
+
+		Convey("when an active member", func() {
+			active := now.AddDate(0, 0, 10)
+			Convey("has growth for a higher level", func() {
+				level, expireAt := domainservice.RefreshVipLevel(1, active, 5000, levels, 365, now)
+				Convey("Then the member should be upgraded with a new expire time", func() {
+					So(level, ShouldEqual, 2)
+					So(expireAt, ShouldEqual, renewed)
+				})
+			})
+			Convey("has growth for the current level", func() {
+				level, expireAt := domainservice.RefreshVipLevel(1, active, 1200, levels, 365, now)
+				Convey("Then the level and expire time should not change", func() {
+					So(level, ShouldEqual, 1)
+					So(expireAt, ShouldEqual, active)
+				})
+			})
+			Convey("has not enough growth for the current level", func() {
+				level, expireAt := domainservice.RefreshVipLevel(2, active, 100, levels, 365, now)
+				Convey("Then the member should not be downgraded before expiring", func() {
+					So(level, ShouldEqual, 2)
+					So(expireAt, ShouldEqual, active)
+				})
+			})
+			Convey("has a purchased level higher than the growth levels", func() {
+				level, expireAt := domainservice.RefreshVipLevel(3, active, 8000, levels, 365, now)
+				Convey("Then the purchased level should be kept", func() {
+					So(level, ShouldEqual, 3)
+					So(expireAt, ShouldEqual, active)
+				})
+			})
+		})
+
+		Convey("when the member's level has expired", func() {
+			expired := now.AddDate(0, 0, -1)
+			Convey("and the growth is enough for a lower level", func() {
+				level, expireAt := domainservice.RefreshVipLevel(2, expired, 1200, levels, 365, now)
+				Convey("Then the member should get the lower level from now on", func() {
+					So(level, ShouldEqual, 1)
+					So(expireAt, ShouldEqual, renewed)
+				})
+			})
+			Convey("and the growth is not enough for any level", func() {
+				level, expireAt := domainservice.RefreshVipLevel(2, expired, 100, levels, 365, now)
+				Convey("Then the member should become a normal user", func() {
+					So(level, ShouldEqual, 0)
+					So(expireAt, ShouldEqual, expired)
+				})
+			})
+			Convey("right now", func() {
+				level, expireAt := domainservice.RefreshVipLevel(1, now, 1000, levels, 365, now)
+				Convey("Then the level should be earned again with a new expire time", func() {
+					So(level, ShouldEqual, 1)
+					So(expireAt, ShouldEqual, renewed)
+				})
+			})
+		})
+	})
+}